@@ -1,14 +1,26 @@
 package app
 
 import (
+	"context"
+	"fmt"
 	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/RiadMefti/TimeTracker/back-end/collab"
+	"github.com/RiadMefti/TimeTracker/back-end/config"
 	"github.com/RiadMefti/TimeTracker/back-end/controllers"
 	"github.com/RiadMefti/TimeTracker/back-end/db"
+	"github.com/RiadMefti/TimeTracker/back-end/events"
 	"github.com/RiadMefti/TimeTracker/back-end/middleware"
 	"github.com/RiadMefti/TimeTracker/back-end/repositories"
 	"github.com/RiadMefti/TimeTracker/back-end/routes"
 	"github.com/RiadMefti/TimeTracker/back-end/services"
+	"github.com/RiadMefti/TimeTracker/back-end/storage"
 	"github.com/RiadMefti/TimeTracker/back-end/utils"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -20,14 +32,49 @@ import (
 	swagger "github.com/arsmn/fiber-swagger/v2"
 )
 
+// logLevels maps LOG_LEVEL to the lowest slog level written.
+var logLevels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+// cancelledRequestGrace is how long shutdown waits for cancelled requests to
+// return before closing the database under them.
+const cancelledRequestGrace = 5 * time.Second
+
 func RunApp() error {
 
+	//config
+	config, err := config.Load()
+	if err != nil {
+		return err
+	}
+	slog.SetLogLoggerLevel(logLevels[config.LogLevel])
+
+	// Cancelling requests cancels the requests still running when the
+	// shutdown timeout is over. Cancelling jobs stops the background jobs as
+	// soon as shutdown starts.
+	requests, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	inFlight := &middleware.InFlight{}
+	jobs, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
 	//init app
-	app := fiber.New()
+	bodyLimits := middleware.NewBodyLimits(config.BodyLimits)
+	ipRateLimiter, userRateLimiter, err := middleware.NewRateLimiters(config.RateLimits)
+	if err != nil {
+		return err
+	}
+	// Per-route caps are checked by the body limit middleware; the app only
+	// needs to accept the largest of them
+	app := fiber.New(fiber.Config{BodyLimit: bodyLimits.Max()})
 
 	//Create Db
 
-	db, err := db.InitDb()
+	db, err := db.InitDb(config.DB)
 
 	if err != nil {
 		return err
@@ -40,60 +87,199 @@ func RunApp() error {
 	timeBoxEntryRepository := repositories.NewTimeBoxEntryRepository(db)
 	folderRepository := repositories.NewFolderRepository(db)
 	noteRepository := repositories.NewNoteRepository(db)
+	noteLinkRepository := repositories.NewNoteLinkRepository(db)
+	noteRelationRepository := repositories.NewNoteRelationRepository(db)
+	attachmentRepository := repositories.NewAttachmentRepository(db)
+	shareLinkRepository := repositories.NewShareLinkRepository(db)
+	trashRepository := repositories.NewTrashRepository(db)
+	dailyNoteRepository := repositories.NewDailyNoteRepository(db)
+	tagRepository := repositories.NewTagRepository(db)
+	noteTaskRepository := repositories.NewNoteTaskRepository(db)
+	timerRepository := repositories.NewTimerRepository(db)
+	webhookRepository := repositories.NewWebhookRepository(db)
+	accessTokenRepository := repositories.NewAccessTokenRepository(db)
+	userSettingsRepository := repositories.NewUserSettingsRepository(db)
+	exportRepository := repositories.NewExportRepository(db)
+	accountRepository := repositories.NewAccountRepository(db)
+
+	//storage
+	blobStore, err := storage.NewBlobStore(config.Storage)
+	if err != nil {
+		return err
+	}
+	attachmentConfig, err := services.NewAttachmentConfig(config.Attachments)
+	if err != nil {
+		return err
+	}
 
 	//services
+	eventBus := events.NewBus()
 	authService := services.NewAuthService(userRepository)
-	projectService := services.NewProjectService(projectRepository)
-	timeEntryService := services.NewTimeEntryService(timeEntryRepository)
-	timeBoxEntryService := services.NewTimeBoxEntryService(timeBoxEntryRepository)
-	folderService := services.NewFolderService(folderRepository)
-	noteService := services.NewNoteService(noteRepository)	
+	projectService := services.NewProjectService(projectRepository, eventBus)
+	timeEntryService := services.NewTimeEntryService(timeEntryRepository, noteRelationRepository, eventBus)
+	timeBoxEntryService := services.NewTimeBoxEntryService(timeBoxEntryRepository, eventBus)
+	timerService := services.NewTimerService(timerRepository, timeEntryRepository, projectRepository, eventBus)
+	attachmentService := services.NewAttachmentService(attachmentRepository, noteRepository, blobStore, attachmentConfig)
+	noteService := services.NewNoteService(noteRepository, noteLinkRepository, noteTaskRepository, eventBus)
+	folderService := services.NewFolderService(folderRepository, noteRepository, noteService, eventBus)
+	noteTemplateService := services.NewNoteTemplateService(noteService, noteRepository, dailyNoteRepository, folderRepository, projectRepository)
+	trashService := services.NewTrashService(trashRepository, attachmentService, config.Retention.Trash())
+	shareService := services.NewShareService(shareLinkRepository, noteRepository, folderService)
+	tagService := services.NewTagService(tagRepository)
+	webhookService := services.NewWebhookService(webhookRepository)
+	if config.Features.Webhooks {
+		eventBus.Listen(webhookService.HandleEvent)
+	}
+	accessTokenService := services.NewAccessTokenService(accessTokenRepository)
+	userSettingsService := services.NewUserSettingsService(userSettingsRepository, projectRepository)
+	exportService := services.NewExportService(exportRepository, blobStore, attachmentConfig.URLSecret)
+	noteRelationService := services.NewNoteRelationService(noteRelationRepository, noteRepository, projectRepository, timeEntryRepository, timeBoxEntryRepository)
 
-	firebaseService, err := services.NewFirebaseService()
+	authenticator, err := services.NewAuthenticator(config.Auth, userRepository)
 	if err != nil {
 		return err
 	}
+	accountService := services.NewAccountService(accountRepository, exportService, attachmentService, authenticator, config.Retention.AccountDeletionGrace())
 
 	//middleware
 
-	app.Use(logger.New())
+	// Every request is logged at the info level and below
+	if logLevels[config.LogLevel] <= slog.LevelInfo {
+		app.Use(logger.New())
+	}
 	app.Use(recover.New())
+	app.Use(middleware.RequestContextMiddleware(requests, config.RequestTimeout, inFlight))
 	app.Use(cors.New(cors.Config{
-		AllowOrigins:     "*",
+		AllowOrigins:     strings.Join(config.CORSOrigins, ","),
 		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS,PATCH",
-		AllowHeaders:     "Accept,Authorization,Content-Type",
+		AllowHeaders:     "Accept,Authorization,Content-Type,If-Match,Last-Event-ID,X-Share-Password",
+		ExposeHeaders:    "ETag,RateLimit-Limit,RateLimit-Policy,RateLimit-Remaining,RateLimit-Reset,Retry-After",
 		AllowCredentials: false,
 		MaxAge:           300,
 	}))
+	app.Use(ipRateLimiter.Handler())
+	app.Use(middleware.BodyLimitMiddleware(bodyLimits))
 	// Swagger endpoint before
-	app.Get("/swagger/*", swagger.HandlerDefault)
+	if config.Features.Swagger {
+		app.Get("/swagger/*", swagger.HandlerDefault)
+	}
 
-	app.Use(middleware.AuthorizationMiddleware(firebaseService))
+	// Public routes, reachable without a token
+	attachmentController := controllers.NewAttachmentController(attachmentService)
+	shareController := controllers.NewShareController(shareService)
+	exportController := controllers.NewExportController(exportService)
+	routes.SetupPublicAttachmentRoutes(app, attachmentController)
+	if config.Features.ShareLinks {
+		routes.SetupPublicShareRoutes(app, shareController)
+	}
+	routes.SetupPublicExportRoutes(app, exportController)
+	if localAuthenticator, ok := authenticator.(*services.LocalAuthenticator); ok {
+		routes.SetupLocalAuthRoutes(app, controllers.NewLocalAuthController(localAuthenticator), config.Features.Signup)
+	}
+
+	app.Use(middleware.AuthorizationMiddleware(authenticator, accessTokenService))
+	app.Use(userRateLimiter.Handler())
+	app.Use(middleware.UserSettingsMiddleware(userSettingsService))
 
 	//controllers
 
-	authController := controllers.NewAuthController(authService)
+	authController := controllers.NewAuthController(authService, accountService)
+	accountController := controllers.NewAccountController(accountService)
 	projectController := controllers.NewProjectController(projectService)
 	timeEntryController := controllers.NewTimeEntryController(timeEntryService)
 	timeBoxEntryController := controllers.NewTimeBoxEntryController(timeBoxEntryService)
 	folderController := controllers.NewFolderController(folderService)
 	noteController := controllers.NewNoteController(noteService)
+	noteTemplateController := controllers.NewNoteTemplateController(noteTemplateService)
+	noteRelationController := controllers.NewNoteRelationController(noteRelationService)
+	trashController := controllers.NewTrashController(trashService)
+	collabHub := collab.NewHub(noteService)
+	collabController := controllers.NewCollabController(collabHub, noteService)
+	tagController := controllers.NewTagController(tagService)
+	timerController := controllers.NewTimerController(timerService)
+	eventController := controllers.NewEventController(eventBus)
+	webhookController := controllers.NewWebhookController(webhookService)
+	accessTokenController := controllers.NewAccessTokenController(accessTokenService)
+	userSettingsController := controllers.NewUserSettingsController(userSettingsService)
 
 	//routes
 	routes.SetupAuthRoutes(app, authController)
+	routes.SetupAccountRoutes(app, accountController)
 	routes.SetupProjectRoutes(app, projectController)
 	routes.SetupTimeEntryRoutes(app, timeEntryController)
 	routes.SetupTimeBoxEntryRoutes(app, timeBoxEntryController)
+	routes.SetupTimerRoutes(app, timerController)
+	routes.SetupEventRoutes(app, eventController)
+	if config.Features.Webhooks {
+		routes.SetupWebhookRoutes(app, webhookController)
+	}
+	routes.SetupAccessTokenRoutes(app, accessTokenController)
+	routes.SetupUserSettingsRoutes(app, userSettingsController)
+	routes.SetupExportRoutes(app, exportController)
 	routes.SetupFolderRoutes(app, folderController)
+	routes.SetupNoteTemplateRoutes(app, noteTemplateController)
 	routes.SetupNoteRoutes(app, noteController)
+	routes.SetupNoteRelationRoutes(app, noteRelationController)
+	if config.Features.Collab {
+		routes.SetupCollabRoutes(app, collabController)
+	}
+	routes.SetupAttachmentRoutes(app, attachmentController)
+	if config.Features.ShareLinks {
+		routes.SetupShareRoutes(app, shareController)
+	}
+	routes.SetupTrashRoutes(app, trashController)
+	routes.SetupTagRoutes(app, tagController)
 	app.Get("/hello", func(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusOK).JSON(utils.CreateApiResponse(true, "hello from server", "hello sent successfully"))
 	})
-	log.Println("Starting server on port 3000")
-	errStart := app.Listen(":3000")
-	if errStart != nil {
+	//background jobs
+	trashService.StartPurgeJob(jobs, time.Hour)
+	go noteService.IndexUnindexedNotes(jobs)
+	if config.Features.Webhooks {
+		webhookService.StartDeliveryJob(jobs, time.Minute)
+	}
+	exportService.StartExportJob(jobs, time.Hour)
+	accountService.StartPurgeJob(jobs, time.Hour)
+
+	log.Printf("Starting server on port %d (%s)", config.Port, config.Env)
+	errStart := make(chan error, 1)
+	go func() {
+		errStart <- app.Listen(fmt.Sprintf(":%d", config.Port))
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	select {
+	case err := <-errStart:
 		return err
+	case sig := <-signals:
+		log.Printf("Received %s, shutting down", sig)
+	}
+
+	// Event streams never finish on their own, so they are ended first for
+	// the server to drain. Jobs stop where they are; their work is leased or
+	// idempotent and picked up again on the next start.
+	stopJobs()
+	eventBus.Close()
+	drain, cancelDrain := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancelDrain()
+	if err := app.ShutdownWithContext(drain); err != nil {
+		log.Printf("Requests still running after %s are cancelled: %v", config.ShutdownTimeout, err)
+		cancelRequests()
+	}
+	// The server stops waiting at the timeout but leaves the handlers
+	// running. Cancelled, their queries fail at once, so they are waited for
+	// before the database is closed.
+	wait, cancelWait := context.WithTimeout(context.Background(), cancelledRequestGrace)
+	defer cancelWait()
+	if running := inFlight.Wait(wait); running > 0 {
+		log.Printf("%d requests still running after they were cancelled, closing the database anyway", running)
 	}
+	collabHub.Close()
+	// Deliveries of the events published while draining are recorded
+	// before the database is closed.
+	webhookService.Close()
+	log.Println("Server stopped")
 
 	return nil
 
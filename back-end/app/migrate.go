@@ -0,0 +1,33 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/RiadMefti/TimeTracker/back-end/config"
+	"github.com/RiadMefti/TimeTracker/back-end/db"
+)
+
+// RunMigrate runs the migrate subcommand, `migrate up|down|redo|status`,
+// against the configured database.
+func RunMigrate(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: migrate up|down|redo|status")
+	}
+
+	config, err := config.Load()
+	if err != nil {
+		return err
+	}
+	// Migrations may rewrite whole tables, so they run without the query
+	// timeout
+	config.DB.QueryTimeout = 0
+	database, err := db.Open(config.DB)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	return db.Migrate(context.Background(), database, args[0], log.Printf)
+}
@@ -0,0 +1,340 @@
+// Package collab relays Yjs updates between the clients editing a note and
+// saves the merged document back to the note.
+//
+// The hub does not understand Yjs itself. It keeps the updates of a session
+// in memory so that late joiners can catch up, and relies on clients to send
+// the merged TipTap document as snapshots. A session ends, and its updates are
+// dropped, when the last client leaves; the next one starts from the saved
+// note content.
+package collab
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	saveDebounce    = 2 * time.Second
+	maxMessageBytes = 2 << 20
+	// maxSessionBytes is how much update data a session buffers before it
+	// asks a client to compact it into a single state update.
+	maxSessionBytes = 8 << 20
+	sendBuffer      = 256
+	pongWait        = 60 * time.Second
+	pingInterval    = 25 * time.Second
+	writeWait       = 10 * time.Second
+)
+
+// WebSocket message types, as defined in RFC 6455.
+const (
+	textMessage = 1
+	pingMessage = 9
+)
+
+// Conn is the part of a WebSocket connection used by the hub.
+type Conn interface {
+	ReadMessage() (int, []byte, error)
+	WriteMessage(messageType int, data []byte) error
+	SetReadLimit(limit int64)
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+	SetPongHandler(h func(appData string) error)
+	Close() error
+}
+
+// Store saves session snapshots to the note and returns its new version.
+type Store interface {
+	SaveContent(ctx context.Context, noteID int, content string, userID string) (int, error)
+}
+
+// Hub tracks the editing sessions of this process, one per note.
+type Hub struct {
+	store    Store
+	mu       sync.Mutex
+	rooms    map[int]*room
+	sessions sync.WaitGroup
+}
+
+func NewHub(store Store) *Hub {
+	return &Hub{store: store, rooms: map[int]*room{}}
+}
+
+type room struct {
+	noteID  int
+	clients map[*client]bool
+	updates [][]byte
+	size    int
+
+	// compacting is the client asked for a state update, and compactFrom
+	// the number of buffered updates that state replaces.
+	compacting  *client
+	compactFrom int
+
+	snapshot   string // latest unsaved snapshot
+	snapshotBy string
+	timer      *time.Timer
+	saveMu     sync.Mutex // keeps saves in order
+}
+
+type client struct {
+	id     string
+	userID string
+	name   string
+	state  json.RawMessage
+	conn   Conn
+	send   chan []byte
+	done   chan struct{}
+	once   sync.Once
+}
+
+func (c *client) close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+// queue sends data to the client without blocking. A client that cannot keep
+// up is disconnected; it resyncs when it reconnects.
+func (c *client) queue(data []byte) {
+	select {
+	case c.send <- data:
+	case <-c.done:
+	default:
+		c.close()
+	}
+}
+
+// Serve runs the session of one client on conn until it disconnects. The
+// caller must have checked that userID may edit the note.
+func (h *Hub) Serve(conn Conn, noteID int, userID, name string) {
+	c := &client{
+		id:     newClientID(),
+		userID: userID,
+		name:   name,
+		conn:   conn,
+		send:   make(chan []byte, sendBuffer),
+		done:   make(chan struct{}),
+	}
+
+	conn.SetReadLimit(maxMessageBytes)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	h.sessions.Add(1)
+	defer h.sessions.Done()
+
+	r := h.join(noteID, c)
+	go c.writeLoop()
+	defer h.leave(r, c)
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			c.queue(encode(message{Type: "error", Message: "invalid message"}))
+			continue
+		}
+		h.handle(r, c, &msg)
+	}
+}
+
+func (c *client) writeLoop() {
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+	defer c.close()
+
+	for {
+		select {
+		case data := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(textMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(pingMessage, nil); err != nil {
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// Close disconnects every client and returns once their sessions have ended,
+// which saves the pending snapshots. Clients reconnect and start again from
+// the saved note.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	for _, r := range h.rooms {
+		for c := range r.clients {
+			c.close()
+		}
+	}
+	h.mu.Unlock()
+
+	h.sessions.Wait()
+}
+
+func (h *Hub) join(noteID int, c *client) *room {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r := h.rooms[noteID]
+	initial := r == nil
+	if initial {
+		r = &room{noteID: noteID, clients: map[*client]bool{}}
+		h.rooms[noteID] = r
+	}
+
+	updates := make([][]byte, len(r.updates))
+	copy(updates, r.updates)
+	c.queue(encode(message{Type: "sync", ClientID: c.id, Initial: initial, Updates: updates}))
+
+	r.clients[c] = true
+	h.broadcastPresence(r)
+	return r
+}
+
+func (h *Hub) leave(r *room, c *client) {
+	c.close()
+
+	h.mu.Lock()
+	delete(r.clients, c)
+	if r.compacting == c {
+		r.compacting = nil
+	}
+	empty := len(r.clients) == 0
+	if empty {
+		delete(h.rooms, r.noteID)
+		if r.timer != nil {
+			r.timer.Stop()
+		}
+	} else {
+		h.broadcastPresence(r)
+	}
+	h.mu.Unlock()
+
+	if empty {
+		h.save(r)
+	}
+}
+
+func (h *Hub) handle(r *room, c *client, msg *message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch msg.Type {
+	case "update":
+		if len(msg.Update) == 0 {
+			return
+		}
+		r.updates = append(r.updates, msg.Update)
+		r.size += len(msg.Update)
+		h.broadcast(r, c, message{Type: "update", ClientID: c.id, Update: msg.Update})
+		if r.size > maxSessionBytes && r.compacting == nil {
+			r.compacting = c
+			r.compactFrom = len(r.updates)
+			c.queue(encode(message{Type: "compact"}))
+		}
+	case "state":
+		if r.compacting != c || len(msg.Update) == 0 {
+			return
+		}
+		// Updates that arrived after the request may be missing from the
+		// state, so they are kept. Yjs ignores the ones applied twice.
+		updates := append([][]byte{msg.Update}, r.updates[r.compactFrom:]...)
+		r.updates = updates
+		r.size = 0
+		for _, update := range updates {
+			r.size += len(update)
+		}
+		r.compacting = nil
+	case "awareness":
+		c.state = msg.State
+		h.broadcast(r, c, message{Type: "awareness", ClientID: c.id, State: msg.State})
+	case "snapshot":
+		if msg.Content == "" {
+			return
+		}
+		r.snapshot = msg.Content
+		r.snapshotBy = c.userID
+		if r.timer != nil {
+			r.timer.Stop()
+		}
+		r.timer = time.AfterFunc(saveDebounce, func() { h.save(r) })
+	default:
+		c.queue(encode(message{Type: "error", Message: "unknown message type " + msg.Type}))
+	}
+}
+
+// save writes the pending snapshot of the room, if any, to the note. It runs
+// after the debounce, outside of any request, and may outlive the client
+// whose snapshot it saves.
+func (h *Hub) save(r *room) {
+	r.saveMu.Lock()
+	defer r.saveMu.Unlock()
+
+	h.mu.Lock()
+	content, userID := r.snapshot, r.snapshotBy
+	r.snapshot = ""
+	h.mu.Unlock()
+	if content == "" {
+		return
+	}
+
+	version, err := h.store.SaveContent(context.Background(), r.noteID, content, userID)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err != nil {
+		log.Printf("collab: failed to save note %d: %v", r.noteID, err)
+		h.broadcast(r, nil, message{Type: "error", Message: "failed to save the note"})
+		return
+	}
+	h.broadcast(r, nil, message{Type: "saved", Version: version})
+}
+
+// broadcast queues msg for every client of the room but except. The caller
+// must hold h.mu.
+func (h *Hub) broadcast(r *room, except *client, msg message) {
+	data := encode(msg)
+	for c := range r.clients {
+		if c != except {
+			c.queue(data)
+		}
+	}
+}
+
+func (h *Hub) broadcastPresence(r *room) {
+	peers := make([]peer, 0, len(r.clients))
+	for c := range r.clients {
+		peers = append(peers, peer{ClientID: c.id, UserID: c.userID, Name: c.name, State: c.state})
+	}
+	h.broadcast(r, nil, message{Type: "presence", Peers: peers})
+}
+
+func encode(msg message) []byte {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func newClientID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,49 @@
+package collab
+
+import "encoding/json"
+
+// Messages are JSON text frames with a "type" field. Yjs updates travel
+// base64 encoded, which encoding/json does for []byte.
+//
+// Client to server:
+//
+//	update    a Yjs update, relayed to the other clients
+//	state     the full Yjs state as one update, sent in reply to compact
+//	awareness the sender's presence state (cursor, selection, ...)
+//	snapshot  the merged document as TipTap JSON, saved to the note
+//
+// Server to client:
+//
+//	sync      sent once after connecting, with the updates of the session
+//	update    a Yjs update from another client
+//	awareness a presence state from another client
+//	presence  the clients in the session and their presence state
+//	compact   asks the client for a state message
+//	saved     the latest snapshot was written to the note
+//	error     a message could not be handled
+type message struct {
+	Type string `json:"type"`
+
+	// ClientID identifies the sending client in messages from the server.
+	ClientID string          `json:"clientId,omitempty"`
+	Update   []byte          `json:"update,omitempty"`
+	State    json.RawMessage `json:"state,omitempty"`
+	Content  string          `json:"content,omitempty"`
+
+	// sync
+	Initial bool     `json:"initial,omitempty"` // no session yet, seed from the note
+	Updates [][]byte `json:"updates,omitempty"`
+
+	// presence
+	Peers []peer `json:"peers,omitempty"`
+
+	Version int    `json:"version,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type peer struct {
+	ClientID string          `json:"clientId"`
+	UserID   string          `json:"userId"`
+	Name     string          `json:"name,omitempty"`
+	State    json.RawMessage `json:"state,omitempty"`
+}
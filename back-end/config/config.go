@@ -0,0 +1,346 @@
+// Package config loads the server configuration: built-in defaults, then the
+// YAML file named by CONFIG_FILE if there is one, then environment variables,
+// which win over the file. The result is validated once at startup.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+)
+
+// Config is the whole server configuration. The yaml tags name the keys of
+// the config file and the env tags the variables that override them.
+type Config struct {
+	// Env is "development" or "production". Production refuses to start
+	// with the insecure development defaults.
+	Env      string `yaml:"env" env:"APP_ENV"`
+	Port     int    `yaml:"port" env:"PORT"`
+	LogLevel string `yaml:"log_level" env:"LOG_LEVEL"` // debug, info, warn or error
+	// RequestTimeout bounds the work of each request, zero for no limit.
+	RequestTimeout time.Duration `yaml:"request_timeout" env:"REQUEST_TIMEOUT"`
+	// ShutdownTimeout is how long in-flight requests get to finish on
+	// SIGINT or SIGTERM before they are cancelled.
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	// CORSOrigins are the origins allowed to call the API, or "*" for any.
+	// In the environment they are separated by commas.
+	CORSOrigins []string `yaml:"cors_origins" env:"CORS_ALLOWED_ORIGINS"`
+
+	DB          Database    `yaml:"db"`
+	Auth        Auth        `yaml:"auth"`
+	Storage     Storage     `yaml:"storage"`
+	Attachments Attachments `yaml:"attachments"`
+	Retention   Retention   `yaml:"retention"`
+	RateLimits  RateLimits  `yaml:"rate_limits"`
+	BodyLimits  BodyLimits  `yaml:"body_limits"`
+	Features    Features    `yaml:"features"`
+}
+
+type Database struct {
+	Host     string `yaml:"host" env:"DB_HOST"`
+	Port     int    `yaml:"port" env:"DB_PORT"`
+	User     string `yaml:"user" env:"DB_USER"`
+	Password string `yaml:"password" env:"DB_PASSWORD"`
+	Name     string `yaml:"name" env:"DB_NAME"`
+	SSLMode  string `yaml:"sslmode" env:"DB_SSLMODE"`
+	// Pool settings, see sql.DB. Zero MaxOpenConns and ConnMaxLifetime mean
+	// no limit.
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	// QueryTimeout is the statement_timeout of the server's connections,
+	// zero for no limit. Migrations run without it.
+	QueryTimeout time.Duration `yaml:"query_timeout" env:"DB_QUERY_TIMEOUT"`
+	// AutoMigrate applies pending migrations when the server starts.
+	AutoMigrate bool `yaml:"auto_migrate" env:"DB_AUTO_MIGRATE"`
+}
+
+// DSN is the lib/pq connection string. Sessions run in UTC so timestamptz
+// values come back the same whatever the server is configured with.
+func (d Database) DSN() string {
+	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s timezone=UTC statement_timeout=%d",
+		quoteDSN(d.Host), d.Port, quoteDSN(d.User), quoteDSN(d.Password), quoteDSN(d.Name), quoteDSN(d.SSLMode),
+		d.QueryTimeout.Milliseconds())
+}
+
+// Auth selects the provider that issues bearer tokens: "firebase", "local"
+// for accounts stored in the users table, or "oidc". Firebase credentials are
+// still read by the Firebase service from FIREBASE_* variables.
+type Auth struct {
+	Provider string `yaml:"provider" env:"AUTH_PROVIDER"`
+	JWT      JWT    `yaml:"jwt"`
+	OIDC     OIDC   `yaml:"oidc"`
+}
+
+// JWT configures the tokens of the local provider.
+type JWT struct {
+	Algorithm      string        `yaml:"algorithm" env:"AUTH_JWT_ALG"`
+	Secret         string        `yaml:"secret" env:"AUTH_JWT_SECRET"`
+	PrivateKeyFile string        `yaml:"private_key_file" env:"AUTH_JWT_PRIVATE_KEY_FILE"`
+	Issuer         string        `yaml:"issuer" env:"AUTH_JWT_ISSUER"`
+	TTL            time.Duration `yaml:"ttl" env:"AUTH_JWT_TTL"`
+}
+
+type OIDC struct {
+	Issuer   string `yaml:"issuer" env:"AUTH_OIDC_ISSUER"`
+	Audience string `yaml:"audience" env:"AUTH_OIDC_AUDIENCE"`
+	JWKS     string `yaml:"jwks" env:"AUTH_OIDC_JWKS"`
+}
+
+// Storage selects where attachments and exports are kept: "local" or "s3".
+type Storage struct {
+	Driver   string `yaml:"driver" env:"STORAGE_DRIVER"`
+	LocalDir string `yaml:"local_dir" env:"STORAGE_LOCAL_DIR"`
+	S3       S3     `yaml:"s3"`
+}
+
+type S3 struct {
+	Endpoint  string `yaml:"endpoint" env:"STORAGE_S3_ENDPOINT"`
+	Region    string `yaml:"region" env:"STORAGE_S3_REGION"`
+	Bucket    string `yaml:"bucket" env:"STORAGE_S3_BUCKET"`
+	AccessKey string `yaml:"access_key" env:"STORAGE_S3_ACCESS_KEY"`
+	SecretKey string `yaml:"secret_key" env:"STORAGE_S3_SECRET_KEY"`
+}
+
+type Attachments struct {
+	MaxBytes   int64 `yaml:"max_bytes" env:"ATTACHMENT_MAX_BYTES"`
+	QuotaBytes int64 `yaml:"quota_bytes" env:"ATTACHMENT_QUOTA_BYTES"`
+	// URLSecret signs attachment and export download URLs. Without one a
+	// random secret is generated, so links do not survive a restart and are
+	// not valid on other replicas.
+	URLSecret string `yaml:"url_secret" env:"ATTACHMENT_URL_SECRET"`
+}
+
+type Retention struct {
+	// TrashDays is how long deleted items stay restorable.
+	TrashDays int `yaml:"trash_days" env:"TRASH_RETENTION_DAYS"`
+	// AccountDeletionGraceDays is how long a deleted account can still be
+	// recovered by logging in. Zero deletes it on the next purge.
+	AccountDeletionGraceDays int `yaml:"account_deletion_grace_days" env:"ACCOUNT_DELETION_GRACE_DAYS"`
+}
+
+func (r Retention) Trash() time.Duration {
+	return time.Duration(r.TrashDays) * 24 * time.Hour
+}
+
+func (r Retention) AccountDeletionGrace() time.Duration {
+	return time.Duration(r.AccountDeletionGraceDays) * 24 * time.Hour
+}
+
+// RateLimits are written "<requests>/<window>", for example "120/1m", or
+// "off". IP and Login apply before authentication, the others per user.
+// TimeWrites is one budget for the writes to time entries, time boxes and
+// the timer together. ShareLinks limits creating share links and Webhooks
+// every change to webhooks, replays included. Other requests only count
+// against User.
+type RateLimits struct {
+	IP         string `yaml:"ip" env:"RATE_LIMIT_IP"`
+	Login      string `yaml:"login" env:"RATE_LIMIT_LOGIN"`
+	User       string `yaml:"user" env:"RATE_LIMIT_USER"`
+	TimeWrites string `yaml:"time_writes" env:"RATE_LIMIT_TIME_WRITES"`
+	Export     string `yaml:"export" env:"RATE_LIMIT_EXPORT"`
+	Import     string `yaml:"import" env:"RATE_LIMIT_IMPORT"`
+	ShareLinks string `yaml:"share_links" env:"RATE_LIMIT_SHARE_LINKS"`
+	Webhooks   string `yaml:"webhooks" env:"RATE_LIMIT_WEBHOOKS"`
+}
+
+// BodyLimits cap request bodies in bytes: Notes for note and folder content,
+// Uploads for imports and attachments, Default for everything else.
+type BodyLimits struct {
+	Default int `yaml:"default" env:"BODY_LIMIT_DEFAULT"`
+	Notes   int `yaml:"notes" env:"BODY_LIMIT_NOTES"`
+	Uploads int `yaml:"uploads" env:"BODY_LIMIT_UPLOADS"`
+}
+
+// Features turn optional parts of the API on and off.
+type Features struct {
+	Swagger    bool `yaml:"swagger" env:"FEATURE_SWAGGER"`         // the /swagger UI
+	Signup     bool `yaml:"signup" env:"FEATURE_SIGNUP"`           // local account signup
+	ShareLinks bool `yaml:"share_links" env:"FEATURE_SHARE_LINKS"` // public note and folder links
+	Webhooks   bool `yaml:"webhooks" env:"FEATURE_WEBHOOKS"`
+	Collab     bool `yaml:"collab" env:"FEATURE_COLLAB"` // realtime note editing
+}
+
+// Default is the development configuration, matching docker-compose.
+func Default() *Config {
+	return &Config{
+		Env:             EnvDevelopment,
+		Port:            3000,
+		LogLevel:        "info",
+		RequestTimeout:  time.Minute,
+		ShutdownTimeout: 30 * time.Second,
+		CORSOrigins:     []string{"*"},
+		DB: Database{
+			Host:            "postgres",
+			Port:            5432,
+			User:            "postgres",
+			Password:        "postgres",
+			Name:            "app_db",
+			SSLMode:         "disable",
+			MaxOpenConns:    25,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: 30 * time.Minute,
+			QueryTimeout:    30 * time.Second,
+		},
+		Auth: Auth{
+			Provider: "firebase",
+			JWT:      JWT{Algorithm: "HS256", Issuer: "timetracker", TTL: 24 * time.Hour},
+		},
+		Storage: Storage{
+			Driver:   "local",
+			LocalDir: "data/attachments",
+			S3:       S3{Endpoint: "https://s3.amazonaws.com", Region: "us-east-1"},
+		},
+		Attachments: Attachments{MaxBytes: 10 << 20, QuotaBytes: 500 << 20},
+		Retention:   Retention{TrashDays: 30, AccountDeletionGraceDays: 30},
+		RateLimits: RateLimits{
+			IP:         "600/1m",
+			Login:      "20/1m",
+			User:       "300/1m",
+			TimeWrites: "60/1m",
+			Export:     "5/1h",
+			Import:     "30/1h",
+			ShareLinks: "60/1h",
+			Webhooks:   "30/1h",
+		},
+		BodyLimits: BodyLimits{Default: 1 << 20, Notes: 8 << 20, Uploads: 32 << 20},
+		Features:   Features{Swagger: true, Signup: true, ShareLinks: true, Webhooks: true, Collab: true},
+	}
+}
+
+// Load reads and validates the configuration.
+func Load() (*Config, error) {
+	config := Default()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := config.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := loadEnv(reflect.ValueOf(config).Elem()); err != nil {
+		return nil, err
+	}
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// loadFile reads a YAML file over the defaults. Unknown keys are errors, so
+// that a typo does not silently leave a default in place.
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(c); err != nil && err != io.EOF {
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) Production() bool {
+	return c.Env == EnvProduction
+}
+
+// Validate reports every invalid setting at once. In production it also
+// rejects the development defaults that are not safe to run with.
+func (c *Config) Validate() error {
+	var problems []string
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Sprintf(format, args...))
+		}
+	}
+
+	check(c.Env == EnvDevelopment || c.Env == EnvProduction, "APP_ENV must be development or production")
+	check(c.Port > 0 && c.Port <= 65535, "PORT must be between 1 and 65535")
+	check(oneOf(c.LogLevel, "debug", "info", "warn", "error"), "LOG_LEVEL must be debug, info, warn or error")
+	check(c.RequestTimeout >= 0, "REQUEST_TIMEOUT must not be negative")
+	check(c.ShutdownTimeout > 0, "SHUTDOWN_TIMEOUT must be positive")
+	check(len(c.CORSOrigins) > 0, "CORS_ALLOWED_ORIGINS must not be empty")
+	for _, origin := range c.CORSOrigins {
+		check(origin == "*" || validOrigin(origin), "CORS_ALLOWED_ORIGINS: %q is not an origin such as https://app.example.com", origin)
+	}
+
+	check(c.DB.Host != "" && c.DB.User != "" && c.DB.Name != "", "DB_HOST, DB_USER and DB_NAME are required")
+	check(c.DB.Port > 0 && c.DB.Port <= 65535, "DB_PORT must be between 1 and 65535")
+	check(oneOf(c.DB.SSLMode, "disable", "require", "verify-ca", "verify-full"), "DB_SSLMODE must be disable, require, verify-ca or verify-full")
+	check(c.DB.MaxOpenConns >= 0, "DB_MAX_OPEN_CONNS must not be negative")
+	check(c.DB.MaxIdleConns >= 0, "DB_MAX_IDLE_CONNS must not be negative")
+	check(c.DB.MaxOpenConns == 0 || c.DB.MaxIdleConns <= c.DB.MaxOpenConns, "DB_MAX_IDLE_CONNS must not exceed DB_MAX_OPEN_CONNS")
+	check(c.DB.ConnMaxLifetime >= 0, "DB_CONN_MAX_LIFETIME must not be negative")
+	check(c.DB.QueryTimeout >= 0, "DB_QUERY_TIMEOUT must not be negative")
+
+	check(oneOf(c.Auth.Provider, "firebase", "local", "oidc"), "AUTH_PROVIDER must be firebase, local or oidc")
+	check(c.Auth.JWT.TTL > 0, "AUTH_JWT_TTL must be positive")
+	check(oneOf(c.Storage.Driver, "local", "s3"), "STORAGE_DRIVER must be local or s3")
+
+	check(c.Attachments.MaxBytes > 0, "ATTACHMENT_MAX_BYTES must be positive")
+	check(c.Attachments.QuotaBytes > 0, "ATTACHMENT_QUOTA_BYTES must be positive")
+	check(c.Retention.TrashDays >= 1, "TRASH_RETENTION_DAYS must be at least 1")
+	check(c.Retention.AccountDeletionGraceDays >= 0, "ACCOUNT_DELETION_GRACE_DAYS must not be negative")
+	check(c.BodyLimits.Default > 0 && c.BodyLimits.Notes > 0 && c.BodyLimits.Uploads > 0, "BODY_LIMIT_* must be positive")
+
+	if c.Production() {
+		check(c.DB.Password != "" && c.DB.Password != "postgres", "DB_PASSWORD must be changed from the default in production")
+		check(!contains(c.CORSOrigins, "*"), "CORS_ALLOWED_ORIGINS must list the front-end origins in production, not *")
+		check(c.Attachments.URLSecret != "", "ATTACHMENT_URL_SECRET is required in production")
+	}
+
+	if len(problems) > 0 {
+		return errors.New("invalid configuration:\n  " + strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// validOrigin accepts a scheme and host, with an optional port, and nothing
+// else. Browsers send origins without a trailing slash and they are compared
+// as strings.
+func validOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		u.Path == "" && u.RawQuery == "" && u.Fragment == "" && u.User == nil
+}
+
+// quoteDSN quotes a connection string value when it is empty or contains
+// characters lib/pq would otherwise split on.
+func quoteDSN(value string) string {
+	if value != "" && !strings.ContainsAny(value, " '\\") {
+		return value
+	}
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+func oneOf(value string, allowed ...string) bool {
+	return contains(allowed, value)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type AccessTokenController struct {
+	service *services.AccessTokenService
+}
+
+func NewAccessTokenController(service *services.AccessTokenService) *AccessTokenController {
+	return &AccessTokenController{service: service}
+}
+
+// @Summary Get personal access tokens
+// @Description Get the user's personal access tokens that are not revoked. Token values are not included.
+// @Tags tokens
+// @Produce json
+// @Success 200 {object} models.ApiResponse[[]models.PersonalAccessToken]
+// @Failure 500 {object} models.ApiErrorResponse
+// @Router /me/tokens [get]
+func (c *AccessTokenController) GetTokens(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(string)
+	tokens, err := c.service.GetTokens(ctx.UserContext(), userID)
+	if err != nil {
+		return ctx.Status(500).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[[]*models.PersonalAccessToken]{
+		Success: true,
+		Data:    tokens,
+	})
+}
+
+// @Summary Create personal access token
+// @Description Issue a token for scripts, sent as "Authorization: Bearer <token>". Scopes: read, write, time:read, time:write, notes:read, notes:write; write includes read. The token value is only returned here. time:* covers time entries, time boxes, the timer and projects; notes:* covers notes with their tasks and templates, folders, tags, attachments and shares. Restoring from the trash needs the scope of the item; listing the trash, webhooks, events, exports and settings need read or write. Tokens cannot manage tokens.
+// @Tags tokens
+// @Accept json
+// @Produce json
+// @Param token body models.PersonalAccessTokenCreate true "Token data"
+// @Success 201 {object} models.ApiResponse[models.PersonalAccessToken]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Router /me/tokens [post]
+func (c *AccessTokenController) CreateToken(ctx *fiber.Ctx) error {
+	var token models.PersonalAccessTokenCreate
+	if err := ctx.BodyParser(&token); err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid request body",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	created, err := c.service.CreateToken(ctx.UserContext(), &token, userID)
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(201).JSON(models.ApiResponse[*models.PersonalAccessToken]{
+		Success: true,
+		Data:    created,
+		Message: "Token created successfully",
+	})
+}
+
+// @Summary Revoke personal access token
+// @Description Revoke a token; requests using it are rejected from now on
+// @Tags tokens
+// @Produce json
+// @Param id path int true "Token ID"
+// @Success 200 {object} models.ApiResponse[interface{}]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /me/tokens/{id} [delete]
+func (c *AccessTokenController) RevokeToken(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid token ID",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	if err := c.service.RevokeToken(ctx.UserContext(), id, userID); err != nil {
+		return ctx.Status(404).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[interface{}]{
+		Success: true,
+		Data:    nil,
+		Message: "Token revoked successfully",
+	})
+}
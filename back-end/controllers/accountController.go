@@ -0,0 +1,39 @@
+package controllers
+
+import (
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type AccountController struct {
+	service *services.AccountService
+}
+
+func NewAccountController(service *services.AccountService) *AccountController {
+	return &AccountController{service: service}
+}
+
+// @Summary Delete account
+// @Description Schedule the account and all its data for deletion after the grace period (ACCOUNT_DELETION_GRACE_DAYS, 30 days by default). Logging in through POST /auth/login before then cancels it. Asking again keeps the first schedule.
+// @Tags account
+// @Produce json
+// @Success 202 {object} models.ApiResponse[models.AccountDeletion]
+// @Failure 500 {object} models.ApiErrorResponse
+// @Router /me [delete]
+func (c *AccountController) DeleteAccount(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(string)
+	deletion, err := c.service.ScheduleDeletion(ctx.UserContext(), userID)
+	if err != nil {
+		return ctx.Status(500).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(202).JSON(models.ApiResponse[*models.AccountDeletion]{
+		Success: true,
+		Data:    deletion,
+		Message: "Account deletion scheduled",
+	})
+}
@@ -0,0 +1,276 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/services"
+	"github.com/RiadMefti/TimeTracker/back-end/storage"
+	"github.com/RiadMefti/TimeTracker/back-end/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+type AttachmentController struct {
+	service *services.AttachmentService
+}
+
+func NewAttachmentController(service *services.AttachmentService) *AttachmentController {
+	return &AttachmentController{service: service}
+}
+
+// @Summary Upload attachment
+// @Description Upload a file and attach it to a note. Images, PDF, zip and plain text files are accepted.
+// @Tags attachments
+// @Accept multipart/form-data
+// @Produce json
+// @Param id path int true "Note ID"
+// @Param file formData file true "File to attach"
+// @Success 201 {object} models.ApiResponse[models.Attachment]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Failure 413 {object} models.ApiErrorResponse
+// @Failure 415 {object} models.ApiErrorResponse
+// @Failure 500 {object} models.ApiErrorResponse
+// @Router /notes/{id}/attachments [post]
+func (c *AttachmentController) UploadAttachment(ctx *fiber.Ctx) error {
+	noteID, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid note ID",
+		})
+	}
+
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Missing file",
+		})
+	}
+	if fileHeader.Size > c.service.MaxBytes() {
+		return ctx.Status(413).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: models.ErrAttachmentTooLarge.Error(),
+		})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return ctx.Status(500).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Failed to read file",
+		})
+	}
+	defer file.Close()
+
+	userID := ctx.Locals("userID").(string)
+	fileName := utils.SafeFileName(fileHeader.Filename, "file")
+	attachment, err := c.service.Upload(ctx.UserContext(), noteID, fileName, file, userID)
+	if err != nil {
+		status := 500
+		switch {
+		case errors.Is(err, models.ErrAttachmentTooLarge), errors.Is(err, models.ErrQuotaExceeded):
+			status = 413
+		case errors.Is(err, models.ErrUnsupportedMediaType):
+			status = 415
+		case errors.Is(err, models.ErrNoteNotFound):
+			status = 404
+		case errors.Is(err, models.ErrEmptyAttachment):
+			status = 400
+		}
+		return ctx.Status(status).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	c.absoluteURL(ctx, attachment)
+	return ctx.Status(201).JSON(models.ApiResponse[*models.Attachment]{
+		Success: true,
+		Data:    attachment,
+	})
+}
+
+// @Summary Get note attachments
+// @Description Get the attachments of a note with fresh download URLs
+// @Tags attachments
+// @Produce json
+// @Param id path int true "Note ID"
+// @Success 200 {object} models.ApiResponse[[]models.Attachment]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /notes/{id}/attachments [get]
+func (c *AttachmentController) GetNoteAttachments(ctx *fiber.Ctx) error {
+	noteID, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid note ID",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	attachments, err := c.service.GetNoteAttachments(ctx.UserContext(), noteID, userID)
+	if err != nil {
+		return ctx.Status(404).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	c.absoluteURL(ctx, attachments...)
+	return ctx.JSON(models.ApiResponse[[]*models.Attachment]{
+		Success: true,
+		Data:    attachments,
+	})
+}
+
+// @Summary Get attachment storage usage
+// @Description Get the storage used by the user's attachments and their quota, in bytes
+// @Tags attachments
+// @Produce json
+// @Success 200 {object} models.ApiResponse[models.AttachmentUsage]
+// @Failure 500 {object} models.ApiErrorResponse
+// @Router /attachments/usage [get]
+func (c *AttachmentController) GetUsage(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(string)
+	usage, err := c.service.GetUsage(ctx.UserContext(), userID)
+	if err != nil {
+		return ctx.Status(500).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[*models.AttachmentUsage]{
+		Success: true,
+		Data:    usage,
+	})
+}
+
+// @Summary Get attachment
+// @Description Get an attachment with a fresh download URL
+// @Tags attachments
+// @Produce json
+// @Param id path int true "Attachment ID"
+// @Success 200 {object} models.ApiResponse[models.Attachment]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /attachments/{id} [get]
+func (c *AttachmentController) GetAttachment(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid attachment ID",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	attachment, err := c.service.GetAttachment(ctx.UserContext(), id, userID)
+	if err != nil {
+		return ctx.Status(404).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	c.absoluteURL(ctx, attachment)
+	return ctx.JSON(models.ApiResponse[*models.Attachment]{
+		Success: true,
+		Data:    attachment,
+	})
+}
+
+// @Summary Delete attachment
+// @Description Delete an attachment. Its content is removed once no other attachment uses it.
+// @Tags attachments
+// @Produce json
+// @Param id path int true "Attachment ID"
+// @Success 200 {object} models.ApiResponse[string]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /attachments/{id} [delete]
+func (c *AttachmentController) DeleteAttachment(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid attachment ID",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	if err := c.service.DeleteAttachment(ctx.UserContext(), id, userID); err != nil {
+		return ctx.Status(404).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[string]{
+		Success: true,
+		Data:    "Attachment deleted successfully",
+	})
+}
+
+// @Summary Download attachment
+// @Description Download an attachment through a signed URL. No Authorization header is needed, so the URL can be used in img tags.
+// @Tags attachments
+// @Produce octet-stream
+// @Param id path int true "Attachment ID"
+// @Param expires query int true "Expiry as a Unix timestamp"
+// @Param signature query string true "URL signature"
+// @Success 200 {file} file
+// @Failure 403 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /attachments/{id}/download [get]
+func (c *AttachmentController) DownloadAttachment(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid attachment ID",
+		})
+	}
+
+	expires, _ := strconv.ParseInt(ctx.Query("expires"), 10, 64)
+	attachment, content, err := c.service.Open(ctx.UserContext(), id, expires, ctx.Query("signature"))
+	if err != nil {
+		status := 500
+		switch {
+		case errors.Is(err, models.ErrInvalidDownloadLink):
+			status = 403
+		case errors.Is(err, storage.ErrBlobNotFound), errors.Is(err, models.ErrAttachmentNotFound):
+			status = 404
+		}
+		return ctx.Status(status).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	// Only images are shown inline; everything else is downloaded so the
+	// browser never renders it in the API's origin.
+	disposition := "attachment"
+	if strings.HasPrefix(attachment.MimeType, "image/") {
+		disposition = "inline"
+	}
+	ctx.Set(fiber.HeaderContentDisposition, mime.FormatMediaType(disposition, map[string]string{"filename": attachment.FileName}))
+	ctx.Set(fiber.HeaderContentType, attachment.MimeType)
+	ctx.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	ctx.Set(fiber.HeaderCacheControl, fmt.Sprintf("private, max-age=%d", max(expires-time.Now().Unix(), 0)))
+	return ctx.SendStream(content, int(attachment.Size))
+}
+
+func (c *AttachmentController) absoluteURL(ctx *fiber.Ctx, attachments ...*models.Attachment) {
+	for _, attachment := range attachments {
+		attachment.URL = ctx.BaseURL() + attachment.URL
+	}
+}
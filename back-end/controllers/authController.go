@@ -8,17 +8,19 @@ import (
 )
 
 type AuthController struct {
-	authService *services.AuthService
+	authService    *services.AuthService
+	accountService *services.AccountService
 }
 
-func NewAuthController(authService *services.AuthService) *AuthController {
+func NewAuthController(authService *services.AuthService, accountService *services.AccountService) *AuthController {
 	return &AuthController{
-		authService: authService,
+		authService:    authService,
+		accountService: accountService,
 	}
 }
 
 // @Summary Create or log a user
-// @Description Create or log a user
+// @Description Create or log a user. Logging in cancels a scheduled account deletion whose grace period is not over.
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -39,12 +41,20 @@ func (u *AuthController) LoginUser(c *fiber.Ctx) error {
 		Email: userAuth.Email,
 	}
 
-	created, err := u.authService.RegisterUser(user)
+	created, err := u.authService.RegisterUser(c.UserContext(), user)
 	if err != nil {
 		return c.Status(fiber.StatusUnauthorized).JSON(utils.CreateApiResponse[interface{}](false, nil, "Error while creating user"))
 	}
 	if created {
 		return c.Status(fiber.StatusCreated).JSON(utils.CreateApiResponse(true, user, "user Created"))
 	}
+
+	cancelled, err := u.accountService.CancelDeletion(c.UserContext(), user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "Error while cancelling account deletion"))
+	}
+	if cancelled {
+		return c.Status(fiber.StatusOK).JSON(utils.CreateApiResponse(true, user, "user logged in and account deletion cancelled"))
+	}
 	return c.Status(fiber.StatusOK).JSON(utils.CreateApiResponse(true, user, "user Exists and logged in"))
 }
@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/RiadMefti/TimeTracker/back-end/collab"
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/services"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+type CollabController struct {
+	hub     *collab.Hub
+	service *services.NoteService
+}
+
+func NewCollabController(hub *collab.Hub, service *services.NoteService) *CollabController {
+	return &CollabController{hub: hub, service: service}
+}
+
+// @Summary Collaborate on a note
+// @Description Open a WebSocket session relaying Yjs updates, presence and cursors between the clients editing a note. Snapshots sent by clients are saved to the note. Browsers that cannot set the Authorization header may pass the token as access_token instead. See the collab package for the message format.
+// @Tags notes
+// @Param id path int true "Note ID"
+// @Param access_token query string false "Firebase ID token"
+// @Success 101 "switching protocols"
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Failure 426 {object} models.ApiErrorResponse
+// @Router /notes/{id}/collab [get]
+func (c *CollabController) Upgrade(ctx *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(ctx) {
+		return ctx.Status(fiber.StatusUpgradeRequired).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "WebSocket upgrade required",
+		})
+	}
+
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid note ID",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	if _, err := c.service.GetNote(ctx.UserContext(), id, userID); err != nil {
+		return ctx.Status(404).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	ctx.Locals("noteID", id)
+	return ctx.Next()
+}
+
+// Session runs an upgraded connection checked by Upgrade.
+func (c *CollabController) Session(conn *websocket.Conn) {
+	noteID := conn.Locals("noteID").(int)
+	userID := conn.Locals("userID").(string)
+
+	var name string
+	if user, ok := conn.Locals("user").(*models.Identity); ok {
+		name = user.Name
+		if name == "" {
+			name = user.Email
+		}
+	}
+
+	c.hub.Serve(conn.Conn, noteID, userID, name)
+}
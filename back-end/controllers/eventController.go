@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"bufio"
+	"fmt"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/events"
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	eventRetry     = 3 * time.Second
+	eventHeartbeat = 25 * time.Second
+)
+
+type EventController struct {
+	bus *events.Bus
+}
+
+func NewEventController(bus *events.Bus) *EventController {
+	return &EventController{bus: bus}
+}
+
+// @Summary Stream data changes
+// @Description Server-Sent Events stream of the changes made to the user's data, from any device. The event name is the change type (time_entry.created, timer.started, note.updated, project.deleted, ...). Created and updated events carry the entity, and deleted events {"ID": id}; timer.stopped carries the recorded time entry.
+// @Description Reconnecting with Last-Event-ID (or ?lastEventId=) replays the missed events. When they are no longer available, as can happen after more than 15 minutes without a connection, a "reset" event is sent first and the client should reload its data. EventSource clients can pass their token as ?access_token=.
+// @Tags events
+// @Produce text/event-stream
+// @Param Last-Event-ID header string false "ID of the last event received"
+// @Success 200 {string} string "event stream"
+// @Router /events [get]
+func (c *EventController) Stream(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(string)
+	lastEventID := ctx.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = ctx.Query("lastEventId")
+	}
+
+	missed, complete, sub := c.bus.Subscribe(userID, lastEventID)
+
+	ctx.Set(fiber.HeaderContentType, "text/event-stream")
+	ctx.Set(fiber.HeaderCacheControl, "no-cache")
+	ctx.Set(fiber.HeaderConnection, "keep-alive")
+	// Keep reverse proxies from buffering the stream.
+	ctx.Set("X-Accel-Buffering", "no")
+
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer c.bus.Unsubscribe(sub)
+
+		fmt.Fprintf(w, "retry: %d\n\n", eventRetry.Milliseconds())
+		if !complete {
+			fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+		}
+		for _, event := range missed {
+			writeEvent(w, event)
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		heartbeat := time.NewTicker(eventHeartbeat)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case event, ok := <-sub.C:
+				if !ok {
+					// Dropped for falling behind; the client reconnects
+					// and resumes from its last event.
+					return
+				}
+				writeEvent(w, event)
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": heartbeat\n\n")
+			}
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+	return nil
+}
+
+// writeEvent writes event in the SSE format. The data is single-line JSON.
+func writeEvent(w *bufio.Writer, event events.Event) {
+	fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"strconv"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/services"
+	"github.com/RiadMefti/TimeTracker/back-end/storage"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ExportController struct {
+	service *services.ExportService
+}
+
+func NewExportController(service *services.ExportService) *ExportController {
+	return &ExportController{service: service}
+}
+
+// @Summary Export account data
+// @Description Start building a zip of all the user's projects, time entries, time boxes, folders and notes, as JSON plus Markdown copies of the notes. The archive layout is described in its README.md. Poll GET /me/export/{id} for the download link. An export already in progress is returned instead of starting another.
+// @Tags export
+// @Produce json
+// @Success 202 {object} models.ApiResponse[models.DataExport]
+// @Failure 500 {object} models.ApiErrorResponse
+// @Router /me/export [post]
+func (c *ExportController) CreateExport(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(string)
+	export, err := c.service.CreateExport(ctx.UserContext(), userID)
+	if err != nil {
+		return ctx.Status(500).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(202).JSON(models.ApiResponse[*models.DataExport]{
+		Success: true,
+		Data:    export,
+		Message: "Export started",
+	})
+}
+
+// @Summary Get export status
+// @Description Get the status of an export: pending, running, done or failed. Done exports carry a download URL valid for an hour; the archive itself is kept for a week.
+// @Tags export
+// @Produce json
+// @Param id path int true "Export ID"
+// @Success 200 {object} models.ApiResponse[models.DataExport]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /me/export/{id} [get]
+func (c *ExportController) GetExport(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid export ID",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	export, err := c.service.GetExport(ctx.UserContext(), id, userID)
+	if err != nil {
+		status := 500
+		if errors.Is(err, models.ErrExportNotFound) {
+			status = 404
+		}
+		return ctx.Status(status).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	if export.URL != "" {
+		export.URL = ctx.BaseURL() + export.URL
+	}
+	return ctx.JSON(models.ApiResponse[*models.DataExport]{
+		Success: true,
+		Data:    export,
+	})
+}
+
+// @Summary Download export
+// @Description Download an export archive through a signed URL. No Authorization header is needed.
+// @Tags export
+// @Produce application/zip
+// @Param id path int true "Export ID"
+// @Param expires query int true "Expiry as a Unix timestamp"
+// @Param signature query string true "URL signature"
+// @Success 200 {file} file
+// @Failure 403 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /me/export/{id}/download [get]
+func (c *ExportController) DownloadExport(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid export ID",
+		})
+	}
+
+	expires, _ := strconv.ParseInt(ctx.Query("expires"), 10, 64)
+	export, content, err := c.service.Open(ctx.UserContext(), id, expires, ctx.Query("signature"))
+	if err != nil {
+		status := 500
+		switch {
+		case errors.Is(err, models.ErrInvalidDownloadLink):
+			status = 403
+		case errors.Is(err, models.ErrExportNotFound), errors.Is(err, storage.ErrBlobNotFound):
+			status = 404
+		}
+		return ctx.Status(status).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	fileName := fmt.Sprintf("timetracker-export-%s.zip", export.Created.UTC().Format("2006-01-02"))
+	ctx.Set(fiber.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+	ctx.Set(fiber.HeaderContentType, "application/zip")
+	ctx.Set(fiber.HeaderCacheControl, fmt.Sprintf("private, max-age=%d", max(expires-time.Now().Unix(), 0)))
+	return ctx.SendStream(content, int(export.Size))
+}
@@ -1,10 +1,14 @@
 package controllers
 
 import (
+	"bufio"
+	"errors"
+	"log"
 	"strconv"
 
 	"github.com/RiadMefti/TimeTracker/back-end/models"
 	"github.com/RiadMefti/TimeTracker/back-end/services"
+	"github.com/RiadMefti/TimeTracker/back-end/utils"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -36,7 +40,7 @@ func (c *FolderController) CreateFolder(ctx *fiber.Ctx) error {
 	}
 
 	userID := ctx.Locals("userID").(string)
-	folder, err := c.service.CreateFolder(&folderCreate, userID)
+	folder, err := c.service.CreateFolder(ctx.UserContext(), &folderCreate, userID)
 	if err != nil {
 		return ctx.Status(500).JSON(models.ApiErrorResponse{
 			Success: false,
@@ -60,7 +64,7 @@ func (c *FolderController) CreateFolder(ctx *fiber.Ctx) error {
 // @Router /folders [get]
 func (c *FolderController) GetAllFolders(ctx *fiber.Ctx) error {
 	userID := ctx.Locals("userID").(string)
-	folders, err := c.service.GetAllFolders(userID)
+	folders, err := c.service.GetAllFolders(ctx.UserContext(), userID)
 	if err != nil {
 		return ctx.Status(500).JSON(models.ApiErrorResponse{
 			Success: false,
@@ -99,7 +103,7 @@ func (c *FolderController) GetFolder(ctx *fiber.Ctx) error {
 	}
 
 	userID := ctx.Locals("userID").(string)
-	folder, err := c.service.GetFolder(id, userID)
+	folder, err := c.service.GetFolder(ctx.UserContext(), id, userID)
 	if err != nil {
 		return ctx.Status(404).JSON(models.ApiErrorResponse{
 			Success: false,
@@ -107,6 +111,7 @@ func (c *FolderController) GetFolder(ctx *fiber.Ctx) error {
 		})
 	}
 
+	ctx.Set(fiber.HeaderETag, utils.FormatETag(folder.Version))
 	return ctx.JSON(models.ApiResponse[*models.Folder]{
 		Success: true,
 		Data:    folder,
@@ -114,15 +119,17 @@ func (c *FolderController) GetFolder(ctx *fiber.Ctx) error {
 }
 
 // @Summary Update folder
-// @Description Update an existing folder
+// @Description Update an existing folder. Send the ETag from a previous read in If-Match to reject stale writes.
 // @Tags folders
 // @Accept json
 // @Produce json
 // @Param id path int true "Folder ID"
+// @Param If-Match header string false "ETag of the version being updated"
 // @Param folder body models.FolderUpdate true "Updated folder data"
 // @Success 200 {object} models.ApiResponse[models.Folder]
 // @Failure 400 {object} models.ApiErrorResponse
 // @Failure 404 {object} models.ApiErrorResponse
+// @Failure 412 {object} models.ApiResponse[models.Folder] "current server copy"
 // @Failure 500 {object} models.ApiErrorResponse
 // @Router /folders/{id} [put]
 func (c *FolderController) UpdateFolder(ctx *fiber.Ctx) error {
@@ -142,8 +149,31 @@ func (c *FolderController) UpdateFolder(ctx *fiber.Ctx) error {
 		})
 	}
 
+	expectedVersion, err := utils.ParseIfMatch(ctx)
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
 	userID := ctx.Locals("userID").(string)
-	folder, err := c.service.UpdateFolder(id, &folderUpdate, userID)
+	folder, err := c.service.UpdateFolder(ctx.UserContext(), id, &folderUpdate, userID, expectedVersion)
+	if errors.Is(err, models.ErrVersionConflict) {
+		current, err := c.service.GetFolder(ctx.UserContext(), id, userID)
+		if err != nil {
+			return ctx.Status(404).JSON(models.ApiErrorResponse{
+				Success: false,
+				Message: err.Error(),
+			})
+		}
+		ctx.Set(fiber.HeaderETag, utils.FormatETag(current.Version))
+		return ctx.Status(412).JSON(models.ApiResponse[*models.Folder]{
+			Success: false,
+			Data:    current,
+			Message: "Folder was modified by another request",
+		})
+	}
 	if err != nil {
 		return ctx.Status(404).JSON(models.ApiErrorResponse{
 			Success: false,
@@ -151,6 +181,7 @@ func (c *FolderController) UpdateFolder(ctx *fiber.Ctx) error {
 		})
 	}
 
+	ctx.Set(fiber.HeaderETag, utils.FormatETag(folder.Version))
 	return ctx.JSON(models.ApiResponse[*models.Folder]{
 		Success: true,
 		Data:    folder,
@@ -159,7 +190,7 @@ func (c *FolderController) UpdateFolder(ctx *fiber.Ctx) error {
 }
 
 // @Summary Delete folder
-// @Description Delete a folder and all its contents
+// @Description Move a folder and all its contents to the trash
 // @Tags folders
 // @Produce json
 // @Param id path int true "Folder ID"
@@ -178,7 +209,7 @@ func (c *FolderController) DeleteFolder(ctx *fiber.Ctx) error {
 	}
 
 	userID := ctx.Locals("userID").(string)
-	err = c.service.DeleteFolder(id, userID)
+	err = c.service.DeleteFolder(ctx.UserContext(), id, userID)
 	if err != nil {
 		return ctx.Status(404).JSON(models.ApiErrorResponse{
 			Success: false,
@@ -211,7 +242,7 @@ func (c *FolderController) GetFoldersByParent(ctx *fiber.Ctx) error {
 		}
 	}
 
-	folders, err := c.service.GetFoldersByParent(parentID, userID)
+	folders, err := c.service.GetFoldersByParent(ctx.UserContext(), parentID, userID)
 	if err != nil {
 		return ctx.Status(500).JSON(models.ApiErrorResponse{
 			Success: false,
@@ -229,3 +260,139 @@ func (c *FolderController) GetFoldersByParent(ctx *fiber.Ctx) error {
 		Data:    folders,
 	})
 }
+
+// @Summary Reorder folders
+// @Description Set the order of the subfolders of a parent. Subfolders that are not listed keep their relative order after the listed ones.
+// @Tags folders
+// @Accept json
+// @Produce json
+// @Param order body models.FolderOrder true "Parent and folder IDs in the new order"
+// @Success 200 {object} models.ApiResponse[[]models.Folder]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 500 {object} models.ApiErrorResponse
+// @Router /folders/order [put]
+func (c *FolderController) ReorderFolders(ctx *fiber.Ctx) error {
+	var order models.FolderOrder
+	if err := ctx.BodyParser(&order); err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid request body",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	if err := c.service.ReorderFolders(ctx.UserContext(), &order, userID); err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	folders, err := c.service.GetFoldersByParent(ctx.UserContext(), order.ParentID, userID)
+	if err != nil {
+		return ctx.Status(500).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[[]*models.Folder]{
+		Success: true,
+		Data:    folders,
+		Message: "Folders reordered successfully",
+	})
+}
+
+// @Summary Export folder archive
+// @Description Download a folder, its subfolders and notes as a zip. Each note is stored as Markdown with a JSON sidecar holding the original TipTap content.
+// @Tags folders
+// @Produce application/zip
+// @Param id path int true "Folder ID"
+// @Success 200 {file} file "zip archive"
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /folders/{id}/archive [get]
+func (c *FolderController) ExportFolderArchive(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid folder ID",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	archive, err := c.service.GetFolderArchive(ctx.UserContext(), id, userID)
+	if err != nil {
+		return ctx.Status(404).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	ctx.Attachment(utils.SafeFileName(archive.Root.Name, "folder") + ".zip")
+	ctx.Set(fiber.HeaderContentType, "application/zip")
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		if err := archive.Write(w); err != nil {
+			log.Printf("failed to write archive for folder %d: %v", id, err)
+		}
+		w.Flush()
+	})
+	return nil
+}
+
+// @Summary Import folder archive
+// @Description Rebuild a folder tree from a zip archive under the given parent folder. Names that are already taken get a numeric suffix.
+// @Tags folders
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "Zip archive"
+// @Param ParentID formData int false "Parent folder ID (omit for root)"
+// @Success 201 {object} models.ApiResponse[models.ArchiveImportResult]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Router /folders/import-archive [post]
+func (c *FolderController) ImportFolderArchive(ctx *fiber.Ctx) error {
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Missing file",
+		})
+	}
+
+	var parentID *int
+	if parentIDStr := ctx.FormValue("ParentID"); parentIDStr != "" {
+		id, err := strconv.Atoi(parentIDStr)
+		if err != nil {
+			return ctx.Status(400).JSON(models.ApiErrorResponse{
+				Success: false,
+				Message: "Invalid parent folder ID",
+			})
+		}
+		parentID = &id
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return ctx.Status(500).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Failed to read file",
+		})
+	}
+	defer file.Close()
+
+	userID := ctx.Locals("userID").(string)
+	result, err := c.service.ImportArchive(ctx.UserContext(), file, fileHeader.Size, parentID, userID)
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(201).JSON(models.ApiResponse[*models.ArchiveImportResult]{
+		Success: true,
+		Data:    result,
+		Message: "Archive imported successfully",
+	})
+}
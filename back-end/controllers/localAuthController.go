@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// LocalAuthController serves the account endpoints of the local auth
+// provider. They are only registered when AUTH_PROVIDER is "local".
+type LocalAuthController struct {
+	authenticator *services.LocalAuthenticator
+}
+
+func NewLocalAuthController(authenticator *services.LocalAuthenticator) *LocalAuthController {
+	return &LocalAuthController{authenticator: authenticator}
+}
+
+// @Summary Sign up
+// @Description Create a local account and get a bearer token for it. Only available with the local auth provider.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body models.Credentials true "Email and password (8 to 72 characters)"
+// @Success 201 {object} models.ApiResponse[models.AuthToken]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 409 {object} models.ApiErrorResponse
+// @Router /auth/local/signup [post]
+func (c *LocalAuthController) Signup(ctx *fiber.Ctx) error {
+	var credentials models.Credentials
+	if err := ctx.BodyParser(&credentials); err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid request body",
+		})
+	}
+
+	token, err := c.authenticator.Signup(ctx.UserContext(), &credentials)
+	if errors.Is(err, models.ErrEmailTaken) {
+		return ctx.Status(409).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(201).JSON(models.ApiResponse[*models.AuthToken]{
+		Success: true,
+		Data:    token,
+		Message: "Account created successfully",
+	})
+}
+
+// @Summary Log in
+// @Description Get a bearer token for a local account. Only available with the local auth provider.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param credentials body models.Credentials true "Email and password"
+// @Success 200 {object} models.ApiResponse[models.AuthToken]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 401 {object} models.ApiErrorResponse
+// @Failure 500 {object} models.ApiErrorResponse
+// @Router /auth/local/login [post]
+func (c *LocalAuthController) Login(ctx *fiber.Ctx) error {
+	var credentials models.Credentials
+	if err := ctx.BodyParser(&credentials); err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid request body",
+		})
+	}
+
+	token, err := c.authenticator.Login(ctx.UserContext(), &credentials)
+	if errors.Is(err, models.ErrInvalidCredentials) {
+		return ctx.Status(401).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		return ctx.Status(500).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[*models.AuthToken]{
+		Success: true,
+		Data:    token,
+		Message: "Logged in successfully",
+	})
+}
@@ -1,10 +1,15 @@
 package controllers
 
 import (
+	"errors"
+	"io"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"github.com/RiadMefti/TimeTracker/back-end/models"
 	"github.com/RiadMefti/TimeTracker/back-end/services"
+	"github.com/RiadMefti/TimeTracker/back-end/utils"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -36,7 +41,7 @@ func (c *NoteController) CreateNote(ctx *fiber.Ctx) error {
 	}
 
 	userID := ctx.Locals("userID").(string)
-	note, err := c.service.CreateNote(&noteCreate, userID)
+	note, err := c.service.CreateNote(ctx.UserContext(), &noteCreate, userID)
 	if err != nil {
 		return ctx.Status(500).JSON(models.ApiErrorResponse{
 			Success: false,
@@ -52,15 +57,33 @@ func (c *NoteController) CreateNote(ctx *fiber.Ctx) error {
 }
 
 // @Summary Get all notes
-// @Description Get all notes for the authenticated user
+// @Description Get all notes for the authenticated user, pinned notes first, then the most recently updated
 // @Tags notes
 // @Produce json
+// @Param tags query string false "Comma-separated tag names; only notes carrying all of them are returned"
+// @Param pinned query bool false "Only pinned (true) or unpinned (false) notes"
 // @Success 200 {object} models.ApiResponse[[]models.Note]
+// @Failure 400 {object} models.ApiErrorResponse
 // @Failure 500 {object} models.ApiErrorResponse
 // @Router /notes [get]
 func (c *NoteController) GetAllNotes(ctx *fiber.Ctx) error {
+	var filter models.NoteFilter
+	if tags := ctx.Query("tags"); tags != "" {
+		filter.Tags = strings.Split(tags, ",")
+	}
+	if pinnedStr := ctx.Query("pinned"); pinnedStr != "" {
+		pinned, err := strconv.ParseBool(pinnedStr)
+		if err != nil {
+			return ctx.Status(400).JSON(models.ApiErrorResponse{
+				Success: false,
+				Message: "Invalid pinned filter",
+			})
+		}
+		filter.Pinned = &pinned
+	}
+
 	userID := ctx.Locals("userID").(string)
-	notes, err := c.service.GetAllNotes(userID)
+	notes, err := c.service.GetNotes(ctx.UserContext(), &filter, userID)
 	if err != nil {
 		return ctx.Status(500).JSON(models.ApiErrorResponse{
 			Success: false,
@@ -99,7 +122,7 @@ func (c *NoteController) GetNote(ctx *fiber.Ctx) error {
 	}
 
 	userID := ctx.Locals("userID").(string)
-	note, err := c.service.GetNote(id, userID)
+	note, err := c.service.GetNote(ctx.UserContext(), id, userID)
 	if err != nil {
 		return ctx.Status(404).JSON(models.ApiErrorResponse{
 			Success: false,
@@ -107,6 +130,7 @@ func (c *NoteController) GetNote(ctx *fiber.Ctx) error {
 		})
 	}
 
+	ctx.Set(fiber.HeaderETag, utils.FormatETag(note.Version))
 	return ctx.JSON(models.ApiResponse[*models.Note]{
 		Success: true,
 		Data:    note,
@@ -114,15 +138,17 @@ func (c *NoteController) GetNote(ctx *fiber.Ctx) error {
 }
 
 // @Summary Update note
-// @Description Update an existing note
+// @Description Update an existing note. Send the ETag from a previous read in If-Match to reject stale writes.
 // @Tags notes
 // @Accept json
 // @Produce json
 // @Param id path int true "Note ID"
+// @Param If-Match header string false "ETag of the version being updated"
 // @Param note body models.NoteUpdate true "Updated note data"
 // @Success 200 {object} models.ApiResponse[models.Note]
 // @Failure 400 {object} models.ApiErrorResponse
 // @Failure 404 {object} models.ApiErrorResponse
+// @Failure 412 {object} models.ApiResponse[models.Note] "current server copy"
 // @Failure 500 {object} models.ApiErrorResponse
 // @Router /notes/{id} [put]
 func (c *NoteController) UpdateNote(ctx *fiber.Ctx) error {
@@ -142,8 +168,31 @@ func (c *NoteController) UpdateNote(ctx *fiber.Ctx) error {
 		})
 	}
 
+	expectedVersion, err := utils.ParseIfMatch(ctx)
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
 	userID := ctx.Locals("userID").(string)
-	note, err := c.service.UpdateNote(id, &noteUpdate, userID)
+	note, err := c.service.UpdateNote(ctx.UserContext(), id, &noteUpdate, userID, expectedVersion)
+	if errors.Is(err, models.ErrVersionConflict) {
+		current, err := c.service.GetNote(ctx.UserContext(), id, userID)
+		if err != nil {
+			return ctx.Status(404).JSON(models.ApiErrorResponse{
+				Success: false,
+				Message: err.Error(),
+			})
+		}
+		ctx.Set(fiber.HeaderETag, utils.FormatETag(current.Version))
+		return ctx.Status(412).JSON(models.ApiResponse[*models.Note]{
+			Success: false,
+			Data:    current,
+			Message: "Note was modified by another request",
+		})
+	}
 	if err != nil {
 		return ctx.Status(404).JSON(models.ApiErrorResponse{
 			Success: false,
@@ -151,6 +200,7 @@ func (c *NoteController) UpdateNote(ctx *fiber.Ctx) error {
 		})
 	}
 
+	ctx.Set(fiber.HeaderETag, utils.FormatETag(note.Version))
 	return ctx.JSON(models.ApiResponse[*models.Note]{
 		Success: true,
 		Data:    note,
@@ -159,11 +209,11 @@ func (c *NoteController) UpdateNote(ctx *fiber.Ctx) error {
 }
 
 // @Summary Delete note
-// @Description Delete a note
+// @Description Move a note to the trash. The response lists the notes that still link to it.
 // @Tags notes
 // @Produce json
 // @Param id path int true "Note ID"
-// @Success 200 {object} models.ApiResponse[interface{}]
+// @Success 200 {object} models.ApiResponse[models.NoteDeleteResult]
 // @Failure 400 {object} models.ApiErrorResponse
 // @Failure 404 {object} models.ApiErrorResponse
 // @Failure 500 {object} models.ApiErrorResponse
@@ -178,7 +228,7 @@ func (c *NoteController) DeleteNote(ctx *fiber.Ctx) error {
 	}
 
 	userID := ctx.Locals("userID").(string)
-	err = c.service.DeleteNote(id, userID)
+	result, err := c.service.DeleteNote(ctx.UserContext(), id, userID)
 	if err != nil {
 		return ctx.Status(404).JSON(models.ApiErrorResponse{
 			Success: false,
@@ -186,9 +236,9 @@ func (c *NoteController) DeleteNote(ctx *fiber.Ctx) error {
 		})
 	}
 
-	return ctx.JSON(models.ApiResponse[interface{}]{
+	return ctx.JSON(models.ApiResponse[*models.NoteDeleteResult]{
 		Success: true,
-		Data:    nil,
+		Data:    result,
 		Message: "Note deleted successfully",
 	})
 }
@@ -211,7 +261,7 @@ func (c *NoteController) GetNotesByFolder(ctx *fiber.Ctx) error {
 		}
 	}
 
-	notes, err := c.service.GetNotesByFolder(folderID, userID)
+	notes, err := c.service.GetNotesByFolder(ctx.UserContext(), folderID, userID)
 	if err != nil {
 		return ctx.Status(500).JSON(models.ApiErrorResponse{
 			Success: false,
@@ -229,3 +279,342 @@ func (c *NoteController) GetNotesByFolder(ctx *fiber.Ctx) error {
 		Data:    notes,
 	})
 }
+
+// @Summary Get note backlinks
+// @Description Get the notes that link to a note, with the text surrounding each link
+// @Tags notes
+// @Produce json
+// @Param id path int true "Note ID"
+// @Success 200 {object} models.ApiResponse[[]models.Backlink]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /notes/{id}/backlinks [get]
+func (c *NoteController) GetBacklinks(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid note ID",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	backlinks, err := c.service.GetBacklinks(ctx.UserContext(), id, userID)
+	if err != nil {
+		return ctx.Status(404).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[[]*models.Backlink]{
+		Success: true,
+		Data:    backlinks,
+	})
+}
+
+// @Summary Export note
+// @Description Export a note as a Markdown file or a standalone HTML page
+// @Tags notes
+// @Produce plain
+// @Produce html
+// @Param id path int true "Note ID"
+// @Param format query string false "Export format" Enums(md, html) default(md)
+// @Success 200 {string} string "exported note"
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Failure 500 {object} models.ApiErrorResponse
+// @Router /notes/{id}/export [get]
+func (c *NoteController) ExportNote(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid note ID",
+		})
+	}
+
+	format := ctx.Query("format", "md")
+	if format != "md" && format != "html" {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid export format, expected md or html",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	note, err := c.service.GetNote(ctx.UserContext(), id, userID)
+	if err != nil {
+		return ctx.Status(404).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	body, err := c.service.ExportNote(note, format)
+	if err != nil {
+		return ctx.Status(500).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	ctx.Attachment(utils.SafeFileName(note.Title, "note") + "." + format)
+	if format == "md" {
+		ctx.Set(fiber.HeaderContentType, "text/markdown; charset=utf-8")
+	} else {
+		ctx.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	}
+	return ctx.SendString(body)
+}
+
+// @Summary Import note
+// @Description Create a note from an uploaded Markdown file
+// @Tags notes
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "Markdown file (.md, .markdown or .txt)"
+// @Param Title formData string false "Note title (defaults to the file name)"
+// @Param FolderID formData int false "Folder ID (omit for a root note)"
+// @Success 201 {object} models.ApiResponse[models.Note]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 500 {object} models.ApiErrorResponse
+// @Router /notes/import [post]
+func (c *NoteController) ImportNote(ctx *fiber.Ctx) error {
+	fileHeader, err := ctx.FormFile("file")
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Missing file",
+		})
+	}
+
+	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
+	if ext != ".md" && ext != ".markdown" && ext != ".txt" {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Only Markdown files can be imported",
+		})
+	}
+
+	var folderID *int
+	if folderIDStr := ctx.FormValue("FolderID"); folderIDStr != "" {
+		id, err := strconv.Atoi(folderIDStr)
+		if err != nil {
+			return ctx.Status(400).JSON(models.ApiErrorResponse{
+				Success: false,
+				Message: "Invalid folder ID",
+			})
+		}
+		folderID = &id
+	}
+
+	title := strings.TrimSpace(ctx.FormValue("Title"))
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(fileHeader.Filename), filepath.Ext(fileHeader.Filename))
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return ctx.Status(500).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Failed to read file",
+		})
+	}
+	defer file.Close()
+
+	markdown, err := io.ReadAll(file)
+	if err != nil {
+		return ctx.Status(500).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Failed to read file",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	note, err := c.service.ImportMarkdown(ctx.UserContext(), title, markdown, folderID, userID)
+	if err != nil {
+		return ctx.Status(500).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(201).JSON(models.ApiResponse[*models.Note]{
+		Success: true,
+		Data:    note,
+		Message: "Note imported successfully",
+	})
+}
+
+// @Summary Pin note
+// @Description Pin a note so it is listed first and returned by ?pinned=true. The note version is not bumped.
+// @Tags notes
+// @Produce json
+// @Param id path int true "Note ID"
+// @Success 200 {object} models.ApiResponse[models.Note]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /notes/{id}/pin [post]
+func (c *NoteController) PinNote(ctx *fiber.Ctx) error {
+	return c.setPinned(ctx, true)
+}
+
+// @Summary Unpin note
+// @Tags notes
+// @Produce json
+// @Param id path int true "Note ID"
+// @Success 200 {object} models.ApiResponse[models.Note]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /notes/{id}/pin [delete]
+func (c *NoteController) UnpinNote(ctx *fiber.Ctx) error {
+	return c.setPinned(ctx, false)
+}
+
+func (c *NoteController) setPinned(ctx *fiber.Ctx, pinned bool) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid note ID",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	note, err := c.service.SetPinned(ctx.UserContext(), id, pinned, userID)
+	if err != nil {
+		return ctx.Status(404).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[*models.Note]{
+		Success: true,
+		Data:    note,
+	})
+}
+
+// @Summary Reorder notes
+// @Description Set the order of the notes in a folder. Notes of the folder that are not listed keep their relative order after the listed ones.
+// @Tags notes
+// @Accept json
+// @Produce json
+// @Param order body models.NoteOrder true "Folder and note IDs in the new order"
+// @Success 200 {object} models.ApiResponse[[]models.Note]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 500 {object} models.ApiErrorResponse
+// @Router /notes/order [put]
+func (c *NoteController) ReorderNotes(ctx *fiber.Ctx) error {
+	var order models.NoteOrder
+	if err := ctx.BodyParser(&order); err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid request body",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	if err := c.service.ReorderNotes(ctx.UserContext(), &order, userID); err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	notes, err := c.service.GetNotesByFolder(ctx.UserContext(), order.FolderID, userID)
+	if err != nil {
+		return ctx.Status(500).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[[]*models.Note]{
+		Success: true,
+		Data:    notes,
+		Message: "Notes reordered successfully",
+	})
+}
+
+// @Summary Get tasks
+// @Description Get the TipTap task items of all notes, most recently updated note first
+// @Tags notes
+// @Produce json
+// @Param status query string false "open, done or all (default)"
+// @Success 200 {object} models.ApiResponse[[]models.NoteTask]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 500 {object} models.ApiErrorResponse
+// @Router /notes/tasks [get]
+func (c *NoteController) GetTasks(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(string)
+	tasks, err := c.service.GetTasks(ctx.UserContext(), ctx.Query("status"), userID)
+	if errors.Is(err, models.ErrUnknownTaskStatus) {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		return ctx.Status(500).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[[]*models.NoteTask]{
+		Success: true,
+		Data:    tasks,
+	})
+}
+
+// @Summary Update task
+// @Description Check or uncheck a task item by rewriting its note. Without a body the checkbox is toggled.
+// @Tags notes
+// @Accept json
+// @Produce json
+// @Param id path int true "Task ID"
+// @Param task body models.NoteTaskUpdate false "New checked state"
+// @Success 200 {object} models.ApiResponse[models.NoteTask]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Failure 409 {object} models.ApiErrorResponse "the note changed while it was being updated"
+// @Router /notes/tasks/{id} [patch]
+func (c *NoteController) UpdateTask(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid task ID",
+		})
+	}
+
+	var update models.NoteTaskUpdate
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&update); err != nil {
+			return ctx.Status(400).JSON(models.ApiErrorResponse{
+				Success: false,
+				Message: "Invalid request body",
+			})
+		}
+	}
+
+	userID := ctx.Locals("userID").(string)
+	task, err := c.service.UpdateTask(ctx.UserContext(), id, &update, userID)
+	if err != nil {
+		status := 404
+		if errors.Is(err, models.ErrVersionConflict) {
+			status = 409
+		}
+		return ctx.Status(status).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[*models.NoteTask]{
+		Success: true,
+		Data:    task,
+	})
+}
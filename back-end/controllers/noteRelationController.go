@@ -0,0 +1,199 @@
+package controllers
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/repositories"
+	"github.com/RiadMefti/TimeTracker/back-end/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type NoteRelationController struct {
+	service *services.NoteRelationService
+}
+
+func NewNoteRelationController(service *services.NoteRelationService) *NoteRelationController {
+	return &NoteRelationController{service: service}
+}
+
+// @Summary Get note relations
+// @Description Get the IDs of the projects, time entries and time boxes attached to a note
+// @Tags notes
+// @Produce json
+// @Param id path int true "Note ID"
+// @Success 200 {object} models.ApiResponse[models.NoteRelations]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /notes/{id}/relations [get]
+func (c *NoteRelationController) GetRelations(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid note ID",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	relations, err := c.service.GetRelations(ctx.UserContext(), id, userID)
+	if err != nil {
+		return ctx.Status(404).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[*models.NoteRelations]{
+		Success: true,
+		Data:    relations,
+	})
+}
+
+// @Summary Attach project to note
+// @Tags notes
+// @Produce json
+// @Param id path int true "Note ID"
+// @Param targetId path int true "Project ID"
+// @Success 200 {object} models.ApiResponse[models.NoteRelations]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /notes/{id}/projects/{targetId} [post]
+func (c *NoteRelationController) AttachProject(ctx *fiber.Ctx) error {
+	return c.update(ctx, repositories.NoteProjectRelation, c.service.Attach)
+}
+
+// @Summary Detach project from note
+// @Tags notes
+// @Produce json
+// @Param id path int true "Note ID"
+// @Param targetId path int true "Project ID"
+// @Success 200 {object} models.ApiResponse[models.NoteRelations]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /notes/{id}/projects/{targetId} [delete]
+func (c *NoteRelationController) DetachProject(ctx *fiber.Ctx) error {
+	return c.update(ctx, repositories.NoteProjectRelation, c.service.Detach)
+}
+
+// @Summary Attach time entry to note
+// @Tags notes
+// @Produce json
+// @Param id path int true "Note ID"
+// @Param targetId path int true "Time Entry ID"
+// @Success 200 {object} models.ApiResponse[models.NoteRelations]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /notes/{id}/time-entries/{targetId} [post]
+func (c *NoteRelationController) AttachTimeEntry(ctx *fiber.Ctx) error {
+	return c.update(ctx, repositories.NoteTimeEntryRelation, c.service.Attach)
+}
+
+// @Summary Detach time entry from note
+// @Tags notes
+// @Produce json
+// @Param id path int true "Note ID"
+// @Param targetId path int true "Time Entry ID"
+// @Success 200 {object} models.ApiResponse[models.NoteRelations]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /notes/{id}/time-entries/{targetId} [delete]
+func (c *NoteRelationController) DetachTimeEntry(ctx *fiber.Ctx) error {
+	return c.update(ctx, repositories.NoteTimeEntryRelation, c.service.Detach)
+}
+
+// @Summary Attach time box to note
+// @Tags notes
+// @Produce json
+// @Param id path int true "Note ID"
+// @Param targetId path int true "Time Box ID"
+// @Success 200 {object} models.ApiResponse[models.NoteRelations]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /notes/{id}/time-boxes/{targetId} [post]
+func (c *NoteRelationController) AttachTimeBox(ctx *fiber.Ctx) error {
+	return c.update(ctx, repositories.NoteTimeBoxRelation, c.service.Attach)
+}
+
+// @Summary Detach time box from note
+// @Tags notes
+// @Produce json
+// @Param id path int true "Note ID"
+// @Param targetId path int true "Time Box ID"
+// @Success 200 {object} models.ApiResponse[models.NoteRelations]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /notes/{id}/time-boxes/{targetId} [delete]
+func (c *NoteRelationController) DetachTimeBox(ctx *fiber.Ctx) error {
+	return c.update(ctx, repositories.NoteTimeBoxRelation, c.service.Detach)
+}
+
+func (c *NoteRelationController) update(
+	ctx *fiber.Ctx,
+	target repositories.NoteRelationTarget,
+	apply func(context.Context, repositories.NoteRelationTarget, int, int, string) (*models.NoteRelations, error),
+) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid note ID",
+		})
+	}
+
+	targetID, err := strconv.Atoi(ctx.Params("targetId"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid " + target.Label() + " ID",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	relations, err := apply(ctx.UserContext(), target, id, targetID, userID)
+	if err != nil {
+		return ctx.Status(404).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[*models.NoteRelations]{
+		Success: true,
+		Data:    relations,
+	})
+}
+
+// @Summary Get project notes
+// @Description Get the notes attached to a project
+// @Tags projects
+// @Produce json
+// @Param id path int true "Project ID"
+// @Success 200 {object} models.ApiResponse[[]models.NoteStub]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /projects/{id}/notes [get]
+func (c *NoteRelationController) GetProjectNotes(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid project ID",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	notes, err := c.service.GetProjectNotes(ctx.UserContext(), id, userID)
+	if err != nil {
+		return ctx.Status(404).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[[]*models.NoteStub]{
+		Success: true,
+		Data:    notes,
+	})
+}
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type NoteTemplateController struct {
+	service *services.NoteTemplateService
+}
+
+func NewNoteTemplateController(service *services.NoteTemplateService) *NoteTemplateController {
+	return &NoteTemplateController{service: service}
+}
+
+// @Summary Get note templates
+// @Description Get the notes flagged as templates
+// @Tags notes
+// @Produce json
+// @Success 200 {object} models.ApiResponse[[]models.Note]
+// @Failure 500 {object} models.ApiErrorResponse
+// @Router /notes/templates [get]
+func (c *NoteTemplateController) GetTemplates(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(string)
+	templates, err := c.service.GetTemplates(ctx.UserContext(), userID)
+	if err != nil {
+		return ctx.Status(500).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[[]*models.Note]{
+		Success: true,
+		Data:    templates,
+	})
+}
+
+// @Summary Create note from template
+// @Description Create a note from a template, substituting {{date}}, {{time}}, {{weekday}} and {{project}} in its title and text
+// @Tags notes
+// @Accept json
+// @Produce json
+// @Param id path int true "Template note ID"
+// @Param note body models.NoteFromTemplate false "Options for the new note"
+// @Success 201 {object} models.ApiResponse[models.Note]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Router /notes/templates/{id}/instantiate [post]
+func (c *NoteTemplateController) CreateFromTemplate(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid template ID",
+		})
+	}
+
+	var req models.NoteFromTemplate
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&req); err != nil {
+			return ctx.Status(400).JSON(models.ApiErrorResponse{
+				Success: false,
+				Message: "Invalid request body",
+			})
+		}
+	}
+
+	userID := ctx.Locals("userID").(string)
+	settings := ctx.Locals("settings").(*models.UserSettings)
+	note, err := c.service.CreateFromTemplate(ctx.UserContext(), id, &req, settings, userID)
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(201).JSON(models.ApiResponse[*models.Note]{
+		Success: true,
+		Data:    note,
+		Message: "Note created successfully",
+	})
+}
+
+// @Summary Get or create today's daily note
+// @Description Return today's daily note, creating it on the first call of the day. The day is taken in the timezone from the user settings.
+// @Tags notes
+// @Produce json
+// @Success 200 {object} models.ApiResponse[models.DailyNote]
+// @Success 201 {object} models.ApiResponse[models.DailyNote]
+// @Failure 500 {object} models.ApiErrorResponse
+// @Router /notes/daily [post]
+func (c *NoteTemplateController) GetOrCreateDailyNote(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(string)
+	settings := ctx.Locals("settings").(*models.UserSettings)
+	daily, err := c.service.GetOrCreateDailyNote(ctx.UserContext(), settings, userID)
+	if err != nil {
+		return ctx.Status(500).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	status := 200
+	if daily.Created {
+		status = 201
+	}
+	return ctx.Status(status).JSON(models.ApiResponse[*models.DailyNote]{
+		Success: true,
+		Data:    daily,
+	})
+}
+
+// @Summary Get daily note settings
+// @Tags notes
+// @Produce json
+// @Success 200 {object} models.ApiResponse[models.DailyNoteSettings]
+// @Failure 500 {object} models.ApiErrorResponse
+// @Router /notes/daily/settings [get]
+func (c *NoteTemplateController) GetDailySettings(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(string)
+	settings, err := c.service.GetDailySettings(ctx.UserContext(), userID)
+	if err != nil {
+		return ctx.Status(500).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[*models.DailyNoteSettings]{
+		Success: true,
+		Data:    settings,
+	})
+}
+
+// @Summary Update daily note settings
+// @Description Set the folder and template used for daily notes
+// @Tags notes
+// @Accept json
+// @Produce json
+// @Param settings body models.DailyNoteSettings true "Daily note settings"
+// @Success 200 {object} models.ApiResponse[models.DailyNoteSettings]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Router /notes/daily/settings [put]
+func (c *NoteTemplateController) UpdateDailySettings(ctx *fiber.Ctx) error {
+	var settings models.DailyNoteSettings
+	if err := ctx.BodyParser(&settings); err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid request body",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	updated, err := c.service.UpdateDailySettings(ctx.UserContext(), &settings, userID)
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[*models.DailyNoteSettings]{
+		Success: true,
+		Data:    updated,
+		Message: "Daily note settings updated successfully",
+	})
+}
@@ -1,6 +1,8 @@
 package controllers
 
 import (
+	"errors"
+
 	"github.com/RiadMefti/TimeTracker/back-end/models"
 	"github.com/RiadMefti/TimeTracker/back-end/services"
 	"github.com/RiadMefti/TimeTracker/back-end/utils"
@@ -31,7 +33,7 @@ func (p *ProjectController) GetUserProjects(c *fiber.Ctx) error {
 		return nil
 	}
 
-	projects, err := p.projectService.GetUserProjects(userAuth.UID)
+	projects, err := p.projectService.GetUserProjects(c.UserContext(), userAuth.UID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "an error occured while retrieving projects"))
 	}
@@ -62,7 +64,7 @@ func (p *ProjectController) CreateUserProject(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(utils.CreateApiResponse[interface{}](false, nil, "Invalid request body"))
 	}
 
-	projects, err := p.projectService.CreateUserProject(projectToCreate, userAuth.UID)
+	projects, err := p.projectService.CreateUserProject(c.UserContext(), projectToCreate, userAuth.UID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "An error occurred while creating project"))
 	}
@@ -76,9 +78,11 @@ func (p *ProjectController) CreateUserProject(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param If-Match header string false "ETag of the version being updated"
 // @Param project body models.Project true "Project to update"
 // @Success 200 {object} models.ApiResponse[[]models.Project]
 // @Failure 400 {object} models.ApiErrorResponse
+// @Failure 412 {object} models.ApiResponse[models.Project] "current server copy"
 // @Failure 500 {object} models.ApiErrorResponse
 // @Router /projects/ [put]
 func (p *ProjectController) UpdateUserProject(c *fiber.Ctx) error {
@@ -92,17 +96,35 @@ func (p *ProjectController) UpdateUserProject(c *fiber.Ctx) error {
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(utils.CreateApiResponse[interface{}](false, nil, "Invalid request body"))
 	}
-	projects, err := p.projectService.UpdateUserProject(projectToUpdate, userAuth.UID)
+	expectedVersion, err := utils.ParseIfMatch(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.CreateApiResponse[interface{}](false, nil, err.Error()))
+	}
+
+	updated, err := p.projectService.UpdateUserProject(c.UserContext(), projectToUpdate, userAuth.UID, expectedVersion)
+	if errors.Is(err, models.ErrVersionConflict) {
+		current, err := p.projectService.GetUserProject(c.UserContext(), projectToUpdate.ID, userAuth.UID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "an error occured while retrieving project"))
+		}
+		c.Set(fiber.HeaderETag, utils.FormatETag(current.Version))
+		return c.Status(fiber.StatusPreconditionFailed).JSON(utils.CreateApiResponse(false, current, "project was modified by another request"))
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "an error occured while updating project"))
 	}
 
+	c.Set(fiber.HeaderETag, utils.FormatETag(updated.Version))
+	projects, err := p.projectService.GetUserProjects(c.UserContext(), userAuth.UID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "an error occured while retrieving projects"))
+	}
 	return c.Status(fiber.StatusOK).JSON(utils.CreateApiResponse(true, projects, "project updated successfully"))
 
 }
 
 // @Summary Delete a project
-// @Description Delete a project by ID for the authenticated user
+// @Description Move a project and its time entries to the trash
 // @Tags projects
 // @Produce json
 // @Security BearerAuth
@@ -116,7 +138,7 @@ func (p *ProjectController) DeleteUserProject(c *fiber.Ctx) error {
 		return nil
 	}
 	projectID := c.Params("id")
-	projects, err := p.projectService.DeleteUserProject(projectID, userAuth.UID)
+	projects, err := p.projectService.DeleteUserProject(c.UserContext(), projectID, userAuth.UID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "an error occured while deleting project"))
 	}
@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+// sharePageCSP only lets shared pages load images and submit the password
+// form; shared content can never run scripts.
+const sharePageCSP = "default-src 'none'; img-src https: data:; style-src 'unsafe-inline'; form-action 'self'"
+
+type ShareController struct {
+	service *services.ShareService
+}
+
+func NewShareController(service *services.ShareService) *ShareController {
+	return &ShareController{service: service}
+}
+
+// @Summary Create share link
+// @Description Create a read-only public link to a note or a folder subtree, with an optional expiry and password. The link URL is only returned in this response.
+// @Tags shares
+// @Accept json
+// @Produce json
+// @Param share body models.ShareLinkCreate true "Share link data"
+// @Success 201 {object} models.ApiResponse[models.ShareLink]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Router /shares [post]
+func (c *ShareController) CreateShareLink(ctx *fiber.Ctx) error {
+	var share models.ShareLinkCreate
+	if err := ctx.BodyParser(&share); err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid request body",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	link, err := c.service.CreateShareLink(ctx.UserContext(), &share, userID)
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	c.setURL(ctx, link)
+	return ctx.Status(201).JSON(models.ApiResponse[*models.ShareLink]{
+		Success: true,
+		Data:    link,
+	})
+}
+
+// @Summary Get share links
+// @Description Get all share links created by the user
+// @Tags shares
+// @Produce json
+// @Success 200 {object} models.ApiResponse[[]models.ShareLink]
+// @Failure 500 {object} models.ApiErrorResponse
+// @Router /shares [get]
+func (c *ShareController) GetShareLinks(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(string)
+	links, err := c.service.GetShareLinks(ctx.UserContext(), userID)
+	if err != nil {
+		return ctx.Status(500).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	c.setURL(ctx, links...)
+	return ctx.JSON(models.ApiResponse[[]*models.ShareLink]{
+		Success: true,
+		Data:    links,
+	})
+}
+
+// @Summary Revoke share link
+// @Description Delete a share link. Its URL stops working immediately.
+// @Tags shares
+// @Produce json
+// @Param id path int true "Share link ID"
+// @Success 200 {object} models.ApiResponse[string]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /shares/{id} [delete]
+func (c *ShareController) RevokeShareLink(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid share link ID",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	if err := c.service.RevokeShareLink(ctx.UserContext(), id, userID); err != nil {
+		return ctx.Status(404).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[string]{
+		Success: true,
+		Data:    "Share link revoked successfully",
+	})
+}
+
+// @Summary View shared content
+// @Description Public, unauthenticated view of a shared note or folder as sanitized HTML or as JSON
+// @Tags shares
+// @Produce html
+// @Produce json
+// @Param token path string true "Share token"
+// @Param format query string false "Response format" Enums(html, json) default(html)
+// @Param password formData string false "Password for protected links, posted by the password form"
+// @Param X-Share-Password header string false "Password for protected links"
+// @Success 200 {object} models.ApiResponse[models.SharedContent]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 401 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /share/{token} [get]
+// @Router /share/{token} [post]
+func (c *ShareController) ViewShare(ctx *fiber.Ctx) error {
+	format := ctx.Query("format", "html")
+	if format != "html" && format != "json" {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid format, expected html or json",
+		})
+	}
+
+	// The password is never read from the query string, which would leak it
+	// into logs and browser history.
+	password := ctx.Get("X-Share-Password")
+	if password == "" && ctx.Method() == fiber.MethodPost {
+		password = ctx.FormValue("password")
+	}
+
+	// The token is a credential, so keep it out of caches and referrers.
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	ctx.Set(fiber.HeaderReferrerPolicy, "no-referrer")
+	ctx.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+
+	content, err := c.service.GetSharedContent(ctx.UserContext(), ctx.Params("token"), password)
+	if errors.Is(err, models.ErrSharePasswordRequired) && format == "html" {
+		ctx.Set(fiber.HeaderContentSecurityPolicy, sharePageCSP)
+		ctx.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return ctx.Status(401).SendString(services.RenderSharePasswordHTML(password != ""))
+	}
+	if err != nil {
+		status := 500
+		switch {
+		case errors.Is(err, models.ErrShareNotFound):
+			status = 404
+		case errors.Is(err, models.ErrSharePasswordRequired):
+			status = 401
+		}
+		return ctx.Status(status).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	if format == "json" {
+		return ctx.JSON(models.ApiResponse[*models.SharedContent]{
+			Success: true,
+			Data:    content,
+		})
+	}
+
+	ctx.Set(fiber.HeaderContentSecurityPolicy, sharePageCSP)
+	ctx.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return ctx.SendString(services.RenderSharedHTML(content))
+}
+
+// setURL fills in the URL of links created in this request. Stored links
+// only keep a hash of their token, so the URL cannot be shown again.
+func (c *ShareController) setURL(ctx *fiber.Ctx, links ...*models.ShareLink) {
+	for _, link := range links {
+		if link.Token != "" {
+			link.URL = ctx.BaseURL() + "/share/" + link.Token
+		}
+	}
+}
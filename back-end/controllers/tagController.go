@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type TagController struct {
+	service *services.TagService
+}
+
+func NewTagController(service *services.TagService) *TagController {
+	return &TagController{service: service}
+}
+
+// @Summary Get tags
+// @Description Get the user's tags with the number of notes carrying each. Tags are created by using them on a note.
+// @Tags tags
+// @Produce json
+// @Success 200 {object} models.ApiResponse[[]models.Tag]
+// @Failure 500 {object} models.ApiErrorResponse
+// @Router /tags [get]
+func (c *TagController) GetTags(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(string)
+	tags, err := c.service.GetTags(ctx.UserContext(), userID)
+	if err != nil {
+		return ctx.Status(500).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[[]*models.Tag]{
+		Success: true,
+		Data:    tags,
+	})
+}
+
+// @Summary Delete tag
+// @Description Delete a tag and remove it from every note
+// @Tags tags
+// @Produce json
+// @Param id path int true "Tag ID"
+// @Success 200 {object} models.ApiResponse[interface{}]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /tags/{id} [delete]
+func (c *TagController) DeleteTag(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid tag ID",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	if err := c.service.DeleteTag(ctx.UserContext(), id, userID); err != nil {
+		return ctx.Status(404).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[interface{}]{
+		Success: true,
+		Data:    nil,
+		Message: "Tag deleted successfully",
+	})
+}
@@ -1,6 +1,8 @@
 package controllers
 
 import (
+	"database/sql"
+	"errors"
 	"strconv"
 
 	"github.com/RiadMefti/TimeTracker/back-end/models"
@@ -33,7 +35,7 @@ func (t *TimeBoxEntryController) GetUserTimeBoxEntries(c *fiber.Ctx) error {
 		return nil
 	}
 
-	entries, err := t.timeBoxEntryService.GetUserTimeBoxEntries(userAuth.UID)
+	entries, err := t.timeBoxEntryService.GetUserTimeBoxEntries(c.UserContext(), userAuth.UID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "An error occurred while retrieving time box entries"))
 	}
@@ -63,7 +65,7 @@ func (t *TimeBoxEntryController) CreateTimeBoxEntry(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(utils.CreateApiResponse[interface{}](false, nil, "Invalid request body"))
 	}
 
-	entries, err := t.timeBoxEntryService.CreateTimeBoxEntry(entryToCreate, userAuth.UID)
+	entries, err := t.timeBoxEntryService.CreateTimeBoxEntry(c.UserContext(), entryToCreate, userAuth.UID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "An error occurred while creating time box entry"))
 	}
@@ -77,9 +79,12 @@ func (t *TimeBoxEntryController) CreateTimeBoxEntry(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param If-Match header string false "ETag of the version being updated"
 // @Param timeBoxEntry body models.TimeBoxEntry true "Time box entry to update"
 // @Success 200 {object} models.ApiResponse[[]models.TimeBoxEntry]
 // @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Failure 412 {object} models.ApiResponse[models.TimeBoxEntry] "current server copy"
 // @Failure 500 {object} models.ApiErrorResponse
 // @Router /time-box-entries/ [put]
 func (t *TimeBoxEntryController) UpdateTimeBoxEntry(c *fiber.Ctx) error {
@@ -93,11 +98,32 @@ func (t *TimeBoxEntryController) UpdateTimeBoxEntry(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(utils.CreateApiResponse[interface{}](false, nil, "Invalid request body"))
 	}
 
-	entries, err := t.timeBoxEntryService.UpdateTimeBoxEntry(entryToUpdate, userAuth.UID)
+	expectedVersion, err := utils.ParseIfMatch(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.CreateApiResponse[interface{}](false, nil, err.Error()))
+	}
+
+	updated, err := t.timeBoxEntryService.UpdateTimeBoxEntry(c.UserContext(), entryToUpdate, userAuth.UID, expectedVersion)
+	if errors.Is(err, models.ErrVersionConflict) {
+		current, err := t.timeBoxEntryService.GetTimeBoxEntry(c.UserContext(), entryToUpdate.ID, userAuth.UID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "An error occurred while retrieving time box entry"))
+		}
+		c.Set(fiber.HeaderETag, utils.FormatETag(current.Version))
+		return c.Status(fiber.StatusPreconditionFailed).JSON(utils.CreateApiResponse(false, current, "Time box entry was modified by another request"))
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(utils.CreateApiResponse[interface{}](false, nil, "Time box entry not found"))
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "An error occurred while updating time box entry"))
 	}
 
+	c.Set(fiber.HeaderETag, utils.FormatETag(updated.Version))
+	entries, err := t.timeBoxEntryService.GetUserTimeBoxEntries(c.UserContext(), userAuth.UID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "An error occurred while retrieving time box entries"))
+	}
 	return c.Status(fiber.StatusOK).JSON(utils.CreateApiResponse(true, entries, "Time box entry updated successfully"))
 }
 
@@ -123,7 +149,7 @@ func (t *TimeBoxEntryController) DeleteTimeBoxEntry(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(utils.CreateApiResponse[interface{}](false, nil, "Invalid time box entry ID"))
 	}
 
-	entries, err := t.timeBoxEntryService.DeleteTimeBoxEntry(timeBoxEntryID, userAuth.UID)
+	entries, err := t.timeBoxEntryService.DeleteTimeBoxEntry(c.UserContext(), timeBoxEntryID, userAuth.UID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "An error occurred while deleting time box entry"))
 	}
@@ -160,7 +186,7 @@ func (t *TimeBoxEntryController) AssignProjectToTimeBox(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(utils.CreateApiResponse[interface{}](false, nil, "Invalid request body"))
 	}
 
-	entries, err := t.timeBoxEntryService.AssignProjectToTimeBox(timeBoxEntryID, payload.ProjectID, userAuth.UID)
+	entries, err := t.timeBoxEntryService.AssignProjectToTimeBox(c.UserContext(), timeBoxEntryID, payload.ProjectID, userAuth.UID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "An error occurred while assigning project"))
 	}
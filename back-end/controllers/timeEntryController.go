@@ -1,6 +1,8 @@
 package controllers
 
 import (
+	"database/sql"
+	"errors"
 	"strconv"
 
 	"github.com/RiadMefti/TimeTracker/back-end/models"
@@ -24,6 +26,7 @@ func NewTimeEntryController(timeEntryService *services.TimeEntryService) *TimeEn
 // @Tags time-entries
 // @Produce json
 // @Security BearerAuth
+// @Param include query string false "Set to notes to embed the linked note stubs"
 // @Success 200 {object} models.ApiResponse[[]models.TimeEntry]
 // @Failure 500 {object} models.ApiErrorResponse
 // @Router /time-entries/ [get]
@@ -33,11 +36,16 @@ func (t *TimeEntryController) GetUserTimeEntries(c *fiber.Ctx) error {
 		return nil
 	}
 
-	entries, err := t.timeEntryService.GetUserTimeEntries(userAuth.UID)
+	entries, err := t.timeEntryService.GetUserTimeEntries(c.UserContext(), userAuth.UID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "An error occurred while retrieving time entries"))
 	}
 
+	entries, err = t.withNotes(c, entries, userAuth.UID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "An error occurred while retrieving linked notes"))
+	}
+
 	return c.Status(fiber.StatusOK).JSON(utils.CreateApiResponse(true, entries, "Time entries retrieved successfully"))
 }
 
@@ -47,6 +55,7 @@ func (t *TimeEntryController) GetUserTimeEntries(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param include query string false "Set to notes to embed the linked note stubs"
 // @Param timeEntry body models.TimeEntryCreate true "Time entry to create"
 // @Success 200 {object} models.ApiResponse[[]models.TimeEntry]
 // @Failure 400 {object} models.ApiErrorResponse
@@ -63,11 +72,16 @@ func (t *TimeEntryController) CreateTimeEntry(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(utils.CreateApiResponse[interface{}](false, nil, "Invalid request body"))
 	}
 
-	entries, err := t.timeEntryService.CreateTimeEntry(entryToCreate, userAuth.UID)
+	entries, err := t.timeEntryService.CreateTimeEntry(c.UserContext(), entryToCreate, userAuth.UID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "An error occurred while creating time entry"))
 	}
 
+	entries, err = t.withNotes(c, entries, userAuth.UID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "An error occurred while retrieving linked notes"))
+	}
+
 	return c.Status(fiber.StatusOK).JSON(utils.CreateApiResponse(true, entries, "Time entry created successfully"))
 }
 
@@ -77,9 +91,13 @@ func (t *TimeEntryController) CreateTimeEntry(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param include query string false "Set to notes to embed the linked note stubs"
+// @Param If-Match header string false "ETag of the version being updated"
 // @Param timeEntry body models.TimeEntry true "Time entry to update"
 // @Success 200 {object} models.ApiResponse[[]models.TimeEntry]
 // @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Failure 412 {object} models.ApiResponse[models.TimeEntry] "current server copy"
 // @Failure 500 {object} models.ApiErrorResponse
 // @Router /time-entries/ [put]
 func (t *TimeEntryController) UpdateTimeEntry(c *fiber.Ctx) error {
@@ -93,19 +111,46 @@ func (t *TimeEntryController) UpdateTimeEntry(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(utils.CreateApiResponse[interface{}](false, nil, "Invalid request body"))
 	}
 
-	entries, err := t.timeEntryService.UpdateTimeEntry(entryToUpdate, userAuth.UID)
+	expectedVersion, err := utils.ParseIfMatch(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(utils.CreateApiResponse[interface{}](false, nil, err.Error()))
+	}
+
+	updated, err := t.timeEntryService.UpdateTimeEntry(c.UserContext(), entryToUpdate, userAuth.UID, expectedVersion)
+	if errors.Is(err, models.ErrVersionConflict) {
+		current, err := t.timeEntryService.GetTimeEntry(c.UserContext(), entryToUpdate.ID, userAuth.UID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "An error occurred while retrieving time entry"))
+		}
+		c.Set(fiber.HeaderETag, utils.FormatETag(current.Version))
+		return c.Status(fiber.StatusPreconditionFailed).JSON(utils.CreateApiResponse(false, current, "Time entry was modified by another request"))
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.Status(fiber.StatusNotFound).JSON(utils.CreateApiResponse[interface{}](false, nil, "Time entry not found"))
+	}
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "An error occurred while updating time entry"))
 	}
 
+	c.Set(fiber.HeaderETag, utils.FormatETag(updated.Version))
+	entries, err := t.timeEntryService.GetUserTimeEntries(c.UserContext(), userAuth.UID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "An error occurred while retrieving time entries"))
+	}
+	entries, err = t.withNotes(c, entries, userAuth.UID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "An error occurred while retrieving linked notes"))
+	}
+
 	return c.Status(fiber.StatusOK).JSON(utils.CreateApiResponse(true, entries, "Time entry updated successfully"))
 }
 
 // @Summary Delete a time entry
-// @Description Delete a time entry by ID for the authenticated user
+// @Description Move a time entry to the trash
 // @Tags time-entries
 // @Produce json
 // @Security BearerAuth
+// @Param include query string false "Set to notes to embed the linked note stubs"
 // @Param id path int true "Time Entry ID"
 // @Success 200 {object} models.ApiResponse[[]models.TimeEntry]
 // @Failure 400 {object} models.ApiErrorResponse
@@ -123,11 +168,16 @@ func (t *TimeEntryController) DeleteTimeEntry(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(utils.CreateApiResponse[interface{}](false, nil, "Invalid time entry ID"))
 	}
 
-	entries, err := t.timeEntryService.DeleteTimeEntry(timeEntryID, userAuth.UID)
+	entries, err := t.timeEntryService.DeleteTimeEntry(c.UserContext(), timeEntryID, userAuth.UID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "An error occurred while deleting time entry"))
 	}
 
+	entries, err = t.withNotes(c, entries, userAuth.UID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "An error occurred while retrieving linked notes"))
+	}
+
 	return c.Status(fiber.StatusOK).JSON(utils.CreateApiResponse(true, entries, "Time entry deleted successfully"))
 }
 
@@ -137,6 +187,7 @@ func (t *TimeEntryController) DeleteTimeEntry(c *fiber.Ctx) error {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param include query string false "Set to notes to embed the linked note stubs"
 // @Param id path int true "Time Entry ID"
 // @Param project body models.AssignProjectPayload true "Project assignment payload"
 // @Success 200 {object} models.ApiResponse[[]models.TimeEntry]
@@ -160,10 +211,24 @@ func (t *TimeEntryController) AssignProjectToTime(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(utils.CreateApiResponse[interface{}](false, nil, "Invalid request body"))
 	}
 
-	entries, err := t.timeEntryService.AssignProjectToTime(timeEntryID, payload.ProjectID, userAuth.UID)
+	entries, err := t.timeEntryService.AssignProjectToTime(c.UserContext(), timeEntryID, payload.ProjectID, userAuth.UID)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "An error occurred while assigning project"))
 	}
 
+	entries, err = t.withNotes(c, entries, userAuth.UID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "An error occurred while retrieving linked notes"))
+	}
+
 	return c.Status(fiber.StatusOK).JSON(utils.CreateApiResponse(true, entries, "Project assigned to time entry successfully"))
 }
+
+// withNotes embeds the linked note stubs when the request asks for
+// ?include=notes.
+func (t *TimeEntryController) withNotes(c *fiber.Ctx, entries []models.TimeEntry, userID string) ([]models.TimeEntry, error) {
+	if c.Query("include") != "notes" {
+		return entries, nil
+	}
+	return t.timeEntryService.IncludeNotes(c.UserContext(), entries, userID)
+}
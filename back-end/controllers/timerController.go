@@ -0,0 +1,161 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type TimerController struct {
+	service *services.TimerService
+}
+
+func NewTimerController(service *services.TimerService) *TimerController {
+	return &TimerController{service: service}
+}
+
+// @Summary Get the running timer
+// @Description Get the user's running timer. Data is null when no timer is running.
+// @Tags timer
+// @Produce json
+// @Success 200 {object} models.ApiResponse[models.RunningTimer]
+// @Failure 500 {object} models.ApiErrorResponse
+// @Router /timer [get]
+func (c *TimerController) GetTimer(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(string)
+	timer, err := c.service.GetTimer(ctx.UserContext(), userID)
+	if err != nil {
+		return ctx.Status(500).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[*models.RunningTimer]{
+		Success: true,
+		Data:    timer,
+	})
+}
+
+// @Summary Start the timer
+// @Description Start a timer, now or at StartDate. Only one timer runs at a time. Without a ProjectID the default project from the user settings is used.
+// @Tags timer
+// @Accept json
+// @Produce json
+// @Param timer body models.TimerStart true "Timer to start"
+// @Success 201 {object} models.ApiResponse[models.RunningTimer]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 409 {object} models.ApiErrorResponse
+// @Router /timer/start [post]
+func (c *TimerController) StartTimer(ctx *fiber.Ctx) error {
+	var start models.TimerStart
+	if err := ctx.BodyParser(&start); err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid request body",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	settings := ctx.Locals("settings").(*models.UserSettings)
+	timer, err := c.service.StartTimer(ctx.UserContext(), &start, settings, userID)
+	if err != nil {
+		return ctx.Status(timerErrorStatus(err, 400)).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(201).JSON(models.ApiResponse[*models.RunningTimer]{
+		Success: true,
+		Data:    timer,
+		Message: "Timer started successfully",
+	})
+}
+
+// @Summary Update the running timer
+// @Description Change the description, project or start of the running timer. StartDate is kept when omitted.
+// @Tags timer
+// @Accept json
+// @Produce json
+// @Param timer body models.TimerStart true "Timer data"
+// @Success 200 {object} models.ApiResponse[models.RunningTimer]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /timer [put]
+func (c *TimerController) UpdateTimer(ctx *fiber.Ctx) error {
+	var update models.TimerStart
+	if err := ctx.BodyParser(&update); err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid request body",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	timer, err := c.service.UpdateTimer(ctx.UserContext(), &update, userID)
+	if err != nil {
+		return ctx.Status(timerErrorStatus(err, 400)).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[*models.RunningTimer]{
+		Success: true,
+		Data:    timer,
+		Message: "Timer updated successfully",
+	})
+}
+
+// @Summary Stop the timer
+// @Description Stop the running timer and record it as a time entry ending now or at EndDate
+// @Tags timer
+// @Accept json
+// @Produce json
+// @Param timer body models.TimerStop false "When the timer stopped"
+// @Success 200 {object} models.ApiResponse[models.TimerStopResult]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Failure 500 {object} models.ApiErrorResponse
+// @Router /timer/stop [post]
+func (c *TimerController) StopTimer(ctx *fiber.Ctx) error {
+	var stop models.TimerStop
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&stop); err != nil {
+			return ctx.Status(400).JSON(models.ApiErrorResponse{
+				Success: false,
+				Message: "Invalid request body",
+			})
+		}
+	}
+
+	userID := ctx.Locals("userID").(string)
+	result, err := c.service.StopTimer(ctx.UserContext(), &stop, userID)
+	if err != nil {
+		return ctx.Status(timerErrorStatus(err, 500)).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[*models.TimerStopResult]{
+		Success: true,
+		Data:    result,
+		Message: "Timer stopped successfully",
+	})
+}
+
+// timerErrorStatus maps the timer state errors to their status, and any
+// other error to fallback.
+func timerErrorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, models.ErrTimerRunning):
+		return 409
+	case errors.Is(err, models.ErrTimerNotRunning):
+		return 404
+	}
+	return fallback
+}
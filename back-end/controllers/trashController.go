@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type TrashController struct {
+	service *services.TrashService
+}
+
+func NewTrashController(service *services.TrashService) *TrashController {
+	return &TrashController{service: service}
+}
+
+// @Summary Get trash
+// @Description Get the user's deleted notes, folders, time entries and projects, most recently deleted first
+// @Tags trash
+// @Produce json
+// @Success 200 {object} models.ApiResponse[models.Trash]
+// @Failure 500 {object} models.ApiErrorResponse
+// @Router /trash [get]
+func (c *TrashController) GetTrash(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(string)
+	trash, err := c.service.GetTrash(ctx.UserContext(), userID)
+	if err != nil {
+		return ctx.Status(500).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[*models.Trash]{
+		Success: true,
+		Data:    trash,
+	})
+}
+
+// @Summary Restore note
+// @Description Restore a deleted note. Deleted folders above it are restored too.
+// @Tags trash
+// @Produce json
+// @Param id path int true "Note ID"
+// @Success 200 {object} models.ApiResponse[models.Trash]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /trash/notes/{id}/restore [post]
+func (c *TrashController) RestoreNote(ctx *fiber.Ctx) error {
+	return c.restore(ctx, "note", c.service.RestoreNote)
+}
+
+// @Summary Restore folder
+// @Description Restore a deleted folder with the subfolders and notes deleted along with it. Deleted folders above it are restored too.
+// @Tags trash
+// @Produce json
+// @Param id path int true "Folder ID"
+// @Success 200 {object} models.ApiResponse[models.Trash]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /trash/folders/{id}/restore [post]
+func (c *TrashController) RestoreFolder(ctx *fiber.Ctx) error {
+	return c.restore(ctx, "folder", c.service.RestoreFolder)
+}
+
+// @Summary Restore time entry
+// @Description Restore a deleted time entry. Its project is restored too if it was deleted.
+// @Tags trash
+// @Produce json
+// @Param id path int true "Time Entry ID"
+// @Success 200 {object} models.ApiResponse[models.Trash]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /trash/time-entries/{id}/restore [post]
+func (c *TrashController) RestoreTimeEntry(ctx *fiber.Ctx) error {
+	return c.restore(ctx, "time entry", c.service.RestoreTimeEntry)
+}
+
+// @Summary Restore project
+// @Description Restore a deleted project with the time entries deleted along with it
+// @Tags trash
+// @Produce json
+// @Param id path int true "Project ID"
+// @Success 200 {object} models.ApiResponse[models.Trash]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /trash/projects/{id}/restore [post]
+func (c *TrashController) RestoreProject(ctx *fiber.Ctx) error {
+	return c.restore(ctx, "project", c.service.RestoreProject)
+}
+
+func (c *TrashController) restore(ctx *fiber.Ctx, label string, restore func(context.Context, int, string) (*models.Trash, error)) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid " + label + " ID",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	trash, err := restore(ctx.UserContext(), id, userID)
+	if err != nil {
+		return ctx.Status(404).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[*models.Trash]{
+		Success: true,
+		Data:    trash,
+	})
+}
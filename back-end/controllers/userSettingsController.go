@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type UserSettingsController struct {
+	service *services.UserSettingsService
+}
+
+func NewUserSettingsController(service *services.UserSettingsService) *UserSettingsController {
+	return &UserSettingsController{service: service}
+}
+
+// @Summary Get user settings
+// @Description Get the user's timezone, week start, working hours, default project, duration rounding, date format and currency. Users who saved none get the defaults.
+// @Tags settings
+// @Produce json
+// @Success 200 {object} models.ApiResponse[models.UserSettings]
+// @Router /me/settings [get]
+func (c *UserSettingsController) GetSettings(ctx *fiber.Ctx) error {
+	return ctx.JSON(models.ApiResponse[*models.UserSettings]{
+		Success: true,
+		Data:    ctx.Locals("settings").(*models.UserSettings),
+	})
+}
+
+// @Summary Update user settings
+// @Description Replace the user's settings. Omitted fields take their default; an empty WorkingHours list means no working hours. Weekdays are lower case English names, rounding modes none, nearest, up or down, and date formats YYYY-MM-DD, DD/MM/YYYY, MM/DD/YYYY or DD.MM.YYYY.
+// @Tags settings
+// @Accept json
+// @Produce json
+// @Param settings body models.UserSettings true "User settings"
+// @Success 200 {object} models.ApiResponse[models.UserSettings]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Router /me/settings [put]
+func (c *UserSettingsController) UpdateSettings(ctx *fiber.Ctx) error {
+	var settings models.UserSettings
+	if err := ctx.BodyParser(&settings); err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid request body",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	updated, err := c.service.UpdateSettings(ctx.UserContext(), &settings, userID)
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[*models.UserSettings]{
+		Success: true,
+		Data:    updated,
+		Message: "Settings updated successfully",
+	})
+}
@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type WebhookController struct {
+	service *services.WebhookService
+}
+
+func NewWebhookController(service *services.WebhookService) *WebhookController {
+	return &WebhookController{service: service}
+}
+
+// @Summary Get webhooks
+// @Description Get the user's webhooks. Secrets are not included.
+// @Tags webhooks
+// @Produce json
+// @Success 200 {object} models.ApiResponse[[]models.Webhook]
+// @Failure 500 {object} models.ApiErrorResponse
+// @Router /webhooks [get]
+func (c *WebhookController) GetWebhooks(ctx *fiber.Ctx) error {
+	userID := ctx.Locals("userID").(string)
+	hooks, err := c.service.GetWebhooks(ctx.UserContext(), userID)
+	if err != nil {
+		return ctx.Status(500).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[[]*models.Webhook]{
+		Success: true,
+		Data:    hooks,
+	})
+}
+
+// @Summary Create webhook
+// @Description Register a URL to receive the user's events of the given types ("*" for all) as signed POST requests. The URL must resolve to a public address, and redirects are not followed. The response carries the signing secret, which is not shown again. Receivers check X-Webhook-Signature, "sha256=" and the hex HMAC-SHA256 of X-Webhook-Timestamp + "." + body.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body models.WebhookCreate true "Webhook data"
+// @Success 201 {object} models.ApiResponse[models.Webhook]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Router /webhooks [post]
+func (c *WebhookController) CreateWebhook(ctx *fiber.Ctx) error {
+	var hook models.WebhookCreate
+	if err := ctx.BodyParser(&hook); err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid request body",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	created, err := c.service.CreateWebhook(ctx.UserContext(), &hook, userID)
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(201).JSON(models.ApiResponse[*models.Webhook]{
+		Success: true,
+		Data:    created,
+		Message: "Webhook created successfully",
+	})
+}
+
+// @Summary Update webhook
+// @Description Change the URL and event types of a webhook, or pause it with Active
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param webhook body models.WebhookUpdate true "Webhook data"
+// @Success 200 {object} models.ApiResponse[models.Webhook]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Router /webhooks/{id} [put]
+func (c *WebhookController) UpdateWebhook(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid webhook ID",
+		})
+	}
+
+	var hook models.WebhookUpdate
+	if err := ctx.BodyParser(&hook); err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid request body",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	updated, err := c.service.UpdateWebhook(ctx.UserContext(), id, &hook, userID)
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[*models.Webhook]{
+		Success: true,
+		Data:    updated,
+		Message: "Webhook updated successfully",
+	})
+}
+
+// @Summary Delete webhook
+// @Description Delete a webhook and its delivery log
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} models.ApiResponse[interface{}]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Failure 404 {object} models.ApiErrorResponse
+// @Router /webhooks/{id} [delete]
+func (c *WebhookController) DeleteWebhook(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid webhook ID",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	if err := c.service.DeleteWebhook(ctx.UserContext(), id, userID); err != nil {
+		return ctx.Status(404).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[interface{}]{
+		Success: true,
+		Data:    nil,
+		Message: "Webhook deleted successfully",
+	})
+}
+
+// @Summary Get webhook deliveries
+// @Description Get the latest 100 deliveries of a webhook, newest first
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param status query string false "Only deliveries with this status: pending, succeeded or failed"
+// @Success 200 {object} models.ApiResponse[[]models.WebhookDelivery]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Router /webhooks/{id}/deliveries [get]
+func (c *WebhookController) GetDeliveries(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid webhook ID",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	deliveries, err := c.service.GetDeliveries(ctx.UserContext(), id, ctx.Query("status"), userID)
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.JSON(models.ApiResponse[[]*models.WebhookDelivery]{
+		Success: true,
+		Data:    deliveries,
+	})
+}
+
+// @Summary Replay webhook delivery
+// @Description Send the payload of a finished delivery again, as a new delivery
+// @Tags webhooks
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param deliveryId path int true "Delivery ID"
+// @Success 201 {object} models.ApiResponse[models.WebhookDelivery]
+// @Failure 400 {object} models.ApiErrorResponse
+// @Router /webhooks/{id}/deliveries/{deliveryId}/replay [post]
+func (c *WebhookController) ReplayDelivery(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("id"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid webhook ID",
+		})
+	}
+	deliveryID, err := strconv.Atoi(ctx.Params("deliveryId"))
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: "Invalid delivery ID",
+		})
+	}
+
+	userID := ctx.Locals("userID").(string)
+	delivery, err := c.service.ReplayDelivery(ctx.UserContext(), deliveryID, id, userID)
+	if err != nil {
+		return ctx.Status(400).JSON(models.ApiErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	}
+
+	return ctx.Status(201).JSON(models.ApiResponse[*models.WebhookDelivery]{
+		Success: true,
+		Data:    delivery,
+		Message: "Webhook delivery queued",
+	})
+}
@@ -1,31 +1,56 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"log"
+	"time"
 
-	"github.com/RiadMefti/TimeTracker/back-end/utils"
+	"github.com/RiadMefti/TimeTracker/back-end/config"
 	_ "github.com/lib/pq"
 )
 
-func InitDb() (*sql.DB, error) {
-	// Get database credentials from environment variables
-	dbHost := utils.GetEnv("DB_HOST", "postgres")
-	dbUser := utils.GetEnv("DB_USER", "postgres")
-	dbPassword := utils.GetEnv("DB_PASSWORD", "postgres")
-	dbName := utils.GetEnv("DB_NAME", "app_db")
-	dbPort := utils.GetEnv("DB_PORT", "5432")
-	// Construct connection string
-	connexionString := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		dbHost, dbPort, dbUser, dbPassword, dbName)
+// InitDb connects to the database and, when AutoMigrate is set, applies the
+// pending migrations before the server uses it.
+func InitDb(config config.Database) (*sql.DB, error) {
+	if config.AutoMigrate {
+		if err := migrateUp(config); err != nil {
+			return nil, err
+		}
+	}
+	return Open(config)
+}
 
-	db, err := sql.Open("postgres", connexionString)
+// migrateUp applies the pending migrations on a connection of its own, without
+// the query timeout that protects the server.
+func migrateUp(config config.Database) error {
+	config.QueryTimeout = 0
+	db, err := Open(config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open database connection: %w", err)
+		return err
 	}
+	defer db.Close()
+	return Migrate(context.Background(), db, "up", log.Printf)
+}
 
-	err = db.Ping()
+// pingTimeout bounds the check that the database is reachable at startup.
+const pingTimeout = 10 * time.Second
+
+// Open connects to the database with the configured pool settings.
+func Open(config config.Database) (*sql.DB, error) {
+	db, err := sql.Open("postgres", config.DSN())
 	if err != nil {
+		return nil, fmt.Errorf("failed to open database connection: %w", err)
+	}
+	db.SetMaxOpenConns(config.MaxOpenConns)
+	db.SetMaxIdleConns(config.MaxIdleConns)
+	db.SetConnMaxLifetime(config.ConnMaxLifetime)
+
+	ctx, cancel := context.WithTimeout(context.Background(), pingTimeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 	return db, nil
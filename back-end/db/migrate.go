@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/RiadMefti/TimeTracker/back-end/migrations"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+)
+
+// Migrate runs a migration command against the embedded migrations: "up"
+// applies the pending ones, "down" rolls back the latest, "redo" rolls it
+// back and applies it again and "status" lists them all. Each step holds a
+// Postgres advisory lock, so replicas starting together apply migrations
+// once; the others wait for the lock and then find nothing to do. Redo
+// takes the lock for each of its two steps.
+func Migrate(ctx context.Context, db *sql.DB, command string, logf func(format string, args ...any)) error {
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return fmt.Errorf("failed to create migration lock: %w", err)
+	}
+	provider, err := goose.NewProvider(goose.DialectPostgres, db, migrations.FS,
+		goose.WithSessionLocker(locker), goose.WithGoMigrations(migrations.Go...))
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	switch command {
+	case "up":
+		results, err := provider.Up(ctx)
+		var partial *goose.PartialError
+		if errors.As(err, &partial) {
+			results = partial.Applied
+		}
+		logResults(logf, results...)
+		if err != nil {
+			return fmt.Errorf("failed to apply migrations: %w", err)
+		}
+		if len(results) == 0 {
+			logf("migrations: database is up to date")
+		}
+	case "down":
+		result, err := provider.Down(ctx)
+		if errors.Is(err, goose.ErrNoNextVersion) {
+			return fmt.Errorf("no migration to roll back")
+		}
+		logResults(logf, result)
+		if err != nil {
+			return fmt.Errorf("failed to roll back migration: %w", err)
+		}
+	case "redo":
+		result, err := provider.Down(ctx)
+		if errors.Is(err, goose.ErrNoNextVersion) {
+			return fmt.Errorf("no migration to redo")
+		}
+		logResults(logf, result)
+		if err != nil {
+			return fmt.Errorf("failed to roll back migration: %w", err)
+		}
+		result, err = provider.UpByOne(ctx)
+		logResults(logf, result)
+		if err != nil {
+			return fmt.Errorf("failed to apply migration: %w", err)
+		}
+	case "status":
+		statuses, err := provider.Status(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get migration status: %w", err)
+		}
+		for _, status := range statuses {
+			applied := "-"
+			if status.State == goose.StateApplied {
+				applied = status.AppliedAt.UTC().Format("2006-01-02 15:04:05")
+			}
+			source := status.Source.Path
+			if source == "" {
+				source = fmt.Sprintf("%d (go)", status.Source.Version)
+			}
+			logf("%-8s %-19s %s", status.State, applied, source)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down, redo or status", command)
+	}
+	return nil
+}
+
+func logResults(logf func(format string, args ...any), results ...*goose.MigrationResult) {
+	for _, result := range results {
+		if result != nil {
+			logf("migrations: %s", result)
+		}
+	}
+}
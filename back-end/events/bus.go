@@ -0,0 +1,263 @@
+// Package events is an in-process publish/subscribe bus for the changes a
+// user makes, used to push them to the user's other devices.
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// historySize is how many events per user are kept for resuming.
+	historySize       = 256
+	subscriptionQueue = 64
+	// historyIdle is how long the history of a user without subscriptions
+	// is kept after their last event.
+	historyIdle = 15 * time.Minute
+)
+
+// Event types.
+const (
+	TimeEntryCreated = "time_entry.created"
+	TimeEntryUpdated = "time_entry.updated"
+	TimeEntryDeleted = "time_entry.deleted"
+	TimeBoxCreated   = "time_box.created"
+	TimeBoxUpdated   = "time_box.updated"
+	TimeBoxDeleted   = "time_box.deleted"
+	TimerStarted     = "timer.started"
+	TimerUpdated     = "timer.updated"
+	TimerStopped     = "timer.stopped"
+	ProjectCreated   = "project.created"
+	ProjectUpdated   = "project.updated"
+	ProjectDeleted   = "project.deleted"
+	FolderCreated    = "folder.created"
+	FolderUpdated    = "folder.updated"
+	FolderDeleted    = "folder.deleted"
+	NoteCreated      = "note.created"
+	NoteUpdated      = "note.updated"
+	NoteDeleted      = "note.deleted"
+)
+
+// Types lists every event type.
+var Types = []string{
+	TimeEntryCreated, TimeEntryUpdated, TimeEntryDeleted,
+	TimeBoxCreated, TimeBoxUpdated, TimeBoxDeleted,
+	TimerStarted, TimerUpdated, TimerStopped,
+	ProjectCreated, ProjectUpdated, ProjectDeleted,
+	FolderCreated, FolderUpdated, FolderDeleted,
+	NoteCreated, NoteUpdated, NoteDeleted,
+}
+
+// Ref is the data of delete events. Other events carry the entity that was
+// created or updated.
+type Ref struct {
+	ID int
+}
+
+type Event struct {
+	ID      string
+	Type    string
+	Created time.Time
+	Data    json.RawMessage
+}
+
+// Listener is called with every event published, whatever the user.
+type Listener func(userID string, event Event)
+
+// Subscription delivers the events published for one user. C is closed when
+// the subscriber falls too far behind; it can resume from the last event it
+// received.
+type Subscription struct {
+	C      <-chan Event
+	c      chan Event
+	userID string
+}
+
+// Bus fans events out to the subscriptions of their user. Event IDs are
+// "<epoch>-<sequence>", where the epoch changes with every process, so IDs
+// from before a restart are recognised as not resumable.
+type Bus struct {
+	mu        sync.Mutex
+	epoch     string
+	seq       uint64
+	history   map[string][]Event
+	subs      map[string]map[*Subscription]bool
+	listeners []Listener
+
+	// swept is when idle histories were last dropped, and evicted the
+	// newest sequence number they held.
+	swept   time.Time
+	evicted uint64
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		epoch:   strconv.FormatInt(time.Now().UnixNano(), 36),
+		history: map[string][]Event{},
+		subs:    map[string]map[*Subscription]bool{},
+		swept:   time.Now(),
+	}
+}
+
+// Publish sends an event to the user's subscribers. data is encoded as JSON
+// right away, so callers may keep modifying it.
+func (b *Bus) Publish(userID, eventType string, data interface{}) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("events: failed to encode %s: %v", eventType, err)
+		return
+	}
+
+	b.mu.Lock()
+	now := time.Now()
+	if now.Sub(b.swept) > time.Minute {
+		b.sweep(now)
+	}
+	b.seq++
+	event := Event{ID: fmt.Sprintf("%s-%d", b.epoch, b.seq), Type: eventType, Created: now, Data: payload}
+
+	history := append(b.history[userID], event)
+	if len(history) > historySize {
+		history = history[len(history)-historySize:]
+	}
+	b.history[userID] = history
+
+	for sub := range b.subs[userID] {
+		select {
+		case sub.c <- event:
+		default:
+			b.drop(sub)
+		}
+	}
+	listeners := b.listeners
+	b.mu.Unlock()
+
+	for _, listener := range listeners {
+		listener(userID, event)
+	}
+}
+
+// Listen registers fn to be called with every event after it has been
+// queued for the subscribers. fn runs on the publishing goroutine, so it
+// delays the caller of Publish.
+func (b *Bus) Listen(fn Listener) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.listeners = append(b.listeners, fn)
+}
+
+// Subscribe starts delivering the user's events. When lastEventID is set,
+// the events published after it are returned as missed; complete is false
+// when some of them are no longer known and the client should reload.
+func (b *Bus) Subscribe(userID, lastEventID string) (missed []Event, complete bool, sub *Subscription) {
+	c := make(chan Event, subscriptionQueue)
+	sub = &Subscription{C: c, c: c, userID: userID}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.subs[userID] == nil {
+		b.subs[userID] = map[*Subscription]bool{}
+	}
+	b.subs[userID][sub] = true
+
+	if lastEventID == "" {
+		return nil, true, sub
+	}
+	missed, complete = b.since(userID, lastEventID)
+	return missed, complete, sub
+}
+
+func (b *Bus) since(userID, lastEventID string) ([]Event, bool) {
+	epoch, seqStr, ok := strings.Cut(lastEventID, "-")
+	seq, err := strconv.ParseUint(seqStr, 10, 64)
+	if !ok || err != nil || epoch != b.epoch {
+		return nil, false
+	}
+
+	// Idle histories are dropped without a trace, so resuming from before
+	// the newest event dropped that way may have missed some.
+	complete := seq >= b.evicted
+
+	history := b.history[userID]
+	for i, event := range history {
+		if eventSeq(event.ID) > seq {
+			// Sequence numbers are shared by all users, so gaps are
+			// normal. Events are only missing if some were evicted
+			// after lastEventID.
+			complete = complete && (i > 0 || seq >= b.oldestSeen(userID))
+			missed := make([]Event, len(history)-i)
+			copy(missed, history[i:])
+			return missed, complete
+		}
+	}
+	return nil, complete
+}
+
+// sweep drops the history of users without subscriptions whose last event
+// is older than historyIdle, so the bus does not keep events for every user
+// seen since the process started. The caller must hold b.mu.
+func (b *Bus) sweep(now time.Time) {
+	for userID, history := range b.history {
+		last := history[len(history)-1]
+		if len(b.subs[userID]) > 0 || now.Sub(last.Created) < historyIdle {
+			continue
+		}
+		b.evicted = max(b.evicted, eventSeq(last.ID))
+		delete(b.history, userID)
+	}
+	b.swept = now
+}
+
+// oldestSeen is the sequence number of the newest event evicted from the
+// user's history, at most. Anything after it is still in the history.
+func (b *Bus) oldestSeen(userID string) uint64 {
+	history := b.history[userID]
+	if len(history) < historySize {
+		return 0
+	}
+	return eventSeq(history[0].ID) - 1
+}
+
+func eventSeq(id string) uint64 {
+	_, seqStr, _ := strings.Cut(id, "-")
+	seq, _ := strconv.ParseUint(seqStr, 10, 64)
+	return seq
+}
+
+// Close ends every subscription, so that event streams finish and their
+// clients reconnect, to another server once this one has stopped.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, subs := range b.subs {
+		for sub := range subs {
+			b.drop(sub)
+		}
+	}
+}
+
+func (b *Bus) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.drop(sub)
+}
+
+// drop removes the subscription and closes its channel. The caller must
+// hold b.mu.
+func (b *Bus) drop(sub *Subscription) {
+	subs := b.subs[sub.userID]
+	if !subs[sub] {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(b.subs, sub.userID)
+	}
+	close(sub.c)
+}
@@ -8,6 +8,7 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"github.com/RiadMefti/TimeTracker/back-end/app"
 	_ "github.com/RiadMefti/TimeTracker/back-end/docs"
@@ -19,7 +20,14 @@ import (
 // @host localhost:3000
 // @BasePath /
 func main() {
-	err := app.RunApp()
+	var err error
+	// `migrate up|down|redo|status` manages the database schema instead of
+	// serving
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err = app.RunMigrate(os.Args[2:])
+	} else {
+		err = app.RunApp()
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
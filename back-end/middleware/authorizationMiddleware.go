@@ -1,24 +1,29 @@
 package middleware
 
 import (
-	"context"
+	"errors"
 	"strings"
 
-	firebase "firebase.google.com/go"
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/services"
 	"github.com/RiadMefti/TimeTracker/back-end/utils"
 	"github.com/gofiber/fiber/v2"
 )
 
-func AuthorizationMiddleware(firebaseService *firebase.App) fiber.Handler {
+// AuthorizationMiddleware accepts the tokens of the configured auth provider
+// and personal access tokens. Access tokens are also checked against the scopes they were
+// issued with. The "user" local holds the *models.Identity of the caller
+// and "userID" its UID.
+func AuthorizationMiddleware(authenticator services.Authenticator, tokenService *services.AccessTokenService) fiber.Handler {
 
 	return func(c *fiber.Ctx) error {
-		ctx := context.Background()
-		client, err := firebaseService.Auth(ctx)
-		if err != nil {
-			panic(err)
-		}
 		header := c.GetReqHeaders()
 		authHeader := header["Authorization"]
+		// Browsers cannot set headers on WebSocket handshakes or EventSource
+		// requests.
+		if token := c.Query("access_token"); len(authHeader) == 0 && token != "" && acceptsQueryToken(c) {
+			authHeader = []string{"Bearer " + token}
+		}
 		if len(authHeader) == 0 {
 
 			return c.Status(fiber.StatusUnauthorized).JSON(utils.CreateApiResponse[interface{}](false, nil, "Missing Authorization header"))
@@ -29,21 +34,41 @@ func AuthorizationMiddleware(firebaseService *firebase.App) fiber.Handler {
 			return c.Status(fiber.StatusUnauthorized).JSON(utils.CreateApiResponse[interface{}](false, nil, "Invalid Authorization header format"))
 
 		}
-		uidToken, err := client.VerifyIDToken(ctx, parts[1])
-		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(utils.CreateApiResponse[interface{}](false, nil, "Unauthorized"))
-
-		}
-
-		user, err := client.GetUser(ctx, uidToken.UID)
-		if err != nil {
-
-			return c.Status(fiber.StatusUnauthorized).JSON(utils.CreateApiResponse[interface{}](false, nil, "Can not find the user"))
 
+		var identity *models.Identity
+		if services.IsAccessToken(parts[1]) {
+			accessToken, err := tokenService.Authenticate(c.UserContext(), parts[1])
+			if err != nil {
+				return authError(c, err)
+			}
+			if !tokenService.Allows(accessToken, c.Method(), c.Path()) {
+				return c.Status(fiber.StatusForbidden).JSON(utils.CreateApiResponse[interface{}](false, nil, "Access token scope does not allow this request"))
+			}
+			identity = &models.Identity{UID: accessToken.UserID}
+		} else {
+			var err error
+			identity, err = authenticator.Verify(c.UserContext(), parts[1])
+			if err != nil {
+				return authError(c, err)
+			}
 		}
 
-		c.Locals("user", user)
-		c.Locals("userID", uidToken.UID)
+		c.Locals("user", identity)
+		c.Locals("userID", identity.UID)
 		return c.Next()
 	}
 }
+
+// authError answers 401 for bad tokens and 503 when they could not be
+// checked.
+func authError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, models.ErrInvalidToken) {
+		return c.Status(fiber.StatusUnauthorized).JSON(utils.CreateApiResponse[interface{}](false, nil, "Unauthorized"))
+	}
+	return c.Status(fiber.StatusServiceUnavailable).JSON(utils.CreateApiResponse[interface{}](false, nil, "Authentication is temporarily unavailable"))
+}
+
+func acceptsQueryToken(c *fiber.Ctx) bool {
+	return strings.EqualFold(c.Get(fiber.HeaderUpgrade), "websocket") ||
+		strings.Contains(c.Get(fiber.HeaderAccept), "text/event-stream")
+}
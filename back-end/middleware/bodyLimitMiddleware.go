@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/RiadMefti/TimeTracker/back-end/config"
+	"github.com/RiadMefti/TimeTracker/back-end/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// BodyLimitRule caps the request bodies of the routes matching Path, a path
+// prefix in which "*" stands for one segment.
+type BodyLimitRule struct {
+	Path  string
+	Bytes int
+}
+
+// BodyLimits caps request bodies per route. Fiber reads whole bodies before
+// any handler runs, so its own BodyLimit must be set to Max.
+type BodyLimits struct {
+	Default int
+	Rules   []BodyLimitRule
+}
+
+// NewBodyLimits applies the configured caps to their routes: Notes to note
+// and folder content, Uploads to imports and attachment uploads, which are
+// also checked against the attachment size limit.
+func NewBodyLimits(config config.BodyLimits) *BodyLimits {
+	return &BodyLimits{
+		Default: config.Default,
+		Rules: []BodyLimitRule{
+			{Path: "/notes/import", Bytes: config.Uploads},
+			{Path: "/folders/import-archive", Bytes: config.Uploads},
+			{Path: "/notes/*/attachments", Bytes: config.Uploads},
+			{Path: "/notes", Bytes: config.Notes},
+			{Path: "/folders", Bytes: config.Notes},
+		},
+	}
+}
+
+// Max is the largest body any route accepts.
+func (l *BodyLimits) Max() int {
+	largest := l.Default
+	for _, rule := range l.Rules {
+		if rule.Bytes > largest {
+			largest = rule.Bytes
+		}
+	}
+	return largest
+}
+
+// BodyLimitMiddleware answers 413 for bodies over the cap of their route.
+func BodyLimitMiddleware(limits *BodyLimits) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		limit := limits.Default
+		for _, rule := range limits.Rules {
+			if matchPath(rule.Path, c.Path()) {
+				limit = rule.Bytes
+				break
+			}
+		}
+
+		if len(c.Request().Body()) > limit {
+			return c.Status(fiber.StatusRequestEntityTooLarge).JSON(utils.CreateApiResponse[interface{}](false, nil, fmt.Sprintf("Request body is larger than %d bytes", limit)))
+		}
+		return c.Next()
+	}
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/RiadMefti/TimeTracker/back-end/config"
+	"github.com/gofiber/fiber/v2"
+)
+
+// The cap of a route applies whatever the case of the path, since the
+// router ignores it.
+func TestBodyLimitMixedCase(t *testing.T) {
+	limits := NewBodyLimits(config.BodyLimits{Default: 10, Notes: 100, Uploads: 1000})
+	app := fiber.New(fiber.Config{BodyLimit: limits.Max()})
+	app.Use(BodyLimitMiddleware(limits))
+	app.Post("/*", func(c *fiber.Ctx) error { return c.SendStatus(fiber.StatusNoContent) })
+
+	tests := []struct {
+		path string
+		size int
+		want int
+	}{
+		{"/notes", 50, fiber.StatusNoContent},
+		{"/NOTES", 50, fiber.StatusNoContent},
+		{"/Notes/Import", 500, fiber.StatusNoContent},
+		{"/NOTES/3/Attachments", 500, fiber.StatusNoContent},
+		{"/Notes", 500, fiber.StatusRequestEntityTooLarge},
+		{"/Projects", 50, fiber.StatusRequestEntityTooLarge},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(fiber.MethodPost, test.path, strings.NewReader(strings.Repeat("x", test.size)))
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != test.want {
+			t.Errorf("POST %s with %d bytes: status %d, want %d", test.path, test.size, resp.StatusCode, test.want)
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/config"
+	"github.com/RiadMefti/TimeTracker/back-end/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RateLimit lets Requests requests through per Window with a token bucket:
+// bursts of up to Requests, refilled evenly over the window. Zero Requests
+// means no limit.
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// RateLimitRule applies Limit to the requests matching Path, a path prefix in
+// which "*" stands for one segment, and one of Methods, or any method when
+// empty. Rules with the same name share their buckets.
+type RateLimitRule struct {
+	Name    string
+	Path    string
+	Methods []string
+	Limit   RateLimit
+}
+
+// RateLimiter keeps a token bucket per rule and client. Clients are the
+// authenticated user when there is one, and the IP address otherwise, with
+// IPv6 addresses grouped by /64 since a single host usually gets a whole one.
+//
+// Buckets that have refilled are dropped every minute, so there are only
+// buckets for the clients seen within about a window and a minute. When
+// there are more than maxRateLimitBuckets, which takes many addresses in a
+// short time, they are swept as often as every second.
+type RateLimiter struct {
+	fallback RateLimit
+	rules    []RateLimitRule
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	swept   time.Time
+}
+
+const maxRateLimitBuckets = 100_000
+
+type tokenBucket struct {
+	limit   RateLimit
+	tokens  float64
+	updated time.Time
+}
+
+func NewRateLimiter(fallback RateLimit, rules ...RateLimitRule) *RateLimiter {
+	return &RateLimiter{fallback: fallback, rules: rules, buckets: map[string]*tokenBucket{}, swept: time.Now()}
+}
+
+// writeMethods are the methods of requests that change data.
+var writeMethods = []string{fiber.MethodPost, fiber.MethodPut, fiber.MethodPatch, fiber.MethodDelete}
+
+// NewRateLimiters builds the limiter run before authentication, keyed by
+// IP, and the one run after it, keyed by user.
+func NewRateLimiters(config config.RateLimits) (ip *RateLimiter, user *RateLimiter, err error) {
+	parse := func(name, value string) RateLimit {
+		if err != nil {
+			return RateLimit{}
+		}
+		var limit RateLimit
+		limit, err = ParseRateLimit(value)
+		if err != nil {
+			err = fmt.Errorf("invalid RATE_LIMIT_%s: %w", name, err)
+		}
+		return limit
+	}
+
+	login := parse("LOGIN", config.Login)
+	ip = NewRateLimiter(parse("IP", config.IP),
+		RateLimitRule{Name: "login", Path: "/auth/local", Methods: writeMethods, Limit: login},
+	)
+
+	timeWrites := parse("TIME_WRITES", config.TimeWrites)
+	imports := parse("IMPORT", config.Import)
+	user = NewRateLimiter(parse("USER", config.User),
+		RateLimitRule{Name: "time", Path: "/time-entries", Methods: writeMethods, Limit: timeWrites},
+		RateLimitRule{Name: "time", Path: "/time-box-entries", Methods: writeMethods, Limit: timeWrites},
+		RateLimitRule{Name: "time", Path: "/timer", Methods: writeMethods, Limit: timeWrites},
+		RateLimitRule{Name: "export", Path: "/me/export", Methods: []string{fiber.MethodPost}, Limit: parse("EXPORT", config.Export)},
+		RateLimitRule{Name: "import", Path: "/notes/import", Limit: imports},
+		RateLimitRule{Name: "import", Path: "/folders/import-archive", Limit: imports},
+		RateLimitRule{Name: "shares", Path: "/shares", Methods: []string{fiber.MethodPost}, Limit: parse("SHARE_LINKS", config.ShareLinks)},
+		RateLimitRule{Name: "webhooks", Path: "/webhooks", Methods: writeMethods, Limit: parse("WEBHOOKS", config.Webhooks)},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ip, user, nil
+}
+
+// ParseRateLimit reads "<requests>/<window>", where the window is a Go
+// duration such as "1m" or "1h", or "off" for no limit.
+func ParseRateLimit(value string) (RateLimit, error) {
+	if value == "off" {
+		return RateLimit{}, nil
+	}
+	requests, window, ok := strings.Cut(value, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("expected <requests>/<window>, got %q", value)
+	}
+	limit := RateLimit{}
+	var err error
+	if limit.Requests, err = strconv.Atoi(requests); err != nil || limit.Requests < 1 {
+		return RateLimit{}, fmt.Errorf("invalid request count %q", requests)
+	}
+	if limit.Window, err = time.ParseDuration(window); err != nil || limit.Window <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid window %q", window)
+	}
+	return limit, nil
+}
+
+// Handler rejects requests over the limit with 429 and reports the state of
+// the bucket in RateLimit-* headers. Installed after the authorization
+// middleware it limits users, before it IP addresses.
+func (l *RateLimiter) Handler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		name, limit := l.match(c.Method(), c.Path())
+		if limit.Requests == 0 {
+			return c.Next()
+		}
+
+		client := "ip:" + clientAddress(c.IP())
+		if userID, ok := c.Locals("userID").(string); ok {
+			client = "user:" + userID
+		}
+		allowed, remaining, reset, retry := l.take(name+"|"+client, limit, time.Now())
+
+		c.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, int(limit.Window.Seconds())))
+		c.Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+		c.Set("RateLimit-Remaining", strconv.Itoa(remaining))
+		c.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(reset)))
+		if !allowed {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(ceilSeconds(retry)))
+			return c.Status(fiber.StatusTooManyRequests).JSON(utils.CreateApiResponse[interface{}](false, nil, "Too many requests"))
+		}
+		return c.Next()
+	}
+}
+
+func (l *RateLimiter) match(method, path string) (string, RateLimit) {
+	for _, rule := range l.rules {
+		if matchPath(rule.Path, path) && (len(rule.Methods) == 0 || containsMethod(rule.Methods, method)) {
+			return rule.Name, rule.Limit
+		}
+	}
+	return "default", l.fallback
+}
+
+// take spends a token of the bucket at key. It returns whether there was one,
+// the whole tokens left, how long until the bucket is full again and, when
+// refused, how long until the next token.
+func (l *RateLimiter) take(key string, limit RateLimit, now time.Time) (bool, int, time.Duration, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if since := now.Sub(l.swept); since > time.Minute || (len(l.buckets) >= maxRateLimitBuckets && since > time.Second) {
+		l.sweep(now)
+	}
+
+	capacity := float64(limit.Requests)
+	rate := capacity / limit.Window.Seconds() // tokens per second
+	bucket := l.buckets[key]
+	if bucket == nil || bucket.limit != limit {
+		bucket = &tokenBucket{limit: limit, tokens: capacity, updated: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = math.Min(capacity, bucket.tokens+now.Sub(bucket.updated).Seconds()*rate)
+	bucket.updated = now
+
+	allowed := bucket.tokens >= 1
+	if allowed {
+		bucket.tokens--
+	}
+	reset := time.Duration((capacity - bucket.tokens) / rate * float64(time.Second))
+	retry := time.Duration((1 - bucket.tokens) / rate * float64(time.Second))
+	return allowed, int(bucket.tokens), reset, retry
+}
+
+// sweep drops the buckets that have refilled, which are the same as new
+// ones. The caller must hold l.mu.
+func (l *RateLimiter) sweep(now time.Time) {
+	for key, bucket := range l.buckets {
+		refill := now.Sub(bucket.updated).Seconds() * float64(bucket.limit.Requests) / bucket.limit.Window.Seconds()
+		if bucket.tokens+refill >= float64(bucket.limit.Requests) {
+			delete(l.buckets, key)
+		}
+	}
+	l.swept = now
+}
+
+// matchPath reports whether path starts with the segments of pattern, where
+// "*" matches any one segment. Segments are compared regardless of case, as
+// the router does.
+func matchPath(pattern, path string) bool {
+	want := strings.Split(strings.Trim(pattern, "/"), "/")
+	got := strings.Split(strings.Trim(path, "/"), "/")
+	if len(got) < len(want) {
+		return false
+	}
+	for i, segment := range want {
+		if segment != "*" && !strings.EqualFold(segment, got[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// clientAddress returns the address to key anonymous clients by: IPv4
+// addresses as they are, IPv6 addresses as their /64.
+func clientAddress(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil || !addr.Is6() || addr.Is4In6() {
+		return ip
+	}
+	return netip.PrefixFrom(addr, 64).Masked().String()
+}
+
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/config"
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestRateLimitRules(t *testing.T) {
+	_, user, err := NewRateLimiters(config.Default().RateLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		rule   string
+	}{
+		{fiber.MethodPost, "/time-entries", "time"},
+		{fiber.MethodPut, "/time-box-entries/3", "time"},
+		{fiber.MethodPost, "/timer/stop", "time"},
+		{fiber.MethodGet, "/time-entries", "default"},
+		{fiber.MethodPost, "/shares", "shares"},
+		{fiber.MethodGet, "/shares", "default"},
+		{fiber.MethodDelete, "/shares/3", "default"},
+		{fiber.MethodPost, "/webhooks", "webhooks"},
+		{fiber.MethodPut, "/webhooks/3", "webhooks"},
+		{fiber.MethodPost, "/webhooks/3/deliveries/9/replay", "webhooks"},
+		{fiber.MethodGet, "/webhooks/3/deliveries", "default"},
+		{fiber.MethodPost, "/notes", "default"},
+		// Routes match regardless of case, so the rules must too.
+		{fiber.MethodPost, "/TIME-ENTRIES", "time"},
+		{fiber.MethodPost, "/Timer/Start", "time"},
+		{fiber.MethodPost, "/Shares", "shares"},
+		{fiber.MethodDelete, "/WEBHOOKS/3", "webhooks"},
+		{fiber.MethodPost, "/Notes/Import", "import"},
+	}
+	for _, test := range tests {
+		if rule, _ := user.match(test.method, test.path); rule != test.rule {
+			t.Errorf("%s %s matched %q, want %q", test.method, test.path, rule, test.rule)
+		}
+	}
+}
+
+func TestLoginRateLimitMixedCase(t *testing.T) {
+	ip, _, err := NewRateLimiters(config.Default().RateLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{"/auth/local/login", "/AUTH/local/login", "/Auth/Local/Signup"} {
+		if rule, _ := ip.match(fiber.MethodPost, path); rule != "login" {
+			t.Errorf("POST %s matched %q, want login", path, rule)
+		}
+	}
+}
+
+// Buckets of clients that went quiet are dropped once they have refilled,
+// so addresses seen once do not pile up.
+func TestRateLimiterSweep(t *testing.T) {
+	limit := RateLimit{Requests: 10, Window: 2 * time.Minute}
+	l := NewRateLimiter(limit)
+	start := l.swept
+
+	for i := 0; i < 1000; i++ {
+		l.take("default|ip:10.0."+strconv.Itoa(i/256)+"."+strconv.Itoa(i%256), limit, start.Add(time.Second))
+	}
+	if len(l.buckets) != 1000 {
+		t.Fatalf("%d buckets, want 1000", len(l.buckets))
+	}
+
+	// The sweep a minute later drops the refilled buckets.
+	for i := 0; i < 10; i++ {
+		l.take("default|user:busy", limit, start.Add(90*time.Second))
+	}
+	if len(l.buckets) != 1 {
+		t.Errorf("%d buckets after the sweep, want 1", len(l.buckets))
+	}
+
+	// A client that has not refilled keeps its bucket through the next one.
+	l.take("default|ip:10.1.0.1", limit, start.Add(160*time.Second))
+	if len(l.buckets) != 2 || l.buckets["default|user:busy"] == nil {
+		t.Errorf("buckets after the second sweep: %v, want the busy one kept", l.buckets)
+	}
+}
+
+func TestRateLimiterSweepsEarlyWhenFull(t *testing.T) {
+	limit := RateLimit{Requests: 10, Window: time.Second}
+	l := NewRateLimiter(limit)
+	now := l.swept
+
+	for i := 0; i < maxRateLimitBuckets; i++ {
+		l.take("default|ip:"+strconv.Itoa(i), limit, now)
+	}
+	l.take("default|ip:next", limit, now.Add(2*time.Second))
+	if len(l.buckets) != 1 {
+		t.Errorf("%d buckets, want the full ones swept before a minute", len(l.buckets))
+	}
+}
+
+func TestClientAddress(t *testing.T) {
+	tests := map[string]string{
+		"203.0.113.7":          "203.0.113.7",
+		"::ffff:203.0.113.7":   "::ffff:203.0.113.7",
+		"2001:db8:1:2:3:4:5:6": "2001:db8:1:2::/64",
+		"2001:db8:1:2::9":      "2001:db8:1:2::/64",
+		"not an address":       "not an address",
+	}
+	for ip, want := range tests {
+		if got := clientAddress(ip); got != want {
+			t.Errorf("clientAddress(%q) = %q, want %q", ip, got, want)
+		}
+	}
+}
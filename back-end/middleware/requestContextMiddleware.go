@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequestContextMiddleware gives every request a context, which handlers pass
+// down to the queries with c.UserContext(). It ends when the handler returns,
+// after timeout when there is one, or when parent is cancelled, which the
+// server does to requests still running at the end of the shutdown timeout.
+// fasthttp does not report clients that disconnect, so the timeout is what
+// bounds the work of abandoned requests. Handlers are counted in inFlight
+// until they return.
+func RequestContextMiddleware(parent context.Context, timeout time.Duration, inFlight *InFlight) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		inFlight.add()
+		defer inFlight.done()
+
+		var ctx context.Context
+		var cancel context.CancelFunc
+		if timeout > 0 {
+			ctx, cancel = context.WithTimeout(parent, timeout)
+		} else {
+			ctx, cancel = context.WithCancel(parent)
+		}
+		defer cancel()
+
+		c.SetUserContext(ctx)
+		return c.Next()
+	}
+}
+
+// InFlight counts the handlers running, so that shutdown can wait for them
+// before closing what they use. Unlike a sync.WaitGroup, it can be waited on
+// while requests still come in.
+type InFlight struct {
+	mu    sync.Mutex
+	count int
+	idle  chan struct{}
+}
+
+func (f *InFlight) add() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.count++
+}
+
+func (f *InFlight) done() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.count--
+	if f.count == 0 && f.idle != nil {
+		close(f.idle)
+		f.idle = nil
+	}
+}
+
+// Wait waits until no handler is running or ctx is done, and returns the
+// number still running.
+func (f *InFlight) Wait(ctx context.Context) int {
+	f.mu.Lock()
+	if f.count == 0 {
+		f.mu.Unlock()
+		return 0
+	}
+	if f.idle == nil {
+		f.idle = make(chan struct{})
+	}
+	idle := f.idle
+	f.mu.Unlock()
+
+	select {
+	case <-idle:
+	case <-ctx.Done():
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.count
+}
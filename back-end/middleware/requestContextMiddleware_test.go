@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestInFlightWait(t *testing.T) {
+	var inFlight InFlight
+	if running := inFlight.Wait(context.Background()); running != 0 {
+		t.Fatalf("Wait with no handlers returned %d", running)
+	}
+
+	inFlight.add()
+	inFlight.add()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if running := inFlight.Wait(ctx); running != 2 {
+		t.Errorf("Wait at the timeout returned %d, want 2", running)
+	}
+
+	go func() {
+		inFlight.done()
+		// A request coming in while shutdown waits is waited for too.
+		inFlight.add()
+		inFlight.done()
+		inFlight.done()
+	}()
+	if running := inFlight.Wait(context.Background()); running != 0 {
+		t.Errorf("Wait returned %d, want 0", running)
+	}
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"github.com/RiadMefti/TimeTracker/back-end/services"
+	"github.com/RiadMefti/TimeTracker/back-end/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// UserSettingsMiddleware loads the settings of the caller once per request
+// into the "settings" local, as a *models.UserSettings. It must run after
+// AuthorizationMiddleware.
+func UserSettingsMiddleware(service *services.UserSettingsService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		settings, err := service.GetSettings(c.UserContext(), c.Locals("userID").(string))
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(utils.CreateApiResponse[interface{}](false, nil, "Failed to load user settings"))
+		}
+
+		c.Locals("settings", settings)
+		return c.Next()
+	}
+}
@@ -0,0 +1,37 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS note_projects (
+    note_id integer NOT NULL,
+    project_id integer NOT NULL,
+    PRIMARY KEY (note_id, project_id),
+    FOREIGN KEY (note_id) REFERENCES notes(id) ON DELETE CASCADE,
+    FOREIGN KEY (project_id) REFERENCES projects(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS note_time_entries (
+    note_id integer NOT NULL,
+    time_entry_id integer NOT NULL,
+    PRIMARY KEY (note_id, time_entry_id),
+    FOREIGN KEY (note_id) REFERENCES notes(id) ON DELETE CASCADE,
+    FOREIGN KEY (time_entry_id) REFERENCES times(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS note_time_boxes (
+    note_id integer NOT NULL,
+    time_box_id integer NOT NULL,
+    PRIMARY KEY (note_id, time_box_id),
+    FOREIGN KEY (note_id) REFERENCES notes(id) ON DELETE CASCADE,
+    FOREIGN KEY (time_box_id) REFERENCES timeBoxes(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_projects_project_id ON note_projects(project_id);
+CREATE INDEX IF NOT EXISTS idx_note_time_entries_time_entry_id ON note_time_entries(time_entry_id);
+CREATE INDEX IF NOT EXISTS idx_note_time_boxes_time_box_id ON note_time_boxes(time_box_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS note_time_boxes;
+DROP TABLE IF EXISTS note_time_entries;
+DROP TABLE IF EXISTS note_projects;
+-- +goose StatementEnd
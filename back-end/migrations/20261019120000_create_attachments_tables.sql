@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS blobs (
+    hash char(64) PRIMARY KEY,
+    size bigint NOT NULL,
+    created timestamp DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS attachments (
+    id serial PRIMARY KEY,
+    note_id integer NOT NULL,
+    user_id text NOT NULL,
+    blob_hash char(64) NOT NULL,
+    file_name varchar(255) NOT NULL,
+    mime_type varchar(255) NOT NULL,
+    size bigint NOT NULL,
+    created timestamp DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (note_id) REFERENCES notes(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (blob_hash) REFERENCES blobs(hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_note_id ON attachments(note_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_user_id ON attachments(user_id);
+CREATE INDEX IF NOT EXISTS idx_attachments_blob_hash ON attachments(blob_hash);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS attachments;
+DROP TABLE IF EXISTS blobs;
+-- +goose StatementEnd
@@ -0,0 +1,30 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS is_template boolean NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS daily_note_settings (
+    user_id text PRIMARY KEY,
+    folder_id integer,
+    template_id integer,
+    timezone text NOT NULL DEFAULT 'UTC',
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (folder_id) REFERENCES folders(id) ON DELETE SET NULL,
+    FOREIGN KEY (template_id) REFERENCES notes(id) ON DELETE SET NULL
+);
+
+CREATE TABLE IF NOT EXISTS daily_notes (
+    user_id text NOT NULL,
+    day date NOT NULL,
+    note_id integer NOT NULL,
+    PRIMARY KEY (user_id, day),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (note_id) REFERENCES notes(id) ON DELETE CASCADE
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS daily_notes;
+DROP TABLE IF EXISTS daily_note_settings;
+ALTER TABLE notes DROP COLUMN IF EXISTS is_template;
+-- +goose StatementEnd
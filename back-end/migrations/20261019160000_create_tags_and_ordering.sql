@@ -0,0 +1,50 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS tags (
+    id serial PRIMARY KEY,
+    user_id text NOT NULL,
+    name text NOT NULL,
+    created timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_user_name ON tags (user_id, lower(name));
+
+CREATE TABLE IF NOT EXISTS note_tags (
+    note_id integer NOT NULL,
+    tag_id integer NOT NULL,
+    PRIMARY KEY (note_id, tag_id),
+    FOREIGN KEY (note_id) REFERENCES notes(id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_tags_tag_id ON note_tags(tag_id);
+
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS pinned boolean NOT NULL DEFAULT false;
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS position integer NOT NULL DEFAULT 0;
+ALTER TABLE folders ADD COLUMN IF NOT EXISTS position integer NOT NULL DEFAULT 0;
+
+-- Keep the order users saw before: newest notes first, folders by name.
+UPDATE notes n SET position = ordered.position
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY user_id, folder_id ORDER BY updated DESC, id) - 1 AS position
+    FROM notes
+) ordered
+WHERE n.id = ordered.id;
+
+UPDATE folders f SET position = ordered.position
+FROM (
+    SELECT id, row_number() OVER (PARTITION BY user_id, parent_id ORDER BY name, id) - 1 AS position
+    FROM folders
+) ordered
+WHERE f.id = ordered.id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE folders DROP COLUMN IF EXISTS position;
+ALTER TABLE notes DROP COLUMN IF EXISTS position;
+ALTER TABLE notes DROP COLUMN IF EXISTS pinned;
+DROP TABLE IF EXISTS note_tags;
+DROP TABLE IF EXISTS tags;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS note_tasks (
+    id serial PRIMARY KEY,
+    note_id integer NOT NULL,
+    path text NOT NULL,
+    position integer NOT NULL,
+    text text NOT NULL,
+    checked boolean NOT NULL DEFAULT false,
+    UNIQUE (note_id, path),
+    FOREIGN KEY (note_id) REFERENCES notes(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_note_tasks_checked ON note_tasks(checked);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS note_tasks;
+-- +goose StatementEnd
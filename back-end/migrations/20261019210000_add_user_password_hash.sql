@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Only set for accounts of the local auth provider.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_hash text;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS password_hash;
+-- +goose StatementEnd
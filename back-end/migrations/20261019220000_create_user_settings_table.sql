@@ -0,0 +1,41 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS user_settings (
+    user_id text PRIMARY KEY,
+    timezone text NOT NULL DEFAULT 'UTC',
+    week_start text NOT NULL DEFAULT 'monday',
+    working_hours jsonb NOT NULL DEFAULT '[]',
+    default_project_id integer,
+    rounding_mode text NOT NULL DEFAULT 'none',
+    rounding_minutes integer NOT NULL DEFAULT 0,
+    date_format text NOT NULL DEFAULT 'YYYY-MM-DD',
+    currency text NOT NULL DEFAULT 'USD',
+    updated timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (default_project_id) REFERENCES projects(id) ON DELETE SET NULL
+);
+
+-- The daily note timezone becomes the user's timezone.
+INSERT INTO user_settings (user_id, timezone, working_hours)
+SELECT user_id, timezone, '[
+    {"Weekday": "monday", "Start": "09:00", "End": "17:00"},
+    {"Weekday": "tuesday", "Start": "09:00", "End": "17:00"},
+    {"Weekday": "wednesday", "Start": "09:00", "End": "17:00"},
+    {"Weekday": "thursday", "Start": "09:00", "End": "17:00"},
+    {"Weekday": "friday", "Start": "09:00", "End": "17:00"}
+]'::jsonb
+FROM daily_note_settings
+ON CONFLICT (user_id) DO NOTHING;
+
+ALTER TABLE daily_note_settings DROP COLUMN IF EXISTS timezone;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE daily_note_settings ADD COLUMN IF NOT EXISTS timezone text NOT NULL DEFAULT 'UTC';
+
+UPDATE daily_note_settings d SET timezone = s.timezone
+FROM user_settings s WHERE s.user_id = d.user_id;
+
+DROP TABLE IF EXISTS user_settings;
+-- +goose StatementEnd
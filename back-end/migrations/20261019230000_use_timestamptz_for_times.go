@@ -0,0 +1,178 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// timeColumns are the TIMESTAMP columns of a table, which hold the
+// wall-clock time the client sent without its offset.
+type timeColumns struct {
+	table   string
+	key     string
+	columns []string
+}
+
+var wallClockColumns = []timeColumns{
+	{table: "times", key: "id", columns: []string{"start_date", "end_date"}},
+	{table: "timeBoxes", key: "id", columns: []string{"start_date", "end_date"}},
+	{table: "running_timers", key: "user_id", columns: []string{"started_at"}},
+}
+
+// useTimestamptzForTimes converts the time columns to TIMESTAMPTZ. The old
+// values are read in the timezone of the user's settings; users without
+// settings get UTC.
+func useTimestamptzForTimes(ctx context.Context, tx *sql.Tx) error {
+	for _, table := range wallClockColumns {
+		if err := alterTimeColumns(ctx, tx, table, "timestamptz"); err != nil {
+			return err
+		}
+	}
+	// The columns now read the old wall-clock times as UTC, which only
+	// needs fixing for users in other timezones.
+	return convertUserTimes(ctx, tx, func(stored time.Time, location *time.Location) time.Time {
+		return localInstant(stored.UTC(), location)
+	})
+}
+
+// useTimestampForTimes turns the columns back into wall-clock times in the
+// timezone of the user's settings.
+func useTimestampForTimes(ctx context.Context, tx *sql.Tx) error {
+	err := convertUserTimes(ctx, tx, func(stored time.Time, location *time.Location) time.Time {
+		return wallClock(stored, location)
+	})
+	if err != nil {
+		return err
+	}
+	for _, table := range wallClockColumns {
+		if err := alterTimeColumns(ctx, tx, table, "timestamp"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// alterTimeColumns changes the type of the columns of table. Values are
+// converted as UTC, whatever the session timezone is.
+func alterTimeColumns(ctx context.Context, tx *sql.Tx, table timeColumns, columnType string) error {
+	changes := make([]string, len(table.columns))
+	for i, column := range table.columns {
+		changes[i] = fmt.Sprintf("ALTER COLUMN %s TYPE %s USING %s AT TIME ZONE 'UTC'", column, columnType, column)
+	}
+	if _, err := tx.ExecContext(ctx, `ALTER TABLE `+table.table+` `+strings.Join(changes, ", ")); err != nil {
+		return fmt.Errorf("failed to change the time columns of %s: %w", table.table, err)
+	}
+	return nil
+}
+
+// convertUserTimes rewrites the time columns of every user whose settings
+// are in a timezone other than UTC.
+func convertUserTimes(ctx context.Context, tx *sql.Tx, convert func(stored time.Time, location *time.Location) time.Time) error {
+	rows, err := tx.QueryContext(ctx, `SELECT user_id, timezone FROM user_settings WHERE timezone <> 'UTC'`)
+	if err != nil {
+		return fmt.Errorf("failed to get user timezones: %w", err)
+	}
+	zones := make(map[string]string)
+	for rows.Next() {
+		var userID, zone string
+		if err := rows.Scan(&userID, &zone); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan user timezone: %w", err)
+		}
+		zones[userID] = zone
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get user timezones: %w", err)
+	}
+
+	for userID, zone := range zones {
+		location, err := time.LoadLocation(zone)
+		if err != nil {
+			return fmt.Errorf("user %s has unknown timezone %q: %w", userID, zone, err)
+		}
+		for _, table := range wallClockColumns {
+			if err := convertTimes(ctx, tx, table, userID, func(stored time.Time) time.Time {
+				return convert(stored, location)
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func convertTimes(ctx context.Context, tx *sql.Tx, table timeColumns, userID string, convert func(time.Time) time.Time) error {
+	rows, err := tx.QueryContext(ctx,
+		`SELECT `+table.key+`, `+strings.Join(table.columns, ", ")+` FROM `+table.table+` WHERE user_id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get %s: %w", table.table, err)
+	}
+	var updates [][]any
+	for rows.Next() {
+		var key any
+		times := make([]time.Time, len(table.columns))
+		dest := []any{&key}
+		for i := range times {
+			dest = append(dest, &times[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan %s: %w", table.table, err)
+		}
+		args := make([]any, 0, len(times)+1)
+		for _, t := range times {
+			args = append(args, convert(t))
+		}
+		updates = append(updates, append(args, key))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get %s: %w", table.table, err)
+	}
+
+	assignments := make([]string, len(table.columns))
+	for i, column := range table.columns {
+		assignments[i] = fmt.Sprintf("%s = $%d", column, i+1)
+	}
+	query := fmt.Sprintf(`UPDATE %s SET %s WHERE %s = $%d`,
+		table.table, strings.Join(assignments, ", "), table.key, len(table.columns)+1)
+	for _, args := range updates {
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to update %s: %w", table.table, err)
+		}
+	}
+	return nil
+}
+
+// localInstant returns the instant at which the clock in location showed the
+// wall-clock time of wall, ignoring wall's own zone. It follows Postgres'
+// AT TIME ZONE: a time skipped when the clocks go forward is read with the
+// offset from before the change, so it lands as far past the change as it
+// was into the gap, and a time shown twice when the clocks go back is read
+// with the offset from after the change, the later of the two.
+func localInstant(wall time.Time, location *time.Location) time.Time {
+	year, month, day := wall.Date()
+	hour, minute, second := wall.Clock()
+	asUTC := time.Date(year, month, day, hour, minute, second, wall.Nanosecond(), time.UTC)
+
+	_, before := asUTC.Add(-24 * time.Hour).In(location).Zone()
+	_, after := asUTC.Add(24 * time.Hour).In(location).Zone()
+	instant := asUTC.Add(-time.Duration(after) * time.Second)
+	if _, offset := instant.In(location).Zone(); offset == after {
+		return instant
+	}
+	return asUTC.Add(-time.Duration(before) * time.Second)
+}
+
+// wallClock returns the time the clock in location showed at instant, as a
+// UTC time with the same wall-clock fields.
+func wallClock(instant time.Time, location *time.Location) time.Time {
+	local := instant.In(location)
+	year, month, day := local.Date()
+	hour, minute, second := local.Clock()
+	return time.Date(year, month, day, hour, minute, second, local.Nanosecond(), time.UTC)
+}
@@ -0,0 +1,104 @@
+package migrations
+
+import (
+	"testing"
+	"time"
+)
+
+func newYork(t *testing.T) *time.Location {
+	t.Helper()
+	location, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("timezone data not available: %v", err)
+	}
+	return location
+}
+
+// wall is a stored TIMESTAMP value, which has no zone.
+func wall(month time.Month, day, hour, minute int) time.Time {
+	return time.Date(2026, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+func instant(value string) time.Time {
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+func TestLocalInstantAcrossDST(t *testing.T) {
+	location := newYork(t)
+
+	tests := []struct {
+		name string
+		wall time.Time
+		want string
+	}{
+		{"before spring forward", wall(time.March, 8, 1, 59), "2026-03-08T01:59:00-05:00"},
+		// 02:00 to 03:00 did not happen; Postgres reads it as EST.
+		{"in the spring gap", wall(time.March, 8, 2, 30), "2026-03-08T03:30:00-04:00"},
+		{"after spring forward", wall(time.March, 8, 3, 0), "2026-03-08T03:00:00-04:00"},
+		{"day before spring forward", wall(time.March, 7, 20, 0), "2026-03-07T20:00:00-05:00"},
+		{"day after spring forward", wall(time.March, 9, 9, 0), "2026-03-09T09:00:00-04:00"},
+		{"before fall back", wall(time.November, 1, 0, 59), "2026-11-01T00:59:00-04:00"},
+		// 01:00 to 02:00 happened twice; Postgres reads it as EST.
+		{"in the repeated hour", wall(time.November, 1, 1, 30), "2026-11-01T01:30:00-05:00"},
+		{"after fall back", wall(time.November, 1, 2, 0), "2026-11-01T02:00:00-05:00"},
+		{"day before fall back", wall(time.October, 31, 22, 0), "2026-10-31T22:00:00-04:00"},
+		{"day after fall back", wall(time.November, 2, 9, 0), "2026-11-02T09:00:00-05:00"},
+	}
+	for _, test := range tests {
+		got := localInstant(test.wall, location)
+		if want := instant(test.want); !got.Equal(want) {
+			t.Errorf("%s: localInstant(%s) = %s, want %s",
+				test.name, test.wall.Format("2006-01-02 15:04"), got.In(location).Format(time.RFC3339), test.want)
+		}
+	}
+}
+
+// An entry spanning a DST change keeps its real length once converted, where
+// the wall-clock columns had it an hour off.
+func TestLocalInstantDurationAcrossDST(t *testing.T) {
+	location := newYork(t)
+
+	spring := localInstant(wall(time.March, 8, 3, 30), location).Sub(localInstant(wall(time.March, 8, 1, 30), location))
+	if spring != time.Hour {
+		t.Errorf("01:30 to 03:30 on 2026-03-08 lasted %s, want 1h", spring)
+	}
+	fall := localInstant(wall(time.November, 1, 2, 30), location).Sub(localInstant(wall(time.November, 1, 0, 30), location))
+	if fall != 3*time.Hour {
+		t.Errorf("00:30 to 02:30 on 2026-11-01 lasted %s, want 3h", fall)
+	}
+}
+
+// Rolling back gives the wall-clock times the user saw, so up then down
+// keeps every time that existed.
+func TestWallClockRoundTrip(t *testing.T) {
+	location := newYork(t)
+
+	for _, stored := range []time.Time{
+		wall(time.March, 8, 1, 59),
+		wall(time.March, 8, 3, 0),
+		wall(time.November, 1, 0, 59),
+		wall(time.November, 1, 1, 30),
+		wall(time.November, 1, 2, 0),
+		wall(time.July, 1, 12, 0),
+	} {
+		if got := wallClock(localInstant(stored, location), location); !got.Equal(stored) {
+			t.Errorf("round trip of %s gave %s", stored.Format("2006-01-02 15:04"), got.Format("2006-01-02 15:04"))
+		}
+	}
+
+	// The time in the gap comes back as the time it was read as.
+	if got := wallClock(localInstant(wall(time.March, 8, 2, 30), location), location); !got.Equal(wall(time.March, 8, 3, 30)) {
+		t.Errorf("round trip of 2026-03-08 02:30 gave %s, want 03:30", got.Format("2006-01-02 15:04"))
+	}
+}
+
+func TestLocalInstantUTC(t *testing.T) {
+	stored := wall(time.March, 8, 2, 30)
+	if got := localInstant(stored, time.UTC); !got.Equal(stored) {
+		t.Errorf("localInstant in UTC = %s, want %s", got, stored)
+	}
+}
@@ -0,0 +1,60 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/RiadMefti/TimeTracker/back-end/tiptap"
+)
+
+const convertBatchSize = 500
+
+// convertHTMLNoteContent rewrites notes still holding the HTML the editor
+// saved before notes were stored as TipTap JSON. Versions and update times
+// are left alone since the note reads the same.
+func convertHTMLNoteContent(ctx context.Context, tx *sql.Tx) error {
+	type legacyNote struct {
+		id      int
+		content string
+	}
+
+	lastID := 0
+	for {
+		rows, err := tx.QueryContext(ctx, `
+			SELECT id, content FROM notes
+			WHERE id > $1 AND btrim(content) <> '' AND ltrim(content) NOT LIKE '{%'
+			ORDER BY id LIMIT $2
+		`, lastID, convertBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to get notes: %w", err)
+		}
+		var batch []legacyNote
+		for rows.Next() {
+			var note legacyNote
+			if err := rows.Scan(&note.id, &note.content); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan note: %w", err)
+			}
+			batch = append(batch, note)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to get notes: %w", err)
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		for _, note := range batch {
+			doc, err := tiptap.FromHTML(note.content)
+			if err != nil {
+				return fmt.Errorf("failed to convert note %d: %w", note.id, err)
+			}
+			if _, err := tx.ExecContext(ctx, `UPDATE notes SET content = $1 WHERE id = $2`, doc.String(), note.id); err != nil {
+				return fmt.Errorf("failed to update note %d: %w", note.id, err)
+			}
+			lastID = note.id
+		}
+	}
+}
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Links may point at notes that do not exist yet or were purged; they
+-- resolve once a note with that id belongs to the linking user.
+ALTER TABLE note_links DROP CONSTRAINT IF EXISTS note_links_target_note_id_fkey;
+
+-- The note version whose links and tasks are indexed. Notes where it differs
+-- from version are indexed again on startup, which also backfills notes
+-- written before the indexes existed.
+ALTER TABLE notes ADD COLUMN IF NOT EXISTS indexed_version integer;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE notes DROP COLUMN IF EXISTS indexed_version;
+
+DELETE FROM note_links l WHERE NOT EXISTS (SELECT 1 FROM notes n WHERE n.id = l.target_note_id);
+ALTER TABLE note_links ADD CONSTRAINT note_links_target_note_id_fkey
+    FOREIGN KEY (target_note_id) REFERENCES notes(id) ON DELETE CASCADE;
+-- +goose StatementEnd
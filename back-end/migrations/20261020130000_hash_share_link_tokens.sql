@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Share tokens are stored as their SHA-256 hash, like personal access
+-- tokens, with the first characters kept to tell links apart. Existing links
+-- keep working since their token hashes to the stored value.
+ALTER TABLE share_links ADD COLUMN IF NOT EXISTS token_hash text;
+ALTER TABLE share_links ADD COLUMN IF NOT EXISTS prefix text;
+UPDATE share_links SET token_hash = encode(sha256(convert_to(token, 'UTF8')), 'hex'), prefix = left(token, 8);
+ALTER TABLE share_links ALTER COLUMN token_hash SET NOT NULL;
+ALTER TABLE share_links ALTER COLUMN prefix SET NOT NULL;
+ALTER TABLE share_links ADD CONSTRAINT share_links_token_hash_key UNIQUE (token_hash);
+ALTER TABLE share_links DROP COLUMN IF EXISTS token;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- The tokens cannot be recovered from their hashes, so links are revoked.
+DELETE FROM share_links;
+ALTER TABLE share_links ADD COLUMN IF NOT EXISTS token varchar(64) NOT NULL UNIQUE;
+ALTER TABLE share_links DROP COLUMN IF EXISTS prefix;
+ALTER TABLE share_links DROP COLUMN IF EXISTS token_hash;
+-- +goose StatementEnd
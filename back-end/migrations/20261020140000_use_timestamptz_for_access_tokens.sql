@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- The old columns hold UTC wall-clock times: expiries were normalized to UTC
+-- and the other times written by a server running in UTC.
+ALTER TABLE personal_access_tokens
+    ALTER COLUMN expires_at TYPE timestamptz USING expires_at AT TIME ZONE 'UTC',
+    ALTER COLUMN last_used_at TYPE timestamptz USING last_used_at AT TIME ZONE 'UTC',
+    ALTER COLUMN revoked_at TYPE timestamptz USING revoked_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created TYPE timestamptz USING created AT TIME ZONE 'UTC';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE personal_access_tokens
+    ALTER COLUMN expires_at TYPE timestamp USING expires_at AT TIME ZONE 'UTC',
+    ALTER COLUMN last_used_at TYPE timestamp USING last_used_at AT TIME ZONE 'UTC',
+    ALTER COLUMN revoked_at TYPE timestamp USING revoked_at AT TIME ZONE 'UTC',
+    ALTER COLUMN created TYPE timestamp USING created AT TIME ZONE 'UTC';
+-- +goose StatementEnd
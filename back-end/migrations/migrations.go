@@ -0,0 +1,21 @@
+// Package migrations embeds the goose SQL migrations, and holds the few
+// written in Go, so that the server binary can apply them itself.
+package migrations
+
+import (
+	"embed"
+
+	"github.com/pressly/goose/v3"
+)
+
+//go:embed *.sql
+var FS embed.FS
+
+// Go holds the migrations written in Go, each in the file named after its
+// version. They are applied in version order together with the SQL files.
+var Go = []*goose.Migration{
+	goose.NewGoMigration(20261019230000,
+		&goose.GoFunc{RunTx: useTimestamptzForTimes}, &goose.GoFunc{RunTx: useTimestampForTimes}),
+	// Down is a no-op: the editor reads the converted TipTap JSON as well.
+	goose.NewGoMigration(20261020110000, &goose.GoFunc{RunTx: convertHTMLNoteContent}, nil),
+}
@@ -2,12 +2,16 @@ package models
 
 import "time"
 
+// TimeEntry dates are RFC 3339 timestamps with an offset. They are stored as
+// instants and returned in UTC.
 type TimeEntry struct {
-	ID          int       `json:"ID"`
-	Description string    `json:"Description"`
-	ProjectID   *int      `json:"ProjectID"`
-	StartDate   time.Time `json:"StartDate"`
-	EndDate     time.Time `json:"EndDate"`
+	ID          int        `json:"ID"`
+	Description string     `json:"Description"`
+	ProjectID   *int       `json:"ProjectID"`
+	StartDate   time.Time  `json:"StartDate"`
+	EndDate     time.Time  `json:"EndDate"`
+	Version     int        `json:"Version"`
+	Notes       []NoteStub `json:"Notes,omitempty"` // only filled when requested with include=notes
 }
 
 type TimeEntryCreate struct {
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// Personal access token scopes. "read" and "write" cover every resource;
+// the others only the time tracking or the notes endpoints. Write access
+// includes read access.
+const (
+	ScopeRead       = "read"
+	ScopeWrite      = "write"
+	ScopeTimeRead   = "time:read"
+	ScopeTimeWrite  = "time:write"
+	ScopeNotesRead  = "notes:read"
+	ScopeNotesWrite = "notes:write"
+)
+
+// PersonalAccessToken lets scripts call the API as the user without a
+// Firebase session. Only a hash of the token is stored; Token is set once,
+// in the response that creates it.
+type PersonalAccessToken struct {
+	ID         int        `json:"ID"`
+	Name       string     `json:"Name"`
+	Token      string     `json:"Token,omitempty"`
+	Prefix     string     `json:"Prefix"` // start of the token, to tell tokens apart
+	Scopes     []string   `json:"Scopes"`
+	UserID     string     `json:"-"`
+	ExpiresAt  *time.Time `json:"ExpiresAt,omitempty"` // nil for tokens that never expire
+	LastUsedAt *time.Time `json:"LastUsedAt,omitempty"`
+	Created    time.Time  `json:"Created"`
+}
+
+type PersonalAccessTokenCreate struct {
+	Name      string     `json:"Name"`
+	Scopes    []string   `json:"Scopes"`
+	ExpiresAt *time.Time `json:"ExpiresAt,omitempty"`
+}
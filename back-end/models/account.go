@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// Account audit log actions.
+const (
+	AuditDeletionRequested = "deletion_requested"
+	AuditDeletionCancelled = "deletion_cancelled"
+	AuditIdentityDeleted   = "identity_deleted"
+	AuditPurgeFailed       = "purge_failed"
+	AuditAccountPurged     = "account_purged"
+)
+
+// AccountDeletion is a scheduled account deletion. Logging in through
+// POST /auth/login before ScheduledAt cancels it.
+type AccountDeletion struct {
+	ScheduledAt time.Time `json:"ScheduledAt"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type Attachment struct {
+	ID         int       `json:"ID"`
+	NoteID     int       `json:"NoteID"`
+	UserID     string    `json:"UserID"`
+	FileName   string    `json:"FileName"`
+	MimeType   string    `json:"MimeType"`
+	Size       int64     `json:"Size"`
+	Hash       string    `json:"Hash"` // SHA-256 of the content, shared by identical uploads
+	URL        string    `json:"URL"`  // signed download URL, valid until URLExpires
+	URLExpires time.Time `json:"URLExpires"`
+	Created    time.Time `json:"Created"`
+}
+
+// AttachmentUsage is the storage a user's attachments take up. Identical
+// files are only counted once.
+type AttachmentUsage struct {
+	Used  int64 `json:"Used"`
+	Quota int64 `json:"Quota"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import "errors"
+
+// ErrVersionConflict is returned by updates guarded by an expected version
+// when the row has been modified since the caller last read it.
+var ErrVersionConflict = errors.New("version conflict")
+
+// ErrNoteNotFound is returned for notes that do not exist, belong to another
+// user or are in the trash.
+var ErrNoteNotFound = errors.New("note not found")
+
+// Attachment errors, each mapped to its own HTTP status.
+var (
+	ErrAttachmentNotFound   = errors.New("attachment not found")
+	ErrAttachmentTooLarge   = errors.New("attachment is too large")
+	ErrEmptyAttachment      = errors.New("file is empty")
+	ErrUnsupportedMediaType = errors.New("unsupported file type")
+	ErrQuotaExceeded        = errors.New("storage quota exceeded")
+)
+
+// ErrInvalidDownloadLink is returned for download URLs with a bad signature
+// or past their expiry.
+var ErrInvalidDownloadLink = errors.New("invalid or expired download link")
+
+// Share link errors. An expired link is reported as not found.
+var (
+	ErrShareNotFound         = errors.New("share link not found")
+	ErrSharePasswordRequired = errors.New("share link password required")
+)
+
+// ErrUnknownTaskStatus is returned when listing tasks with a status other
+// than open, done or all.
+var ErrUnknownTaskStatus = errors.New("unknown task status")
+
+// Timer errors, for starting a timer twice or stopping one that is not running.
+var (
+	ErrTimerRunning    = errors.New("a timer is already running")
+	ErrTimerNotRunning = errors.New("no timer is running")
+)
+
+// Authentication errors. ErrAuthUnavailable means the token could not be
+// checked at all, for example because the signing keys could not be fetched.
+var (
+	ErrInvalidToken    = errors.New("invalid token")
+	ErrAuthUnavailable = errors.New("authentication backend unavailable")
+)
+
+// ErrEmailTaken is returned when signing up with an email that already has
+// an account.
+var ErrEmailTaken = errors.New("email is already registered")
+
+// ErrInvalidCredentials is returned by a local login with an unknown email
+// or a wrong password, without telling which.
+var ErrInvalidCredentials = errors.New("invalid email or password")
+
+// ErrExportNotFound is returned for data exports that do not exist, belong
+// to another user or, for downloads, are not done.
+var ErrExportNotFound = errors.New("export not found")
+
+// ErrDeletionNotDue is returned when purging an account whose deletion was
+// cancelled, is not due yet, or is being carried out by another server.
+var ErrDeletionNotDue = errors.New("account deletion is not due")
@@ -0,0 +1,100 @@
+package models
+
+import "time"
+
+// Data export statuses. A running export whose lease ran out is picked up
+// again by the next worker.
+const (
+	ExportPending = "pending"
+	ExportRunning = "running"
+	ExportDone    = "done"
+	ExportFailed  = "failed"
+)
+
+// ExportFormatVersion is the version of the archive layout written by data
+// exports. It goes up whenever a file or field is renamed or removed.
+const ExportFormatVersion = 1
+
+// DataExport is a job building a zip of all the user's data. URL is a signed
+// download link, set once the export is done and until it expires.
+type DataExport struct {
+	ID         int        `json:"ID"`
+	Status     string     `json:"Status"`
+	Error      string     `json:"Error,omitempty"`
+	Size       int64      `json:"Size,omitempty"`
+	URL        string     `json:"URL,omitempty"`
+	URLExpires *time.Time `json:"URLExpires,omitempty"`
+	Created    time.Time  `json:"Created"`
+	FinishedAt *time.Time `json:"FinishedAt,omitempty"`
+	ExpiresAt  *time.Time `json:"ExpiresAt,omitempty"` // when the archive is deleted
+	BlobKey    string     `json:"-"`
+	UserID     string     `json:"-"`
+}
+
+// ExportManifest is manifest.json at the root of an export archive.
+type ExportManifest struct {
+	Format   string    `json:"Format"` // always "timetracker-export"
+	Version  int       `json:"Version"`
+	Exported time.Time `json:"Exported"`
+	UserID   string    `json:"UserID"`
+	Counts   struct {
+		Projects  int `json:"Projects"`
+		Times     int `json:"Times"`
+		TimeBoxes int `json:"TimeBoxes"`
+		Folders   int `json:"Folders"`
+		Notes     int `json:"Notes"`
+	} `json:"Counts"`
+}
+
+// ExportUser is user.json. Password hashes are not exported.
+type ExportUser struct {
+	ID    string `json:"ID"`
+	Email string `json:"Email"`
+}
+
+type ExportProject struct {
+	ID          int        `json:"ID"`
+	Name        string     `json:"Name"`
+	Description string     `json:"Description"`
+	Color       string     `json:"Color"`
+	Version     int        `json:"Version"`
+	DeletedAt   *time.Time `json:"DeletedAt,omitempty"`
+}
+
+// ExportTimeEntry is a row of times.json or timeBoxes.json.
+type ExportTimeEntry struct {
+	ID          int        `json:"ID"`
+	Description string     `json:"Description"`
+	ProjectID   *int       `json:"ProjectID"`
+	StartDate   time.Time  `json:"StartDate"`
+	EndDate     time.Time  `json:"EndDate"`
+	Version     int        `json:"Version"`
+	DeletedAt   *time.Time `json:"DeletedAt,omitempty"`
+}
+
+type ExportFolder struct {
+	ID        int        `json:"ID"`
+	Name      string     `json:"Name"`
+	ParentID  *int       `json:"ParentID"`
+	Position  int        `json:"Position"`
+	Version   int        `json:"Version"`
+	Created   time.Time  `json:"Created"`
+	Updated   time.Time  `json:"Updated"`
+	DeletedAt *time.Time `json:"DeletedAt,omitempty"`
+}
+
+type ExportNote struct {
+	ID         int        `json:"ID"`
+	Title      string     `json:"Title"`
+	Content    string     `json:"Content"` // TipTap JSON content
+	FolderID   *int       `json:"FolderID"`
+	IsTemplate bool       `json:"IsTemplate"`
+	Pinned     bool       `json:"Pinned"`
+	Position   int        `json:"Position"`
+	Tags       []string   `json:"Tags"`
+	Version    int        `json:"Version"`
+	Created    time.Time  `json:"Created"`
+	Updated    time.Time  `json:"Updated"`
+	DeletedAt  *time.Time `json:"DeletedAt,omitempty"`
+	Path       string     `json:"Path,omitempty"` // Markdown file in the archive, for live notes
+}
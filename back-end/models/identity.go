@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Identity is the authenticated user of a request, as stored in the "user"
+// local. Email and Name come from the token and may be empty.
+type Identity struct {
+	UID   string `json:"UID"`
+	Email string `json:"Email"`
+	Name  string `json:"Name"`
+}
+
+// Credentials sign up or log in an account of the local auth provider.
+type Credentials struct {
+	Email    string `json:"Email"`
+	Password string `json:"Password"`
+}
+
+// AuthToken is a bearer token issued by the local auth provider.
+type AuthToken struct {
+	Token     string    `json:"Token"`
+	ExpiresAt time.Time `json:"ExpiresAt"`
+	User      User      `json:"User"`
+}
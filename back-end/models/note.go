@@ -7,6 +7,8 @@ type Folder struct {
 	Name     string    `json:"Name"`
 	ParentID *int      `json:"ParentID,omitempty"` // nil for root folders
 	UserID   string    `json:"UserID"`
+	Position int       `json:"Position"` // order among the parent's folders
+	Version  int       `json:"Version"`
 	Created  time.Time `json:"Created"`
 	Updated  time.Time `json:"Updated"`
 }
@@ -22,23 +24,140 @@ type FolderUpdate struct {
 }
 
 type Note struct {
+	ID         int       `json:"ID"`
+	Title      string    `json:"Title"`
+	Content    string    `json:"Content"`            // TipTap JSON content
+	FolderID   *int      `json:"FolderID,omitempty"` // nil for root notes
+	UserID     string    `json:"UserID"`
+	IsTemplate bool      `json:"IsTemplate"`
+	Pinned     bool      `json:"Pinned"`
+	Position   int       `json:"Position"` // order among the folder's notes
+	Tags       []string  `json:"Tags"`
+	Version    int       `json:"Version"`
+	Created    time.Time `json:"Created"`
+	Updated    time.Time `json:"Updated"`
+}
+
+type NoteCreate struct {
+	Title      string   `json:"Title"`
+	Content    string   `json:"Content"`
+	FolderID   *int     `json:"FolderID,omitempty"`
+	IsTemplate bool     `json:"IsTemplate"`
+	Pinned     bool     `json:"Pinned"`
+	Tags       []string `json:"Tags,omitempty"`
+}
+
+type NoteUpdate struct {
+	Title      string    `json:"Title"`
+	Content    string    `json:"Content"`
+	FolderID   *int      `json:"FolderID,omitempty"`
+	IsTemplate *bool     `json:"IsTemplate,omitempty"` // nil keeps the current flag
+	Pinned     *bool     `json:"Pinned,omitempty"`     // nil keeps the current flag
+	Tags       *[]string `json:"Tags,omitempty"`       // nil keeps the current tags
+}
+
+// NoteFilter narrows GET /notes. A note must carry every listed tag.
+type NoteFilter struct {
+	Tags   []string
+	Pinned *bool
+}
+
+// NoteOrder sets the order of the notes in a folder. Notes of the folder
+// that are not listed keep their relative order after the listed ones.
+type NoteOrder struct {
+	FolderID *int  `json:"FolderID,omitempty"` // nil for root notes
+	NoteIDs  []int `json:"NoteIDs"`
+}
+
+// FolderOrder sets the order of the subfolders of a parent, like NoteOrder.
+type FolderOrder struct {
+	ParentID  *int  `json:"ParentID,omitempty"` // nil for root folders
+	FolderIDs []int `json:"FolderIDs"`
+}
+
+// NoteArchiveMeta is the JSON sidecar stored next to each note's Markdown
+// file in a folder archive.
+type NoteArchiveMeta struct {
+	ID      int       `json:"ID"`
+	Title   string    `json:"Title"`
+	Content string    `json:"Content"` // original TipTap JSON content
+	Created time.Time `json:"Created"`
+	Updated time.Time `json:"Updated"`
+}
+
+// ArchiveFolder and ArchiveNote are the folders and notes created by an
+// archive import. Parent and Folder are indexes into the import's folders,
+// or -1 for the folder the archive is imported into.
+type ArchiveFolder struct {
+	Name   string
+	Parent int
+}
+
+type ArchiveNote struct {
+	Title   string
+	Content string
+	Folder  int
+}
+
+type ArchiveImportResult struct {
+	Folders     []*Folder `json:"Folders"` // top-level folders created by the import
+	FolderCount int       `json:"FolderCount"`
+	NoteCount   int       `json:"NoteCount"`
+}
+
+// NoteLink is an outgoing link from one note to another, with the text
+// surrounding it.
+type NoteLink struct {
+	TargetID int
+	Context  string
+}
+
+// Backlink is a note that links to the requested note.
+type Backlink struct {
+	NoteID  int       `json:"NoteID"`
+	Title   string    `json:"Title"`
+	Context string    `json:"Context"`
+	Updated time.Time `json:"Updated"`
+}
+
+type NoteDeleteResult struct {
+	DanglingLinks []*Backlink `json:"DanglingLinks"` // notes that still link to the deleted note
+}
+
+// NoteStub is the short form of a note embedded in other resources.
+type NoteStub struct {
 	ID       int       `json:"ID"`
 	Title    string    `json:"Title"`
-	Content  string    `json:"Content"` // TipTap JSON content
-	FolderID *int      `json:"FolderID,omitempty"` // nil for root notes
-	UserID   string    `json:"UserID"`
-	Created  time.Time `json:"Created"`
+	FolderID *int      `json:"FolderID,omitempty"`
 	Updated  time.Time `json:"Updated"`
 }
 
-type NoteCreate struct {
-	Title    string `json:"Title"`
-	Content  string `json:"Content"`
-	FolderID *int   `json:"FolderID,omitempty"`
+// NoteRelations lists the projects, time entries and time boxes a note is
+// attached to.
+type NoteRelations struct {
+	ProjectIDs   []int `json:"ProjectIDs"`
+	TimeEntryIDs []int `json:"TimeEntryIDs"`
+	TimeBoxIDs   []int `json:"TimeBoxIDs"`
 }
 
-type NoteUpdate struct {
-	Title    string `json:"Title"`
-	Content  string `json:"Content"`
-	FolderID *int   `json:"FolderID,omitempty"`
+// NoteFromTemplate creates a note from a template. An empty title falls back
+// to the template's title, with its placeholders filled in too.
+type NoteFromTemplate struct {
+	Title     string `json:"Title,omitempty"`
+	FolderID  *int   `json:"FolderID,omitempty"`
+	ProjectID *int   `json:"ProjectID,omitempty"` // fills {{project}}
+	Timezone  string `json:"Timezone,omitempty"`  // IANA zone for {{date}} and {{weekday}}, defaults to the user's timezone
+}
+
+// DailyNoteSettings controls where POST /notes/daily puts the daily note. The
+// day it is for follows the timezone of the user settings.
+type DailyNoteSettings struct {
+	FolderID   *int `json:"FolderID,omitempty"`   // nil for root notes
+	TemplateID *int `json:"TemplateID,omitempty"` // nil for an empty note
+}
+
+type DailyNote struct {
+	Date    string `json:"Date"` // YYYY-MM-DD in the user's timezone
+	Created bool   `json:"Created"`
+	Note    *Note  `json:"Note"`
 }
@@ -5,6 +5,7 @@ type Project struct {
 	Name        string `json:"Name"`
 	Description string `json:"Description"`
 	Color       string `json:"Color"`
+	Version     int    `json:"Version"`
 }
 
 type ProjectCreate struct {
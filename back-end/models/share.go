@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// ShareLink gives read-only access to a note or a folder subtree to anyone
+// holding the token. Only a hash of the token is stored; Token and URL are
+// set once, in the response that creates the link.
+type ShareLink struct {
+	ID           int        `json:"ID"`
+	Token        string     `json:"Token,omitempty"`
+	URL          string     `json:"URL,omitempty"`
+	Prefix       string     `json:"Prefix"` // start of the token, to tell links apart
+	NoteID       *int       `json:"NoteID,omitempty"`
+	FolderID     *int       `json:"FolderID,omitempty"`
+	UserID       string     `json:"UserID"`
+	HasPassword  bool       `json:"HasPassword"`
+	PasswordHash string     `json:"-"`
+	ExpiresAt    *time.Time `json:"ExpiresAt,omitempty"` // nil for links that never expire
+	Created      time.Time  `json:"Created"`
+}
+
+// ShareLinkCreate shares exactly one of NoteID and FolderID.
+type ShareLinkCreate struct {
+	NoteID    *int       `json:"NoteID,omitempty"`
+	FolderID  *int       `json:"FolderID,omitempty"`
+	Password  string     `json:"Password,omitempty"`
+	ExpiresAt *time.Time `json:"ExpiresAt,omitempty"`
+}
+
+// SharedNote is the public view of a shared note. Content is TipTap JSON.
+type SharedNote struct {
+	ID      int       `json:"ID"`
+	Title   string    `json:"Title"`
+	Content string    `json:"Content"`
+	Updated time.Time `json:"Updated"`
+}
+
+type SharedFolder struct {
+	ID      int             `json:"ID"`
+	Name    string          `json:"Name"`
+	Notes   []*SharedNote   `json:"Notes"`
+	Folders []*SharedFolder `json:"Folders"`
+}
+
+// SharedContent holds either the shared note or the shared folder tree.
+type SharedContent struct {
+	Note   *SharedNote   `json:"Note,omitempty"`
+	Folder *SharedFolder `json:"Folder,omitempty"`
+}
@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// Tag is a label from the user's tag namespace.
+type Tag struct {
+	ID        int       `json:"ID"`
+	Name      string    `json:"Name"`
+	NoteCount int       `json:"NoteCount"`
+	Created   time.Time `json:"Created"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// NoteTask is a TipTap taskItem found in one of the user's notes.
+type NoteTask struct {
+	ID        int       `json:"ID"`
+	NoteID    int       `json:"NoteID"`
+	NoteTitle string    `json:"NoteTitle"`
+	Path      string    `json:"Path"` // child indexes from the document root, dot separated
+	Text      string    `json:"Text"`
+	Checked   bool      `json:"Checked"`
+	Updated   time.Time `json:"Updated"` // when the note was last updated
+}
+
+type NoteTaskUpdate struct {
+	Checked *bool `json:"Checked,omitempty"` // nil toggles the checkbox
+}
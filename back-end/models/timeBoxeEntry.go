@@ -2,12 +2,15 @@ package models
 
 import "time"
 
+// TimeBoxEntry dates are RFC 3339 timestamps with an offset. They are stored as
+// instants and returned in UTC.
 type TimeBoxEntry struct {
 	ID          int       `json:"ID"`
 	Description string    `json:"Description"`
 	ProjectID   *int      `json:"ProjectID"`
 	StartDate   time.Time `json:"StartDate"`
 	EndDate     time.Time `json:"EndDate"`
+	Version     int       `json:"Version"`
 }
 
 type TimeBoxEntryCreate struct {
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// RunningTimer is the timer a user has started and not stopped yet. Stopping
+// it records a time entry.
+type RunningTimer struct {
+	Description string    `json:"Description"`
+	ProjectID   *int      `json:"ProjectID"`
+	StartDate   time.Time `json:"StartDate"`
+}
+
+// TimerStart starts a timer. StartDate defaults to now.
+type TimerStart struct {
+	Description string     `json:"Description"`
+	ProjectID   *int       `json:"ProjectID"`
+	StartDate   *time.Time `json:"StartDate"`
+}
+
+// TimerStop stops the running timer. EndDate defaults to now.
+type TimerStop struct {
+	EndDate *time.Time `json:"EndDate"`
+}
+
+// TimerStopResult is the time entry recorded by stopping a timer, along with
+// all the user's time entries.
+type TimerStopResult struct {
+	Entry   TimeEntry   `json:"Entry"`
+	Entries []TimeEntry `json:"Entries"`
+}
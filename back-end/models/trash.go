@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+type TrashedNote struct {
+	Note
+	DeletedAt time.Time `json:"DeletedAt"`
+}
+
+type TrashedFolder struct {
+	Folder
+	DeletedAt time.Time `json:"DeletedAt"`
+}
+
+type TrashedTimeEntry struct {
+	TimeEntry
+	DeletedAt time.Time `json:"DeletedAt"`
+}
+
+type TrashedProject struct {
+	Project
+	DeletedAt time.Time `json:"DeletedAt"`
+}
+
+// Trash lists a user's deleted items by type, most recently deleted first.
+type Trash struct {
+	Notes       []*TrashedNote      `json:"Notes"`
+	Folders     []*TrashedFolder    `json:"Folders"`
+	TimeEntries []*TrashedTimeEntry `json:"TimeEntries"`
+	Projects    []*TrashedProject   `json:"Projects"`
+}
+
+// PurgeResult counts the rows removed for good by a trash purge.
+type PurgeResult struct {
+	Notes       int64
+	Folders     int64
+	TimeEntries int64
+	Projects    int64
+}
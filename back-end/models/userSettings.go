@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Duration rounding modes. Minutes is the step the duration is rounded to
+// and is ignored for RoundingNone.
+const (
+	RoundingNone    = "none"
+	RoundingNearest = "nearest"
+	RoundingUp      = "up"
+	RoundingDown    = "down"
+)
+
+// UserSettings are the user's preferences for how days, weeks and amounts
+// are shown and counted. Weekdays are lower case English names.
+type UserSettings struct {
+	Timezone         string           `json:"Timezone"`  // IANA zone name
+	WeekStart        string           `json:"WeekStart"` // first day of the week
+	WorkingHours     []WorkingHours   `json:"WorkingHours"`
+	DefaultProjectID *int             `json:"DefaultProjectID"` // used by timers started without a project
+	Rounding         DurationRounding `json:"Rounding"`
+	DateFormat       string           `json:"DateFormat"` // YYYY-MM-DD, DD/MM/YYYY, MM/DD/YYYY or DD.MM.YYYY
+	Currency         string           `json:"Currency"`   // ISO 4217 code
+	Updated          *time.Time       `json:"Updated,omitempty"`
+}
+
+// WorkingHours is a working period of a weekday, with Start and End as HH:MM
+// in the user's timezone. A day may have several periods or none.
+type WorkingHours struct {
+	Weekday string `json:"Weekday"`
+	Start   string `json:"Start"`
+	End     string `json:"End"`
+}
+
+type DurationRounding struct {
+	Mode    string `json:"Mode"`
+	Minutes int    `json:"Minutes"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook posts the user's events of the subscribed types to URL. "*"
+// subscribes to every type.
+type Webhook struct {
+	ID      int       `json:"ID"`
+	URL     string    `json:"URL"`
+	Events  []string  `json:"Events"`
+	Active  bool      `json:"Active"`
+	Secret  string    `json:"Secret,omitempty"` // only returned when the webhook is created
+	Created time.Time `json:"Created"`
+	Updated time.Time `json:"Updated"`
+}
+
+type WebhookCreate struct {
+	URL    string   `json:"URL"`
+	Events []string `json:"Events"`
+}
+
+type WebhookUpdate struct {
+	URL    string   `json:"URL"`
+	Events []string `json:"Events"`
+	Active *bool    `json:"Active"`
+}
+
+// Webhook delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// WebhookDelivery is one event sent, or to be sent, to a webhook. Payload is
+// the exact request body.
+type WebhookDelivery struct {
+	ID             int             `json:"ID"`
+	WebhookID      int             `json:"WebhookID"`
+	EventID        string          `json:"EventID"`
+	EventType      string          `json:"EventType"`
+	Payload        json.RawMessage `json:"Payload"`
+	Status         string          `json:"Status"`
+	Attempts       int             `json:"Attempts"`
+	NextAttemptAt  *time.Time      `json:"NextAttemptAt,omitempty"`
+	ResponseStatus *int            `json:"ResponseStatus,omitempty"`
+	LastError      *string         `json:"LastError,omitempty"`
+	ReplayOf       *int            `json:"ReplayOf,omitempty"`
+	Created        time.Time       `json:"Created"`
+	DeliveredAt    *time.Time      `json:"DeliveredAt,omitempty"`
+}
+
+// WebhookTarget is a due delivery with what is needed to send it.
+type WebhookTarget struct {
+	DeliveryID int
+	URL        string
+	Secret     string
+	EventType  string
+	Payload    []byte
+	Attempts   int
+}
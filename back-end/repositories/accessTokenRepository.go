@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/lib/pq"
+)
+
+const accessTokenColumns = `id, name, prefix, scopes, user_id, expires_at, last_used_at, created`
+
+type AccessTokenRepository struct {
+	db *sql.DB
+}
+
+func NewAccessTokenRepository(db *sql.DB) *AccessTokenRepository {
+	return &AccessTokenRepository{db: db}
+}
+
+func (r *AccessTokenRepository) Create(ctx context.Context, token *models.PersonalAccessTokenCreate, hash, prefix string, userID string) (*models.PersonalAccessToken, error) {
+	row := r.db.QueryRowContext(ctx,
+		`INSERT INTO personal_access_tokens (user_id, name, token_hash, prefix, scopes, expires_at)
+         VALUES ($1, $2, $3, $4, $5, $6)
+         RETURNING `+accessTokenColumns,
+		userID, token.Name, hash, prefix, pq.Array(token.Scopes), token.ExpiresAt,
+	)
+	created, err := scanAccessToken(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create access token: %w", err)
+	}
+	return created, nil
+}
+
+// GetAll returns the user's tokens that are not revoked, expired ones
+// included.
+func (r *AccessTokenRepository) GetAll(ctx context.Context, userID string) ([]*models.PersonalAccessToken, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+accessTokenColumns+` FROM personal_access_tokens
+         WHERE user_id = $1 AND revoked_at IS NULL
+         ORDER BY created DESC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := make([]*models.PersonalAccessToken, 0)
+	for rows.Next() {
+		token, err := scanAccessToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan access token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// GetActiveByHash returns the live token with the given hash.
+func (r *AccessTokenRepository) GetActiveByHash(ctx context.Context, hash string) (*models.PersonalAccessToken, error) {
+	row := r.db.QueryRowContext(ctx,
+		`SELECT `+accessTokenColumns+` FROM personal_access_tokens
+         WHERE token_hash = $1 AND revoked_at IS NULL AND (expires_at IS NULL OR expires_at > $2)`,
+		hash, time.Now().UTC(),
+	)
+	token, err := scanAccessToken(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, models.ErrInvalidToken
+		}
+		return nil, fmt.Errorf("%w: failed to get access token: %v", models.ErrAuthUnavailable, err)
+	}
+	return token, nil
+}
+
+// TouchLastUsed records a use of the token. Uses within a minute of the last
+// recorded one are not written.
+func (r *AccessTokenRepository) TouchLastUsed(ctx context.Context, id int) error {
+	now := time.Now()
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE personal_access_tokens SET last_used_at = $1
+         WHERE id = $2 AND (last_used_at IS NULL OR last_used_at < $3)`,
+		now, id, now.Add(-time.Minute),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update access token: %w", err)
+	}
+	return nil
+}
+
+func (r *AccessTokenRepository) Revoke(ctx context.Context, id int, userID string) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE personal_access_tokens SET revoked_at = $1
+         WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL`,
+		time.Now(), id, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("access token not found")
+	}
+	return nil
+}
+
+func scanAccessToken(row rowScanner) (*models.PersonalAccessToken, error) {
+	var token models.PersonalAccessToken
+	err := row.Scan(&token.ID, &token.Name, &token.Prefix, pq.Array(&token.Scopes), &token.UserID,
+		&token.ExpiresAt, &token.LastUsedAt, &token.Created)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+)
+
+// AccountRepository schedules and carries out account deletions, and keeps
+// the audit log of them.
+type AccountRepository struct {
+	db *sql.DB
+}
+
+func NewAccountRepository(db *sql.DB) *AccountRepository {
+	return &AccountRepository{db: db}
+}
+
+// ScheduleDeletion schedules the user's account for deletion at, unless a
+// deletion is already scheduled, and returns the time it will happen.
+func (r *AccountRepository) ScheduleDeletion(ctx context.Context, at time.Time, userID string) (time.Time, error) {
+	var scheduled time.Time
+	err := r.db.QueryRowContext(ctx,
+		`UPDATE users SET deletion_scheduled_at = COALESCE(deletion_scheduled_at, $1)
+         WHERE id = $2
+         RETURNING deletion_scheduled_at`,
+		at, userID,
+	).Scan(&scheduled)
+	if err == sql.ErrNoRows {
+		return time.Time{}, fmt.Errorf("user not found")
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to schedule account deletion: %w", err)
+	}
+	return scheduled, nil
+}
+
+// CancelDeletion cancels the user's scheduled deletion if its grace period
+// is not over, and tells whether there was one to cancel.
+func (r *AccountRepository) CancelDeletion(ctx context.Context, userID string) (bool, error) {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE users SET deletion_scheduled_at = NULL WHERE id = $1 AND deletion_scheduled_at > $2`,
+		userID, time.Now(),
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel account deletion: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// GetDueDeletions returns the users whose grace period is over.
+func (r *AccountRepository) GetDueDeletions(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id FROM users WHERE deletion_scheduled_at <= $1 ORDER BY deletion_scheduled_at`, time.Now(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due account deletions: %w", err)
+	}
+	defer rows.Close()
+
+	var userIDs []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
+
+// DeleteUser deletes the user's account if its grace period is over. The
+// user row is locked first and stays locked until it is deleted, so the
+// deletion cannot be cancelled or carried out twice in between, and prepare
+// only runs for an account that is about to go. An account that is not due,
+// or locked by another purge, fails with models.ErrDeletionNotDue without
+// calling prepare. When prepare fails the account is kept.
+func (r *AccountRepository) DeleteUser(ctx context.Context, userID string, prepare func() error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		`SELECT id FROM users WHERE id = $1 AND deletion_scheduled_at <= $2 FOR UPDATE SKIP LOCKED`,
+		userID, time.Now(),
+	).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return models.ErrDeletionNotDue
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+
+	if err := prepare(); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("failed to delete user: user not found")
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	return nil
+}
+
+func (r *AccountRepository) Audit(ctx context.Context, userID string, action string, detail string) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO account_audit_log (user_id, action, detail) VALUES ($1, $2, $3)`,
+		userID, action, detail,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
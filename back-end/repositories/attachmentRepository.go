@@ -0,0 +1,293 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+)
+
+type AttachmentRepository struct {
+	db *sql.DB
+}
+
+func NewAttachmentRepository(db *sql.DB) *AttachmentRepository {
+	return &AttachmentRepository{db: db}
+}
+
+const attachmentColumns = `id, note_id, user_id, blob_hash, file_name, mime_type, size, created`
+
+// Create records the attachment and its blob row in one transaction. The
+// blob row stays locked until the attachment is committed, so an orphan
+// cleanup can neither remove the blob in between nor run while write puts
+// new content in the store. write is only called when the blob row is new.
+// Unless the user already references the blob, it must fit in their quota.
+func (r *AttachmentRepository) Create(ctx context.Context, attachment *models.Attachment, quota int64, write func() error) (*models.Attachment, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	inserted, err := lockBlob(ctx, tx, attachment.Hash, attachment.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	var owned bool
+	var used int64
+	err = tx.QueryRowContext(ctx, `
+		SELECT
+			EXISTS (SELECT 1 FROM attachments WHERE blob_hash = $1 AND user_id = $2),
+			`+blobUsage("$2")+`
+	`, attachment.Hash, attachment.UserID).Scan(&owned, &used)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage usage: %w", err)
+	}
+	if !owned && used+attachment.Size > quota {
+		return nil, models.ErrQuotaExceeded
+	}
+
+	if inserted {
+		if err := write(); err != nil {
+			return nil, err
+		}
+	}
+
+	query := `
+		INSERT INTO attachments (note_id, user_id, blob_hash, file_name, mime_type, size)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + attachmentColumns
+
+	created, err := scanAttachment(tx.QueryRowContext(ctx, query,
+		attachment.NoteID, attachment.UserID, attachment.Hash,
+		attachment.FileName, attachment.MimeType, attachment.Size,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create attachment: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to create attachment: %w", err)
+	}
+	return created, nil
+}
+
+// lockBlob locks the blob row for hash within tx, inserting it when there is
+// none, and reports whether it was inserted. A row deleted by a cleanup that
+// committed while waiting for the lock is inserted again.
+func lockBlob(ctx context.Context, tx *sql.Tx, hash string, size int64) (bool, error) {
+	for {
+		result, err := tx.ExecContext(ctx, `INSERT INTO blobs (hash, size) VALUES ($1, $2) ON CONFLICT (hash) DO NOTHING`, hash, size)
+		if err != nil {
+			return false, fmt.Errorf("failed to save blob: %w", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return false, fmt.Errorf("failed to get affected rows: %w", err)
+		}
+		if rowsAffected == 1 {
+			return true, nil
+		}
+
+		err = tx.QueryRowContext(ctx, `SELECT hash FROM blobs WHERE hash = $1 FOR UPDATE`, hash).Scan(&hash)
+		if err == nil {
+			return false, nil
+		}
+		if err != sql.ErrNoRows {
+			return false, fmt.Errorf("failed to lock blob: %w", err)
+		}
+	}
+}
+
+func (r *AttachmentRepository) GetByID(ctx context.Context, id int, userID string) (*models.Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE id = $1 AND user_id = $2`
+
+	attachment, err := scanAttachment(r.db.QueryRowContext(ctx, query, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, models.ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+	return attachment, nil
+}
+
+// GetForDownload looks an attachment up without an owner. Callers must have
+// verified a signed download URL first. Attachments of trashed notes are not
+// served.
+func (r *AttachmentRepository) GetForDownload(ctx context.Context, id int) (*models.Attachment, error) {
+	query := `
+		SELECT ` + attachmentColumns + ` FROM attachments
+		WHERE id = $1 AND EXISTS (SELECT 1 FROM notes n WHERE n.id = note_id AND n.deleted_at IS NULL)
+	`
+
+	attachment, err := scanAttachment(r.db.QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, models.ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+	return attachment, nil
+}
+
+func (r *AttachmentRepository) GetByNote(ctx context.Context, noteID int, userID string) ([]*models.Attachment, error) {
+	query := `
+		SELECT ` + attachmentColumns + `
+		FROM attachments
+		WHERE note_id = $1 AND user_id = $2
+		ORDER BY created, id
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, noteID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachments: %w", err)
+	}
+	defer rows.Close()
+
+	attachments := make([]*models.Attachment, 0)
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment: %w", err)
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, rows.Err()
+}
+
+func (r *AttachmentRepository) Delete(ctx context.Context, id int, userID string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM attachments WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.ErrAttachmentNotFound
+	}
+	return nil
+}
+
+// blobUsage sums the size of the distinct blobs referenced by user $user,
+// given as a parameter placeholder.
+func blobUsage(user string) string {
+	return fmt.Sprintf(`(
+		SELECT COALESCE(SUM(b.size), 0)
+		FROM blobs b
+		WHERE EXISTS (SELECT 1 FROM attachments a WHERE a.blob_hash = b.hash AND a.user_id = %s)
+	)`, user)
+}
+
+// GetUsage returns the bytes used by the user's distinct blobs.
+func (r *AttachmentRepository) GetUsage(ctx context.Context, userID string) (int64, error) {
+	var used int64
+	if err := r.db.QueryRowContext(ctx, `SELECT `+blobUsage("$1"), userID).Scan(&used); err != nil {
+		return 0, fmt.Errorf("failed to get storage usage: %w", err)
+	}
+	return used, nil
+}
+
+// DeleteOrphanBlobs removes the blob rows no attachment refers to any more,
+// calling remove to delete the content of each from the store. Every blob
+// is removed in its own transaction holding the row lock, so an upload of
+// the same content waits for the removal to finish and writes the content
+// again. Blobs locked by an upload are skipped, as are those remove fails
+// for, which are kept for the next cleanup.
+func (r *AttachmentRepository) DeleteOrphanBlobs(ctx context.Context, remove func(ctx context.Context, hash string) error) error {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT hash FROM blobs b
+		WHERE NOT EXISTS (SELECT 1 FROM attachments a WHERE a.blob_hash = b.hash)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to get orphaned blobs: %w", err)
+	}
+	hashes := make([]string, 0)
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan blob: %w", err)
+		}
+		hashes = append(hashes, hash)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get orphaned blobs: %w", err)
+	}
+
+	var errs []error
+	for _, hash := range hashes {
+		if err := r.deleteOrphanBlob(ctx, hash, remove); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *AttachmentRepository) deleteOrphanBlob(ctx context.Context, hash string, remove func(ctx context.Context, hash string) error) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, `SELECT hash FROM blobs WHERE hash = $1 FOR UPDATE SKIP LOCKED`, hash).Scan(&hash)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock blob %s: %w", hash, err)
+	}
+
+	// Checked after taking the lock, so attachments committed since the
+	// blob was listed are seen.
+	var referenced bool
+	err = tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM attachments WHERE blob_hash = $1)`, hash).Scan(&referenced)
+	if err != nil {
+		return fmt.Errorf("failed to check blob %s: %w", hash, err)
+	}
+	if referenced {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM blobs WHERE hash = $1`, hash); err != nil {
+		return fmt.Errorf("failed to delete blob %s: %w", hash, err)
+	}
+	if err := remove(ctx, hash); err != nil {
+		return fmt.Errorf("failed to delete blob %s: %w", hash, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to delete blob %s: %w", hash, err)
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanAttachment(row rowScanner) (*models.Attachment, error) {
+	var attachment models.Attachment
+	err := row.Scan(
+		&attachment.ID,
+		&attachment.NoteID,
+		&attachment.UserID,
+		&attachment.Hash,
+		&attachment.FileName,
+		&attachment.MimeType,
+		&attachment.Size,
+		&attachment.Created,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &attachment, nil
+}
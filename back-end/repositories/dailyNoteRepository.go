@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+)
+
+type DailyNoteRepository struct {
+	db *sql.DB
+}
+
+func NewDailyNoteRepository(db *sql.DB) *DailyNoteRepository {
+	return &DailyNoteRepository{db: db}
+}
+
+// GetSettings returns the user's daily note settings, or the defaults when
+// none were saved.
+func (r *DailyNoteRepository) GetSettings(ctx context.Context, userID string) (*models.DailyNoteSettings, error) {
+	query := `SELECT folder_id, template_id FROM daily_note_settings WHERE user_id = $1`
+
+	var folderID, templateID sql.NullInt64
+	settings := &models.DailyNoteSettings{}
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(&folderID, &templateID)
+	if err == sql.ErrNoRows {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get daily note settings: %w", err)
+	}
+
+	if folderID.Valid {
+		id := int(folderID.Int64)
+		settings.FolderID = &id
+	}
+	if templateID.Valid {
+		id := int(templateID.Int64)
+		settings.TemplateID = &id
+	}
+	return settings, nil
+}
+
+func (r *DailyNoteRepository) SaveSettings(ctx context.Context, settings *models.DailyNoteSettings, userID string) error {
+	query := `
+		INSERT INTO daily_note_settings (user_id, folder_id, template_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET folder_id = EXCLUDED.folder_id, template_id = EXCLUDED.template_id
+	`
+
+	_, err := r.db.ExecContext(ctx, query, userID, settings.FolderID, settings.TemplateID)
+	if err != nil {
+		return fmt.Errorf("failed to save daily note settings: %w", err)
+	}
+	return nil
+}
+
+// GetOrCreate returns the ID of the user's note for day (YYYY-MM-DD),
+// inserting note first when there is none. A per-user, per-day advisory lock
+// makes concurrent calls agree on a single note. A daily note that was moved
+// to the trash is replaced by a new one.
+func (r *DailyNoteRepository) GetOrCreate(ctx context.Context, day string, note *models.NoteCreate, userID string) (int, bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('daily_note:' || $1 || ':' || $2))`, userID, day)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to lock daily note: %w", err)
+	}
+
+	var noteID int
+	err = tx.QueryRowContext(ctx, `
+		SELECT d.note_id
+		FROM daily_notes d
+		JOIN notes n ON n.id = d.note_id
+		WHERE d.user_id = $1 AND d.day = $2 AND n.deleted_at IS NULL
+	`, userID, day).Scan(&noteID)
+	if err == nil {
+		return noteID, false, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, false, fmt.Errorf("failed to get daily note: %w", err)
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO notes (title, content, folder_id, user_id, position)
+		VALUES ($1, $2, $3, $4, `+nextNotePosition("$3::int", "$4")+`)
+		RETURNING id
+	`, note.Title, note.Content, note.FolderID, userID).Scan(&noteID)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to create daily note: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO daily_notes (user_id, day, note_id) VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, day) DO UPDATE SET note_id = EXCLUDED.note_id
+	`, userID, day, noteID)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to save daily note: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, false, fmt.Errorf("failed to create daily note: %w", err)
+	}
+	return noteID, true, nil
+}
@@ -0,0 +1,298 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/lib/pq"
+)
+
+const exportColumns = `id, user_id, status, COALESCE(error, ''), COALESCE(blob_key, ''), COALESCE(size, 0), created, finished_at, expires_at`
+
+// ExportRepository stores data export jobs and reads every row of a user for
+// them, trashed rows included.
+type ExportRepository struct {
+	db *sql.DB
+}
+
+func NewExportRepository(db *sql.DB) *ExportRepository {
+	return &ExportRepository{db: db}
+}
+
+func (r *ExportRepository) Create(ctx context.Context, userID string) (*models.DataExport, error) {
+	query := `INSERT INTO data_exports (user_id, status) VALUES ($1, $2) RETURNING ` + exportColumns
+
+	export, err := scanExport(r.db.QueryRowContext(ctx, query, userID, models.ExportPending))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export: %w", err)
+	}
+	return export, nil
+}
+
+func (r *ExportRepository) GetByID(ctx context.Context, id int, userID string) (*models.DataExport, error) {
+	query := `SELECT ` + exportColumns + ` FROM data_exports WHERE id = $1 AND user_id = $2`
+
+	export, err := scanExport(r.db.QueryRowContext(ctx, query, id, userID))
+	if err == sql.ErrNoRows {
+		return nil, models.ErrExportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export: %w", err)
+	}
+	return export, nil
+}
+
+// GetOpen returns the user's pending or running export, or nil when there is
+// none.
+func (r *ExportRepository) GetOpen(ctx context.Context, userID string) (*models.DataExport, error) {
+	query := `
+		SELECT ` + exportColumns + ` FROM data_exports
+		WHERE user_id = $1 AND status IN ($2, $3)
+		ORDER BY created DESC LIMIT 1
+	`
+
+	export, err := scanExport(r.db.QueryRowContext(ctx, query, userID, models.ExportPending, models.ExportRunning))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export: %w", err)
+	}
+	return export, nil
+}
+
+// GetDone returns a finished export for download, whoever it belongs to.
+func (r *ExportRepository) GetDone(ctx context.Context, id int) (*models.DataExport, error) {
+	query := `SELECT ` + exportColumns + ` FROM data_exports WHERE id = $1 AND status = $2`
+
+	export, err := scanExport(r.db.QueryRowContext(ctx, query, id, models.ExportDone))
+	if err == sql.ErrNoRows {
+		return nil, models.ErrExportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get export: %w", err)
+	}
+	return export, nil
+}
+
+// ClaimNext marks the oldest pending export, or a running one whose worker
+// let its lease run out, as running for lease and returns it. It returns nil
+// when there is nothing to do.
+func (r *ExportRepository) ClaimNext(ctx context.Context, lease time.Duration) (*models.DataExport, error) {
+	query := `
+		UPDATE data_exports SET status = $1, lease_until = $2
+		WHERE id = (
+			SELECT id FROM data_exports
+			WHERE status = $3 OR (status = $1 AND lease_until < $4)
+			ORDER BY created ASC
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + exportColumns
+
+	now := time.Now()
+	export, err := scanExport(r.db.QueryRowContext(ctx, query, models.ExportRunning, now.Add(lease), models.ExportPending, now))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim export: %w", err)
+	}
+	return export, nil
+}
+
+func (r *ExportRepository) Finish(ctx context.Context, id int, blobKey string, size int64, expiresAt time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE data_exports SET status = $1, blob_key = $2, size = $3, finished_at = $4, expires_at = $5, lease_until = NULL
+         WHERE id = $6`,
+		models.ExportDone, blobKey, size, time.Now(), expiresAt, id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to finish export: %w", err)
+	}
+	return nil
+}
+
+func (r *ExportRepository) Fail(ctx context.Context, id int, message string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE data_exports SET status = $1, error = $2, finished_at = $3, lease_until = NULL WHERE id = $4`,
+		models.ExportFailed, message, time.Now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record export failure: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpired removes the exports whose archives have expired and returns
+// their blob keys.
+func (r *ExportRepository) DeleteExpired(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`DELETE FROM data_exports WHERE expires_at < $1 RETURNING COALESCE(blob_key, '')`, time.Now(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete expired exports: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan export: %w", err)
+		}
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys, rows.Err()
+}
+
+// DeleteByUser removes all the user's exports and returns the blob keys of
+// their archives.
+func (r *ExportRepository) DeleteByUser(ctx context.Context, userID string) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`DELETE FROM data_exports WHERE user_id = $1 RETURNING COALESCE(blob_key, '')`, userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete exports: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to scan export: %w", err)
+		}
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys, rows.Err()
+}
+
+func (r *ExportRepository) GetUser(ctx context.Context, userID string) (*models.ExportUser, error) {
+	var user models.ExportUser
+	err := r.db.QueryRowContext(ctx, `SELECT id, email FROM users WHERE id = $1`, userID).Scan(&user.ID, &user.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &user, nil
+}
+
+func (r *ExportRepository) GetProjects(ctx context.Context, userID string) ([]models.ExportProject, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, name, COALESCE(description, ''), color, version, deleted_at
+         FROM projects WHERE user_id = $1 ORDER BY id`, userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get projects: %w", err)
+	}
+	defer rows.Close()
+
+	projects := make([]models.ExportProject, 0)
+	for rows.Next() {
+		var project models.ExportProject
+		if err := rows.Scan(&project.ID, &project.Name, &project.Description, &project.Color, &project.Version, &project.DeletedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan project: %w", err)
+		}
+		projects = append(projects, project)
+	}
+	return projects, rows.Err()
+}
+
+func (r *ExportRepository) GetTimes(ctx context.Context, userID string) ([]models.ExportTimeEntry, error) {
+	return r.getTimeEntries(ctx,
+		`SELECT id, COALESCE(description, ''), project_id, start_date, end_date, version, deleted_at
+         FROM times WHERE user_id = $1 ORDER BY start_date, id`, userID)
+}
+
+func (r *ExportRepository) GetTimeBoxes(ctx context.Context, userID string) ([]models.ExportTimeEntry, error) {
+	return r.getTimeEntries(ctx,
+		`SELECT id, COALESCE(description, ''), project_id, start_date, end_date, version, NULL::timestamp
+         FROM timeBoxes WHERE user_id = $1 ORDER BY start_date, id`, userID)
+}
+
+func (r *ExportRepository) getTimeEntries(ctx context.Context, query string, userID string) ([]models.ExportTimeEntry, error) {
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get time entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]models.ExportTimeEntry, 0)
+	for rows.Next() {
+		var entry models.ExportTimeEntry
+		err := rows.Scan(&entry.ID, &entry.Description, &entry.ProjectID, &entry.StartDate, &entry.EndDate, &entry.Version, &entry.DeletedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan time entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+func (r *ExportRepository) GetFolders(ctx context.Context, userID string) ([]models.ExportFolder, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, name, parent_id, position, version, created, updated, deleted_at
+         FROM folders WHERE user_id = $1 ORDER BY id`, userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get folders: %w", err)
+	}
+	defer rows.Close()
+
+	folders := make([]models.ExportFolder, 0)
+	for rows.Next() {
+		var folder models.ExportFolder
+		err := rows.Scan(&folder.ID, &folder.Name, &folder.ParentID, &folder.Position, &folder.Version,
+			&folder.Created, &folder.Updated, &folder.DeletedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan folder: %w", err)
+		}
+		folders = append(folders, folder)
+	}
+	return folders, rows.Err()
+}
+
+func (r *ExportRepository) GetNotes(ctx context.Context, userID string) ([]models.ExportNote, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, title, content, folder_id, is_template, pinned, position,
+             ARRAY(
+                 SELECT t.name FROM note_tags nt JOIN tags t ON t.id = nt.tag_id
+                 WHERE nt.note_id = notes.id ORDER BY lower(t.name)
+             ),
+             version, created, updated, deleted_at
+         FROM notes WHERE user_id = $1 ORDER BY id`, userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notes: %w", err)
+	}
+	defer rows.Close()
+
+	notes := make([]models.ExportNote, 0)
+	for rows.Next() {
+		var note models.ExportNote
+		err := rows.Scan(&note.ID, &note.Title, &note.Content, &note.FolderID, &note.IsTemplate, &note.Pinned,
+			&note.Position, pq.Array(&note.Tags), &note.Version, &note.Created, &note.Updated, &note.DeletedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan note: %w", err)
+		}
+		notes = append(notes, note)
+	}
+	return notes, rows.Err()
+}
+
+func scanExport(row rowScanner) (*models.DataExport, error) {
+	var export models.DataExport
+	err := row.Scan(&export.ID, &export.UserID, &export.Status, &export.Error, &export.BlobKey, &export.Size,
+		&export.Created, &export.FinishedAt, &export.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
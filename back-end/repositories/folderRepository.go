@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
@@ -16,35 +17,102 @@ func NewFolderRepository(db *sql.DB) *FolderRepository {
 	return &FolderRepository{db: db}
 }
 
-func (r *FolderRepository) Create(folder *models.FolderCreate, userID string) (*models.Folder, error) {
+// nextFolderPosition places a folder after the last live subfolder of
+// $parent for user $user, both given as parameter placeholders.
+func nextFolderPosition(parent, user string) string {
+	return fmt.Sprintf(`(
+		SELECT COALESCE(MAX(other.position) + 1, 0) FROM folders other
+		WHERE other.user_id = %s AND other.parent_id IS NOT DISTINCT FROM %s AND other.deleted_at IS NULL
+	)`, user, parent)
+}
+
+func (r *FolderRepository) Create(ctx context.Context, folder *models.FolderCreate, userID string) (*models.Folder, error) {
+	return insertFolder(ctx, r.db, folder, userID)
+}
+
+// ImportTree creates the folders and then the notes of an archive import
+// under parentID in one transaction, so a failed import leaves nothing
+// behind. Folders must come after their parent.
+func (r *FolderRepository) ImportTree(ctx context.Context, parentID *int, folders []models.ArchiveFolder, notes []models.ArchiveNote, userID string) ([]*models.Folder, []*models.Note, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+	
+	createdFolders := make([]*models.Folder, 0, len(folders))
+	folderID := func(index int) *int {
+		if index < 0 {
+			return parentID
+		}
+		return &createdFolders[index].ID
+	}
+	
+	for _, folder := range folders {
+		created, err := insertFolder(ctx, tx, &models.FolderCreate{Name: folder.Name, ParentID: folderID(folder.Parent)}, userID)
+		if err != nil {
+			return nil, nil, err
+		}
+		createdFolders = append(createdFolders, created)
+	}
+	
+	createdNotes := make([]*models.Note, 0, len(notes))
+	for _, note := range notes {
+		created, err := insertNote(ctx, tx, &models.NoteCreate{Title: note.Title, Content: note.Content, FolderID: folderID(note.Folder)}, userID)
+		if err != nil {
+			return nil, nil, err
+		}
+		createdNotes = append(createdNotes, created)
+	}
+	
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to import archive: %w", err)
+	}
+	return createdFolders, createdNotes, nil
+}
+
+// rowQuerier is a *sql.DB or a *sql.Tx.
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func insertFolder(ctx context.Context, q rowQuerier, folder *models.FolderCreate, userID string) (*models.Folder, error) {
 	query := `
-		INSERT INTO folders (name, parent_id, user_id) 
-		VALUES ($1, $2, $3) 
-		RETURNING id
+		INSERT INTO folders (name, parent_id, user_id, position) 
+		VALUES ($1, $2, $3, `+nextFolderPosition("$2::int", "$3")+`) 
+		RETURNING id, name, parent_id, user_id, position, version, created, updated
 	`
 	
-	var id int
-	err := r.db.QueryRow(query, folder.Name, folder.ParentID, userID).Scan(&id)
+	var created models.Folder
+	var parentID sql.NullInt64
+	
+	err := q.QueryRowContext(ctx, query, folder.Name, folder.ParentID, userID).
+		Scan(&created.ID, &created.Name, &parentID, &created.UserID, &created.Position, &created.Version, &created.Created, &created.Updated)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create folder: %w", err)
 	}
-
-	return r.GetByID(id, userID)
+	
+	if parentID.Valid {
+		pid := int(parentID.Int64)
+		created.ParentID = &pid
+	}
+	
+	return &created, nil
 }
 
-func (r *FolderRepository) GetByID(id int, userID string) (*models.Folder, error) {
+func (r *FolderRepository) GetByID(ctx context.Context, id int, userID string) (*models.Folder, error) {
 	query := `
-		SELECT id, name, parent_id, user_id, created, updated 
+		SELECT id, name, parent_id, user_id, position, version, created, updated 
 		FROM folders 
-		WHERE id = $1 AND user_id = $2
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
 	`
 	
-	row := r.db.QueryRow(query, id, userID)
+	row := r.db.QueryRowContext(ctx, query, id, userID)
 	
 	var folder models.Folder
 	var parentID sql.NullInt64
 	
-	err := row.Scan(&folder.ID, &folder.Name, &parentID, &folder.UserID, &folder.Created, &folder.Updated)
+	err := row.Scan(&folder.ID, &folder.Name, &parentID, &folder.UserID, &folder.Position, &folder.Version, &folder.Created, &folder.Updated)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("folder not found")
@@ -60,15 +128,15 @@ func (r *FolderRepository) GetByID(id int, userID string) (*models.Folder, error
 	return &folder, nil
 }
 
-func (r *FolderRepository) GetAllByUser(userID string) ([]*models.Folder, error) {
+func (r *FolderRepository) GetAllByUser(ctx context.Context, userID string) ([]*models.Folder, error) {
 	query := `
-		SELECT id, name, parent_id, user_id, created, updated 
+		SELECT id, name, parent_id, user_id, position, version, created, updated 
 		FROM folders 
-		WHERE user_id = $1 
-		ORDER BY name ASC
+		WHERE user_id = $1 AND deleted_at IS NULL
+		ORDER BY position ASC, id ASC
 	`
 	
-	rows, err := r.db.Query(query, userID)
+	rows, err := r.db.QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get folders: %w", err)
 	}
@@ -80,7 +148,7 @@ func (r *FolderRepository) GetAllByUser(userID string) ([]*models.Folder, error)
 		var folder models.Folder
 		var parentID sql.NullInt64
 		
-		err := rows.Scan(&folder.ID, &folder.Name, &parentID, &folder.UserID, &folder.Created, &folder.Updated)
+		err := rows.Scan(&folder.ID, &folder.Name, &parentID, &folder.UserID, &folder.Position, &folder.Version, &folder.Created, &folder.Updated)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan folder: %w", err)
 		}
@@ -96,29 +164,29 @@ func (r *FolderRepository) GetAllByUser(userID string) ([]*models.Folder, error)
 	return folders, nil
 }
 
-func (r *FolderRepository) GetByParent(parentID *int, userID string) ([]*models.Folder, error) {
+func (r *FolderRepository) GetByParent(ctx context.Context, parentID *int, userID string) ([]*models.Folder, error) {
 	var query string
 	var args []interface{}
 	
 	if parentID == nil {
 		query = `
-			SELECT id, name, parent_id, user_id, created, updated 
+			SELECT id, name, parent_id, user_id, position, version, created, updated 
 			FROM folders 
-			WHERE user_id = $1 AND parent_id IS NULL 
-			ORDER BY name ASC
+			WHERE user_id = $1 AND parent_id IS NULL AND deleted_at IS NULL
+			ORDER BY position ASC, id ASC
 		`
 		args = []interface{}{userID}
 	} else {
 		query = `
-			SELECT id, name, parent_id, user_id, created, updated 
+			SELECT id, name, parent_id, user_id, position, version, created, updated 
 			FROM folders 
-			WHERE user_id = $1 AND parent_id = $2 
-			ORDER BY name ASC
+			WHERE user_id = $1 AND parent_id = $2 AND deleted_at IS NULL
+			ORDER BY position ASC, id ASC
 		`
 		args = []interface{}{userID, *parentID}
 	}
 	
-	rows, err := r.db.Query(query, args...)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get folders by parent: %w", err)
 	}
@@ -130,7 +198,7 @@ func (r *FolderRepository) GetByParent(parentID *int, userID string) ([]*models.
 		var folder models.Folder
 		var parentIDNull sql.NullInt64
 		
-		err := rows.Scan(&folder.ID, &folder.Name, &parentIDNull, &folder.UserID, &folder.Created, &folder.Updated)
+		err := rows.Scan(&folder.ID, &folder.Name, &parentIDNull, &folder.UserID, &folder.Position, &folder.Version, &folder.Created, &folder.Updated)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan folder: %w", err)
 		}
@@ -146,25 +214,69 @@ func (r *FolderRepository) GetByParent(parentID *int, userID string) ([]*models.
 	return folders, nil
 }
 
-func (r *FolderRepository) Update(id int, folder *models.FolderUpdate, userID string) (*models.Folder, error) {
+// Update overwrites the folder and bumps its version. When expectedVersion is
+// set the write only happens if the stored version still matches it. A folder
+// moved to another parent goes to the end of that parent.
+func (r *FolderRepository) Update(ctx context.Context, id int, folder *models.FolderUpdate, userID string, expectedVersion *int) (*models.Folder, error) {
 	query := `
 		UPDATE folders 
-		SET name = $1, parent_id = $2, updated = $3 
-		WHERE id = $4 AND user_id = $5
+		SET name = $1, parent_id = $2, updated = $3, version = version + 1, 
+			position = CASE WHEN parent_id IS DISTINCT FROM $2::int 
+				THEN `+nextFolderPosition("$2::int", "$5")+` ELSE position END 
+		WHERE id = $4 AND user_id = $5 AND deleted_at IS NULL AND ($6::int IS NULL OR version = $6)
+		RETURNING id, name, parent_id, user_id, position, version, created, updated
 	`
 	
-	_, err := r.db.Exec(query, folder.Name, folder.ParentID, time.Now(), id, userID)
+	var updated models.Folder
+	var parentID sql.NullInt64
+	
+	err := r.db.QueryRowContext(ctx, query, folder.Name, folder.ParentID, time.Now(), id, userID, expectedVersion).
+		Scan(&updated.ID, &updated.Name, &parentID, &updated.UserID, &updated.Position, &updated.Version, &updated.Created, &updated.Updated)
+	if err == sql.ErrNoRows {
+		if _, err := r.GetByID(ctx, id, userID); err != nil {
+			return nil, err
+		}
+		return nil, models.ErrVersionConflict
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to update folder: %w", err)
 	}
 	
-	return r.GetByID(id, userID)
+	if parentID.Valid {
+		pid := int(parentID.Int64)
+		updated.ParentID = &pid
+	}
+	
+	return &updated, nil
 }
 
-func (r *FolderRepository) Delete(id int, userID string) error {
-	query := `DELETE FROM folders WHERE id = $1 AND user_id = $2`
+// Reorder renumbers the positions of the subfolders of parentID to follow
+// ids, which must all be live subfolders of that parent.
+func (r *FolderRepository) Reorder(ctx context.Context, parentID *int, ids []int, userID string) error {
+	return reorder(ctx, r.db, "folders", "parent_id", "folder", parentID, ids, userID)
+}
+
+// Delete moves the folder and everything below it to the trash. All rows get
+// the same deleted_at so that restoring the folder brings back exactly what
+// this call removed. Subfolders and notes trashed earlier are left alone.
+func (r *FolderRepository) Delete(ctx context.Context, id int, userID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+	
+	query := `
+		WITH RECURSIVE tree AS (
+			SELECT id FROM folders WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+			UNION ALL
+			SELECT f.id FROM folders f JOIN tree t ON f.parent_id = t.id WHERE f.deleted_at IS NULL
+		)
+		UPDATE folders SET deleted_at = $3 WHERE id IN (SELECT id FROM tree)
+	`
 	
-	result, err := r.db.Exec(query, id, userID)
+	deletedAt := time.Now()
+	result, err := tx.ExecContext(ctx, query, id, userID, deletedAt)
 	if err != nil {
 		return fmt.Errorf("failed to delete folder: %w", err)
 	}
@@ -178,5 +290,17 @@ func (r *FolderRepository) Delete(id int, userID string) error {
 		return fmt.Errorf("folder not found")
 	}
 	
+	_, err = tx.ExecContext(ctx, `
+		UPDATE notes SET deleted_at = $1
+		WHERE deleted_at IS NULL AND folder_id IN (SELECT id FROM folders WHERE deleted_at = $1 AND user_id = $2)
+	`, deletedAt, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete folder notes: %w", err)
+	}
+	
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to delete folder: %w", err)
+	}
+	
 	return nil
 }
@@ -0,0 +1,84 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+)
+
+type NoteLinkRepository struct {
+	db *sql.DB
+}
+
+func NewNoteLinkRepository(db *sql.DB) *NoteLinkRepository {
+	return &NoteLinkRepository{db: db}
+}
+
+// ReplaceLinks swaps the stored outgoing links of a note for links. Links to
+// notes that do not exist yet or are in the trash are kept, and resolve once
+// the target is created or restored. Links to the note itself or to another
+// user's note are skipped.
+func (r *NoteLinkRepository) ReplaceLinks(ctx context.Context, sourceID int, links []models.NoteLink, userID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM note_links WHERE source_note_id = $1`, sourceID)
+	if err != nil {
+		return fmt.Errorf("failed to clear note links: %w", err)
+	}
+
+	query := `
+		INSERT INTO note_links (source_note_id, target_note_id, context)
+		SELECT $1, $3, $2
+		WHERE $3 <> $1 AND NOT EXISTS (SELECT 1 FROM notes WHERE id = $3 AND user_id <> $4)
+	`
+	for _, link := range links {
+		_, err := tx.ExecContext(ctx, query, sourceID, link.Context, link.TargetID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to save note link: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save note links: %w", err)
+	}
+	return nil
+}
+
+// GetBacklinks returns the notes linking to targetID, most recently updated
+// first.
+func (r *NoteLinkRepository) GetBacklinks(ctx context.Context, targetID int, userID string) ([]*models.Backlink, error) {
+	query := `
+		SELECT n.id, n.title, l.context, n.updated
+		FROM note_links l
+		JOIN notes n ON n.id = l.source_note_id
+		WHERE l.target_note_id = $1 AND n.user_id = $2 AND n.deleted_at IS NULL
+		ORDER BY n.updated DESC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, targetID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backlinks: %w", err)
+	}
+	defer rows.Close()
+
+	backlinks := make([]*models.Backlink, 0)
+	for rows.Next() {
+		var backlink models.Backlink
+		err := rows.Scan(&backlink.NoteID, &backlink.Title, &backlink.Context, &backlink.Updated)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan backlink: %w", err)
+		}
+		backlinks = append(backlinks, &backlink)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get backlinks: %w", err)
+	}
+
+	return backlinks, nil
+}
@@ -0,0 +1,182 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+)
+
+// NoteRelationTarget names a join table between notes and another resource.
+type NoteRelationTarget struct {
+	table  string
+	column string
+	label  string
+}
+
+var (
+	NoteProjectRelation   = NoteRelationTarget{table: "note_projects", column: "project_id", label: "project"}
+	NoteTimeEntryRelation = NoteRelationTarget{table: "note_time_entries", column: "time_entry_id", label: "time entry"}
+	NoteTimeBoxRelation   = NoteRelationTarget{table: "note_time_boxes", column: "time_box_id", label: "time box"}
+)
+
+func (t NoteRelationTarget) Label() string {
+	return t.label
+}
+
+type NoteRelationRepository struct {
+	db *sql.DB
+}
+
+func NewNoteRelationRepository(db *sql.DB) *NoteRelationRepository {
+	return &NoteRelationRepository{db: db}
+}
+
+// Attach links the note to the target row. Attaching twice is a no-op.
+func (r *NoteRelationRepository) Attach(ctx context.Context, target NoteRelationTarget, noteID int, targetID int) error {
+	query := fmt.Sprintf(`
+		INSERT INTO %s (note_id, %s) VALUES ($1, $2)
+		ON CONFLICT DO NOTHING
+	`, target.table, target.column)
+
+	_, err := r.db.ExecContext(ctx, query, noteID, targetID)
+	if err != nil {
+		return fmt.Errorf("failed to attach %s: %w", target.label, err)
+	}
+	return nil
+}
+
+func (r *NoteRelationRepository) Detach(ctx context.Context, target NoteRelationTarget, noteID int, targetID int) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE note_id = $1 AND %s = $2`, target.table, target.column)
+
+	result, err := r.db.ExecContext(ctx, query, noteID, targetID)
+	if err != nil {
+		return fmt.Errorf("failed to detach %s: %w", target.label, err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%s is not attached to this note", target.label)
+	}
+	return nil
+}
+
+func (r *NoteRelationRepository) GetRelations(ctx context.Context, noteID int) (*models.NoteRelations, error) {
+	relations := &models.NoteRelations{}
+	for _, item := range []struct {
+		target NoteRelationTarget
+		ids    *[]int
+	}{
+		{NoteProjectRelation, &relations.ProjectIDs},
+		{NoteTimeEntryRelation, &relations.TimeEntryIDs},
+		{NoteTimeBoxRelation, &relations.TimeBoxIDs},
+	} {
+		ids, err := r.targetIDs(ctx, item.target, noteID)
+		if err != nil {
+			return nil, err
+		}
+		*item.ids = ids
+	}
+	return relations, nil
+}
+
+func (r *NoteRelationRepository) targetIDs(ctx context.Context, target NoteRelationTarget, noteID int) ([]int, error) {
+	query := fmt.Sprintf(`SELECT %s FROM %s WHERE note_id = $1 ORDER BY %s`, target.column, target.table, target.column)
+
+	rows, err := r.db.QueryContext(ctx, query, noteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attached %s: %w", target.label, err)
+	}
+	defer rows.Close()
+
+	ids := make([]int, 0)
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan attached %s: %w", target.label, err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// GetNoteStubs returns the user's notes attached to targetID.
+func (r *NoteRelationRepository) GetNoteStubs(ctx context.Context, target NoteRelationTarget, targetID int, userID string) ([]*models.NoteStub, error) {
+	query := fmt.Sprintf(`
+		SELECT n.id, n.title, n.folder_id, n.updated
+		FROM %s rel
+		JOIN notes n ON n.id = rel.note_id
+		WHERE rel.%s = $1 AND n.user_id = $2 AND n.deleted_at IS NULL
+		ORDER BY n.updated DESC
+	`, target.table, target.column)
+
+	rows, err := r.db.QueryContext(ctx, query, targetID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notes: %w", err)
+	}
+	defer rows.Close()
+
+	stubs := make([]*models.NoteStub, 0)
+	for rows.Next() {
+		stub, err := scanNoteStub(rows)
+		if err != nil {
+			return nil, err
+		}
+		stubs = append(stubs, stub)
+	}
+	return stubs, rows.Err()
+}
+
+// GetNoteStubsByTarget returns every attached note of the user's rows of the
+// given kind, keyed by target ID.
+func (r *NoteRelationRepository) GetNoteStubsByTarget(ctx context.Context, target NoteRelationTarget, userID string) (map[int][]models.NoteStub, error) {
+	query := fmt.Sprintf(`
+		SELECT rel.%s, n.id, n.title, n.folder_id, n.updated
+		FROM %s rel
+		JOIN notes n ON n.id = rel.note_id
+		WHERE n.user_id = $1 AND n.deleted_at IS NULL
+		ORDER BY n.updated DESC
+	`, target.column, target.table)
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notes: %w", err)
+	}
+	defer rows.Close()
+
+	stubs := map[int][]models.NoteStub{}
+	for rows.Next() {
+		var targetID int
+		var stub models.NoteStub
+		var folderID sql.NullInt64
+		err := rows.Scan(&targetID, &stub.ID, &stub.Title, &folderID, &stub.Updated)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan note: %w", err)
+		}
+		if folderID.Valid {
+			fid := int(folderID.Int64)
+			stub.FolderID = &fid
+		}
+		stubs[targetID] = append(stubs[targetID], stub)
+	}
+	return stubs, rows.Err()
+}
+
+func scanNoteStub(rows *sql.Rows) (*models.NoteStub, error) {
+	var stub models.NoteStub
+	var folderID sql.NullInt64
+	err := rows.Scan(&stub.ID, &stub.Title, &folderID, &stub.Updated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan note: %w", err)
+	}
+	if folderID.Valid {
+		fid := int(folderID.Int64)
+		stub.FolderID = &fid
+	}
+	return &stub, nil
+}
@@ -1,13 +1,37 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
 
 	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/lib/pq"
 )
 
+// noteTagNames selects the tag names of the note in the current row of notes.
+const noteTagNames = `
+	ARRAY(
+		SELECT t.name FROM note_tags nt JOIN tags t ON t.id = nt.tag_id
+		WHERE nt.note_id = notes.id ORDER BY lower(t.name)
+	)`
+
+// noteColumns is the select list read by scanNote. Queries using it must
+// select from notes without an alias.
+const noteColumns = `
+	id, title, content, folder_id, user_id, is_template, pinned, position,` + noteTagNames + `,
+	version, created, updated`
+
+// nextNotePosition places a note after the last live note of folder $folder
+// for user $user, both given as parameter placeholders.
+func nextNotePosition(folder, user string) string {
+	return fmt.Sprintf(`(
+		SELECT COALESCE(MAX(other.position) + 1, 0) FROM notes other
+		WHERE other.user_id = %s AND other.folder_id IS NOT DISTINCT FROM %s AND other.deleted_at IS NULL
+	)`, user, folder)
+}
+
 type NoteRepository struct {
 	db *sql.DB
 }
@@ -16,167 +40,291 @@ func NewNoteRepository(db *sql.DB) *NoteRepository {
 	return &NoteRepository{db: db}
 }
 
-func (r *NoteRepository) Create(note *models.NoteCreate, userID string) (*models.Note, error) {
+func (r *NoteRepository) Create(ctx context.Context, note *models.NoteCreate, userID string) (*models.Note, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	created, err := insertNote(ctx, tx, note, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to create note: %w", err)
+	}
+
+	return created, nil
+}
+
+// insertNote creates the note and its tags within tx.
+func insertNote(ctx context.Context, tx *sql.Tx, note *models.NoteCreate, userID string) (*models.Note, error) {
 	query := `
-		INSERT INTO notes (title, content, folder_id, user_id) 
-		VALUES ($1, $2, $3, $4) 
+		INSERT INTO notes (title, content, folder_id, user_id, is_template, pinned, position)
+		VALUES ($1, $2, $3, $4, $5, $6, ` + nextNotePosition("$3::int", "$4") + `)
 		RETURNING id
 	`
-	
+
 	var id int
-	err := r.db.QueryRow(query, note.Title, note.Content, note.FolderID, userID).Scan(&id)
+	err := tx.QueryRowContext(ctx, query, note.Title, note.Content, note.FolderID, userID, note.IsTemplate, note.Pinned).Scan(&id)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create note: %w", err)
 	}
 
-	return r.GetByID(id, userID)
+	if err := replaceNoteTags(ctx, tx, id, note.Tags, userID); err != nil {
+		return nil, err
+	}
+
+	created, err := scanNote(tx.QueryRowContext(ctx, `SELECT `+noteColumns+` FROM notes WHERE id = $1`, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create note: %w", err)
+	}
+	return created, nil
 }
 
-func (r *NoteRepository) GetByID(id int, userID string) (*models.Note, error) {
-	query := `
-		SELECT id, title, content, folder_id, user_id, created, updated 
-		FROM notes 
-		WHERE id = $1 AND user_id = $2
-	`
-	
-	row := r.db.QueryRow(query, id, userID)
-	
-	var note models.Note
-	var folderID sql.NullInt64
-	
-	err := row.Scan(&note.ID, &note.Title, &note.Content, &folderID, &note.UserID, &note.Created, &note.Updated)
+func (r *NoteRepository) GetByID(ctx context.Context, id int, userID string) (*models.Note, error) {
+	query := `SELECT ` + noteColumns + ` FROM notes WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`
+
+	note, err := scanNote(r.db.QueryRowContext(ctx, query, id, userID))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("note not found")
+			return nil, models.ErrNoteNotFound
 		}
 		return nil, fmt.Errorf("failed to get note: %w", err)
 	}
-	
-	if folderID.Valid {
-		fid := int(folderID.Int64)
-		note.FolderID = &fid
+	return note, nil
+}
+
+// GetAllByUser returns all of the user's notes except templates.
+func (r *NoteRepository) GetAllByUser(ctx context.Context, userID string) ([]*models.Note, error) {
+	return r.GetFiltered(ctx, &models.NoteFilter{}, userID)
+}
+
+// GetFiltered returns the user's notes matching filter, pinned notes first
+// and then the most recently updated. Filter tags must be lower case.
+// Templates are left out; GetTemplates lists them.
+func (r *NoteRepository) GetFiltered(ctx context.Context, filter *models.NoteFilter, userID string) ([]*models.Note, error) {
+	query := `
+		SELECT ` + noteColumns + `
+		FROM notes
+		WHERE user_id = $1 AND deleted_at IS NULL AND NOT is_template
+			AND ($2::boolean IS NULL OR pinned = $2)
+			AND cardinality($3::text[]) = (
+				SELECT count(*) FROM note_tags nt JOIN tags t ON t.id = nt.tag_id
+				WHERE nt.note_id = notes.id AND lower(t.name) = ANY($3)
+			)
+		ORDER BY pinned DESC, updated DESC
+	`
+
+	tags := filter.Tags
+	if tags == nil {
+		tags = []string{}
 	}
-	
-	return &note, nil
+	return r.queryNotes(ctx, query, userID, filter.Pinned, pq.Array(tags))
 }
 
-func (r *NoteRepository) GetAllByUser(userID string) ([]*models.Note, error) {
+// GetByFolder returns the notes of a folder (nil for root notes) in the
+// user's order, leaving out templates.
+func (r *NoteRepository) GetByFolder(ctx context.Context, folderID *int, userID string) ([]*models.Note, error) {
 	query := `
-		SELECT id, title, content, folder_id, user_id, created, updated 
-		FROM notes 
-		WHERE user_id = $1 
-		ORDER BY updated DESC
+		SELECT ` + noteColumns + `
+		FROM notes
+		WHERE user_id = $1 AND folder_id IS NOT DISTINCT FROM $2::int AND deleted_at IS NULL AND NOT is_template
+		ORDER BY position ASC, id ASC
 	`
-	
-	rows, err := r.db.Query(query, userID)
+
+	return r.queryNotes(ctx, query, userID, folderID)
+}
+
+// GetTemplates returns the user's notes flagged as templates.
+func (r *NoteRepository) GetTemplates(ctx context.Context, userID string) ([]*models.Note, error) {
+	query := `
+		SELECT ` + noteColumns + `
+		FROM notes
+		WHERE user_id = $1 AND is_template AND deleted_at IS NULL
+		ORDER BY title ASC
+	`
+
+	return r.queryNotes(ctx, query, userID)
+}
+
+func (r *NoteRepository) queryNotes(ctx context.Context, query string, args ...interface{}) ([]*models.Note, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get notes: %w", err)
 	}
 	defer rows.Close()
-	
+
 	notes := make([]*models.Note, 0) // Initialize as empty slice instead of nil
-	
 	for rows.Next() {
-		var note models.Note
-		var folderID sql.NullInt64
-		
-		err := rows.Scan(&note.ID, &note.Title, &note.Content, &folderID, &note.UserID, &note.Created, &note.Updated)
+		note, err := scanNote(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan note: %w", err)
 		}
-		
-		if folderID.Valid {
-			fid := int(folderID.Int64)
-			note.FolderID = &fid
+		notes = append(notes, note)
+	}
+
+	return notes, rows.Err()
+}
+
+// Update overwrites the note and bumps its version. When expectedVersion is
+// set the write only happens if the stored version still matches it. A note
+// moved to another folder goes to the end of that folder. The note returned
+// is the row this write produced.
+func (r *NoteRepository) Update(ctx context.Context, id int, note *models.NoteUpdate, userID string, expectedVersion *int) (*models.Note, error) {
+	query := `
+		UPDATE notes
+		SET title = $1, content = $2, folder_id = $3, updated = $4, version = version + 1,
+			is_template = COALESCE($8, is_template), pinned = COALESCE($9, pinned),
+			position = CASE WHEN folder_id IS DISTINCT FROM $3::int
+				THEN ` + nextNotePosition("$3::int", "$6") + ` ELSE position END
+		WHERE id = $5 AND user_id = $6 AND deleted_at IS NULL AND ($7::int IS NULL OR version = $7)
+		RETURNING ` + noteColumns
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	updated, err := scanNote(tx.QueryRowContext(ctx, query, note.Title, note.Content, note.FolderID, time.Now(), id, userID, expectedVersion, note.IsTemplate, note.Pinned))
+	if err == sql.ErrNoRows {
+		if _, err := r.GetByID(ctx, id, userID); err != nil {
+			return nil, err
 		}
-		
-		notes = append(notes, &note)
-	}
-	
-	return notes, nil
-}
-
-func (r *NoteRepository) GetByFolder(folderID *int, userID string) ([]*models.Note, error) {
-	var query string
-	var args []interface{}
-	
-	if folderID == nil {
-		query = `
-			SELECT id, title, content, folder_id, user_id, created, updated 
-			FROM notes 
-			WHERE user_id = $1 AND folder_id IS NULL 
-			ORDER BY updated DESC
-		`
-		args = []interface{}{userID}
-	} else {
-		query = `
-			SELECT id, title, content, folder_id, user_id, created, updated 
-			FROM notes 
-			WHERE user_id = $1 AND folder_id = $2 
-			ORDER BY updated DESC
-		`
-		args = []interface{}{userID, *folderID}
-	}
-	
-	rows, err := r.db.Query(query, args...)
+		return nil, models.ErrVersionConflict
+	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to get notes by folder: %w", err)
+		return nil, fmt.Errorf("failed to update note: %w", err)
 	}
-	defer rows.Close()
-	
-	notes := make([]*models.Note, 0) // Initialize as empty slice instead of nil
-	
-	for rows.Next() {
-		var note models.Note
-		var folderIDNull sql.NullInt64
-		
-		err := rows.Scan(&note.ID, &note.Title, &note.Content, &folderIDNull, &note.UserID, &note.Created, &note.Updated)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan note: %w", err)
+
+	if note.Tags != nil {
+		if err := replaceNoteTags(ctx, tx, id, *note.Tags, userID); err != nil {
+			return nil, err
 		}
-		
-		if folderIDNull.Valid {
-			fid := int(folderIDNull.Int64)
-			note.FolderID = &fid
+		// The row is locked by the update, so this reads the tags of this write.
+		err := tx.QueryRowContext(ctx, `SELECT `+noteTagNames+` FROM notes WHERE id = $1`, id).Scan(pq.Array(&updated.Tags))
+		if err != nil {
+			return nil, fmt.Errorf("failed to get note tags: %w", err)
 		}
-		
-		notes = append(notes, &note)
 	}
-	
-	return notes, nil
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to update note: %w", err)
+	}
+
+	return updated, nil
 }
 
-func (r *NoteRepository) Update(id int, note *models.NoteUpdate, userID string) (*models.Note, error) {
+// UpdateContent replaces only the note content and bumps its version.
+func (r *NoteRepository) UpdateContent(ctx context.Context, id int, content string, userID string) (*models.Note, error) {
 	query := `
-		UPDATE notes 
-		SET title = $1, content = $2, folder_id = $3, updated = $4 
-		WHERE id = $5 AND user_id = $6
-	`
-	
-	_, err := r.db.Exec(query, note.Title, note.Content, note.FolderID, time.Now(), id, userID)
+		UPDATE notes SET content = $1, updated = $2, version = version + 1
+		WHERE id = $3 AND user_id = $4 AND deleted_at IS NULL
+		RETURNING ` + noteColumns
+
+	note, err := scanNote(r.db.QueryRowContext(ctx, query, content, time.Now(), id, userID))
+	if err == sql.ErrNoRows {
+		return nil, models.ErrNoteNotFound
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to update note: %w", err)
 	}
-	
-	return r.GetByID(id, userID)
+
+	return note, nil
+}
+
+// SetPinned pins or unpins the note. Pinning is not an edit of the note, so
+// the version is left alone.
+func (r *NoteRepository) SetPinned(ctx context.Context, id int, pinned bool, userID string) (*models.Note, error) {
+	query := `UPDATE notes SET pinned = $1 WHERE id = $2 AND user_id = $3 AND deleted_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, pinned, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pin note: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return nil, models.ErrNoteNotFound
+	}
+
+	return r.GetByID(ctx, id, userID)
+}
+
+// MarkIndexed records that the links and tasks of version of the note are
+// indexed.
+func (r *NoteRepository) MarkIndexed(ctx context.Context, id int, version int) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE notes SET indexed_version = $1 WHERE id = $2`, version, id)
+	if err != nil {
+		return fmt.Errorf("failed to mark note indexed: %w", err)
+	}
+	return nil
+}
+
+// GetUnindexed returns up to limit notes, of any user and trashed or not,
+// whose current version has not been indexed, in id order after afterID.
+func (r *NoteRepository) GetUnindexed(ctx context.Context, afterID int, limit int) ([]*models.Note, error) {
+	query := `
+		SELECT ` + noteColumns + `
+		FROM notes
+		WHERE id > $1 AND indexed_version IS DISTINCT FROM version
+		ORDER BY id
+		LIMIT $2
+	`
+
+	return r.queryNotes(ctx, query, afterID, limit)
+}
+
+// Reorder renumbers the positions of the notes in folderID to follow ids,
+// which must all be live notes of that folder.
+func (r *NoteRepository) Reorder(ctx context.Context, folderID *int, ids []int, userID string) error {
+	return reorder(ctx, r.db, "notes", "folder_id", "note", folderID, ids, userID)
 }
 
-func (r *NoteRepository) Delete(id int, userID string) error {
-	query := `DELETE FROM notes WHERE id = $1 AND user_id = $2`
-	
-	result, err := r.db.Exec(query, id, userID)
+// Delete moves the note to the trash. It is removed for good by the trash
+// purge once the retention period has passed.
+func (r *NoteRepository) Delete(ctx context.Context, id int, userID string) error {
+	query := `UPDATE notes SET deleted_at = $1 WHERE id = $2 AND user_id = $3 AND deleted_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), id, userID)
 	if err != nil {
 		return fmt.Errorf("failed to delete note: %w", err)
 	}
-	
+
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		return fmt.Errorf("failed to get affected rows: %w", err)
 	}
-	
+
 	if rowsAffected == 0 {
-		return fmt.Errorf("note not found")
+		return models.ErrNoteNotFound
 	}
-	
+
 	return nil
 }
+
+// scanNote reads a row selected with noteColumns, followed by any extra
+// destinations.
+func scanNote(row rowScanner, extra ...interface{}) (*models.Note, error) {
+	var note models.Note
+	var folderID sql.NullInt64
+
+	dest := []interface{}{
+		&note.ID, &note.Title, &note.Content, &folderID, &note.UserID, &note.IsTemplate,
+		&note.Pinned, &note.Position, pq.Array(&note.Tags), &note.Version, &note.Created, &note.Updated,
+	}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+
+	if folderID.Valid {
+		fid := int(folderID.Int64)
+		note.FolderID = &fid
+	}
+	return &note, nil
+}
@@ -0,0 +1,134 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/lib/pq"
+)
+
+type NoteTaskRepository struct {
+	db *sql.DB
+}
+
+func NewNoteTaskRepository(db *sql.DB) *NoteTaskRepository {
+	return &NoteTaskRepository{db: db}
+}
+
+// ReplaceTasks swaps the indexed tasks of a note for tasks. Tasks keep their
+// ID for as long as their node path does not change.
+func (r *NoteTaskRepository) ReplaceTasks(ctx context.Context, noteID int, tasks []models.NoteTask) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	paths := make([]string, len(tasks))
+	for i, task := range tasks {
+		paths[i] = task.Path
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM note_tasks WHERE note_id = $1 AND path <> ALL($2::text[])`, noteID, pq.Array(paths))
+	if err != nil {
+		return fmt.Errorf("failed to clear note tasks: %w", err)
+	}
+
+	query := `
+		INSERT INTO note_tasks (note_id, path, position, text, checked)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (note_id, path) DO UPDATE
+		SET position = EXCLUDED.position, text = EXCLUDED.text, checked = EXCLUDED.checked
+	`
+	for i, task := range tasks {
+		_, err := tx.ExecContext(ctx, query, noteID, task.Path, i, task.Text, task.Checked)
+		if err != nil {
+			return fmt.Errorf("failed to save note task: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save note tasks: %w", err)
+	}
+	return nil
+}
+
+// GetTasks returns the tasks of the user's live notes, most recently updated
+// note first, leaving out templates. A nil checked returns both open and
+// done tasks.
+func (r *NoteTaskRepository) GetTasks(ctx context.Context, checked *bool, userID string) ([]*models.NoteTask, error) {
+	query := `
+		SELECT t.id, t.note_id, n.title, t.path, t.text, t.checked, n.updated
+		FROM note_tasks t
+		JOIN notes n ON n.id = t.note_id
+		WHERE n.user_id = $1 AND n.deleted_at IS NULL AND NOT n.is_template
+			AND ($2::boolean IS NULL OR t.checked = $2)
+		ORDER BY n.updated DESC, t.note_id, t.position
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID, checked)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tasks: %w", err)
+	}
+	defer rows.Close()
+
+	tasks := make([]*models.NoteTask, 0)
+	for rows.Next() {
+		task, err := scanNoteTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, rows.Err()
+}
+
+func (r *NoteTaskRepository) GetByID(ctx context.Context, id int, userID string) (*models.NoteTask, error) {
+	query := `
+		SELECT t.id, t.note_id, n.title, t.path, t.text, t.checked, n.updated
+		FROM note_tasks t
+		JOIN notes n ON n.id = t.note_id
+		WHERE t.id = $1 AND n.user_id = $2 AND n.deleted_at IS NULL
+	`
+
+	task, err := scanNoteTask(r.db.QueryRowContext(ctx, query, id, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("task not found")
+		}
+		return nil, err
+	}
+	return task, nil
+}
+
+// GetByPath returns the task indexed at path in the note.
+func (r *NoteTaskRepository) GetByPath(ctx context.Context, noteID int, path string, userID string) (*models.NoteTask, error) {
+	query := `
+		SELECT t.id, t.note_id, n.title, t.path, t.text, t.checked, n.updated
+		FROM note_tasks t
+		JOIN notes n ON n.id = t.note_id
+		WHERE t.note_id = $1 AND t.path = $2 AND n.user_id = $3 AND n.deleted_at IS NULL
+	`
+
+	task, err := scanNoteTask(r.db.QueryRowContext(ctx, query, noteID, path, userID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("task not found")
+		}
+		return nil, err
+	}
+	return task, nil
+}
+
+func scanNoteTask(row rowScanner) (*models.NoteTask, error) {
+	var task models.NoteTask
+	err := row.Scan(&task.ID, &task.NoteID, &task.NoteTitle, &task.Path, &task.Text, &task.Checked, &task.Updated)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan task: %w", err)
+	}
+	return &task, nil
+}
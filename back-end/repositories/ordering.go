@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// reorder renumbers the position column of the live rows of table under
+// parentID so that ids come first, in the given order, followed by the
+// remaining rows in their current order. Every id must be a live row of the
+// user under parentID.
+func reorder(ctx context.Context, db *sql.DB, table, parentColumn, label string, parentID *int, ids []int, userID string) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`
+		SELECT id FROM %s
+		WHERE user_id = $1 AND %s IS NOT DISTINCT FROM $2::int AND deleted_at IS NULL
+		ORDER BY position, id
+		FOR UPDATE
+	`, table, parentColumn), userID, parentID)
+	if err != nil {
+		return fmt.Errorf("failed to get %ss: %w", label, err)
+	}
+	var current []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan %s: %w", label, err)
+		}
+		current = append(current, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get %ss: %w", label, err)
+	}
+
+	siblings := make(map[int]bool, len(current))
+	for _, id := range current {
+		siblings[id] = true
+	}
+	listed := make(map[int]bool, len(ids))
+	order := make([]int64, 0, len(current))
+	for _, id := range ids {
+		if !siblings[id] {
+			return fmt.Errorf("%s %d is not in this folder", label, id)
+		}
+		if listed[id] {
+			return fmt.Errorf("%s %d is listed more than once", label, id)
+		}
+		listed[id] = true
+		order = append(order, int64(id))
+	}
+	for _, id := range current {
+		if !listed[id] {
+			order = append(order, int64(id))
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`
+		UPDATE %s SET position = o.position - 1
+		FROM unnest($1::int[]) WITH ORDINALITY AS o(id, position)
+		WHERE %s.id = o.id
+	`, table, table), pq.Array(order))
+	if err != nil {
+		return fmt.Errorf("failed to reorder %ss: %w", label, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to reorder %ss: %w", label, err)
+	}
+	return nil
+}
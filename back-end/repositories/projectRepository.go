@@ -1,7 +1,9 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
+	"time"
 
 	"github.com/RiadMefti/TimeTracker/back-end/models"
 )
@@ -16,9 +18,9 @@ func NewProjectRepository(db *sql.DB) *ProjectRepository {
 	}
 }
 
-func (r *ProjectRepository) GetUserProjects(userId string) ([]models.Project, error) {
+func (r *ProjectRepository) GetUserProjects(ctx context.Context, userId string) ([]models.Project, error) {
 
-	rows, err := r.db.Query("SELECT  id, name, description, color FROM projects WHERE user_id =($1)", userId)
+	rows, err := r.db.QueryContext(ctx, "SELECT  id, name, description, color, version FROM projects WHERE user_id =($1) AND deleted_at IS NULL", userId)
 	if err != nil {
 		return nil, err
 	}
@@ -30,7 +32,7 @@ func (r *ProjectRepository) GetUserProjects(userId string) ([]models.Project, er
 	for rows.Next() {
 		var project models.Project
 
-		err := rows.Scan(&project.ID, &project.Name, &project.Description, &project.Color)
+		err := rows.Scan(&project.ID, &project.Name, &project.Description, &project.Color, &project.Version)
 		if err != nil {
 			return nil, err
 		}
@@ -52,35 +54,74 @@ func (r *ProjectRepository) GetUserProjects(userId string) ([]models.Project, er
 
 }
 
-func (r *ProjectRepository) CreateUserProject(projectToCreate models.ProjectCreate, userID string) ([]models.Project, error) {
-	_, err := r.db.Exec(
-		"INSERT INTO projects (name, description, color, user_id) VALUES ($1, $2, $3, $4)",
+func (r *ProjectRepository) CreateUserProject(ctx context.Context, projectToCreate models.ProjectCreate, userID string) (models.Project, error) {
+	var project models.Project
+	err := r.db.QueryRowContext(ctx,
+		"INSERT INTO projects (name, description, color, user_id) VALUES ($1, $2, $3, $4) RETURNING id, name, description, color, version",
 		projectToCreate.Name, projectToCreate.Description, projectToCreate.Color, userID,
-	)
-	if err != nil {
-		return nil, err
+	).Scan(&project.ID, &project.Name, &project.Description, &project.Color, &project.Version)
+	return project, err
+}
+
+func (r *ProjectRepository) GetUserProject(ctx context.Context, projectID int, userID string) (models.Project, error) {
+	var project models.Project
+	err := r.db.QueryRowContext(ctx,
+		"SELECT id, name, description, color, version FROM projects WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL",
+		projectID, userID,
+	).Scan(&project.ID, &project.Name, &project.Description, &project.Color, &project.Version)
+	return project, err
+}
+
+// UpdateUserProject overwrites the project and bumps its version, returning
+// the row this write produced. When expectedVersion is set the write only
+// happens if the stored version still matches it.
+func (r *ProjectRepository) UpdateUserProject(ctx context.Context, projectToUpdate models.Project, userID string, expectedVersion *int) (models.Project, error) {
+	var project models.Project
+	err := r.db.QueryRowContext(ctx,
+		"UPDATE projects SET name = $1, description = $2, color = $3, version = version + 1 WHERE id = $4 AND user_id = $5 AND deleted_at IS NULL AND ($6::int IS NULL OR version = $6) RETURNING id, name, description, color, version",
+		projectToUpdate.Name, projectToUpdate.Description, projectToUpdate.Color, projectToUpdate.ID, userID, expectedVersion,
+	).Scan(&project.ID, &project.Name, &project.Description, &project.Color, &project.Version)
+	if err == sql.ErrNoRows {
+		if _, err := r.GetUserProject(ctx, projectToUpdate.ID, userID); err != nil {
+			return models.Project{}, err
+		}
+		return models.Project{}, models.ErrVersionConflict
 	}
-	return r.GetUserProjects(userID)
+	return project, err
 }
 
-func (r *ProjectRepository) UpdateUserProject(projectToUpdate models.Project, userID string) ([]models.Project, error) {
-	_, err := r.db.Exec(
-		"UPDATE projects SET name = $1, description = $2, color = $3 WHERE id = $4 AND user_id = $5",
-		projectToUpdate.Name, projectToUpdate.Description, projectToUpdate.Color, projectToUpdate.ID, userID,
-	)
+// DeleteUserProject moves the project and its time entries to the trash,
+// stamped with the same deleted_at so they are restored together.
+func (r *ProjectRepository) DeleteUserProject(ctx context.Context, projectId string, userID string) ([]models.Project, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
-	return r.GetUserProjects(userID)
-}
-func (r *ProjectRepository) DeleteUserProject(projectId string, userID string) ([]models.Project, error) {
+	defer tx.Rollback()
 
-	_, err := r.db.Exec(
-		"DELETE FROM projects WHERE id = ($1) AND user_id = ($2)",
-		projectId, userID,
+	deletedAt := time.Now()
+	result, err := tx.ExecContext(ctx,
+		"UPDATE projects SET deleted_at = $1 WHERE id = ($2) AND user_id = ($3) AND deleted_at IS NULL",
+		deletedAt, projectId, userID,
 	)
 	if err != nil {
 		return nil, err
 	}
-	return r.GetUserProjects(userID)
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if rowsAffected > 0 {
+		_, err = tx.ExecContext(ctx,
+			"UPDATE times SET deleted_at = $1 WHERE project_id = ($2) AND user_id = ($3) AND deleted_at IS NULL",
+			deletedAt, projectId, userID,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return r.GetUserProjects(ctx, userID)
 }
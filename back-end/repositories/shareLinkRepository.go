@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+)
+
+type ShareLinkRepository struct {
+	db *sql.DB
+}
+
+func NewShareLinkRepository(db *sql.DB) *ShareLinkRepository {
+	return &ShareLinkRepository{db: db}
+}
+
+const shareLinkColumns = `id, prefix, note_id, folder_id, user_id, COALESCE(password_hash, ''), expires_at, created`
+
+func (r *ShareLinkRepository) Create(ctx context.Context, link *models.ShareLink, hash string) (*models.ShareLink, error) {
+	query := `
+		INSERT INTO share_links (token_hash, prefix, note_id, folder_id, user_id, password_hash, expires_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7)
+		RETURNING ` + shareLinkColumns
+
+	created, err := scanShareLink(r.db.QueryRowContext(ctx, query,
+		hash, link.Prefix, link.NoteID, link.FolderID, link.UserID, link.PasswordHash, link.ExpiresAt,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create share link: %w", err)
+	}
+	return created, nil
+}
+
+func (r *ShareLinkRepository) GetByUser(ctx context.Context, userID string) ([]*models.ShareLink, error) {
+	query := `SELECT ` + shareLinkColumns + ` FROM share_links WHERE user_id = $1 ORDER BY created DESC, id DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share links: %w", err)
+	}
+	defer rows.Close()
+
+	links := make([]*models.ShareLink, 0)
+	for rows.Next() {
+		link, err := scanShareLink(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan share link: %w", err)
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// GetByHash returns the share link whose token has the given hash.
+func (r *ShareLinkRepository) GetByHash(ctx context.Context, hash string) (*models.ShareLink, error) {
+	query := `SELECT ` + shareLinkColumns + ` FROM share_links WHERE token_hash = $1`
+
+	link, err := scanShareLink(r.db.QueryRowContext(ctx, query, hash))
+	if err == sql.ErrNoRows {
+		return nil, models.ErrShareNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share link: %w", err)
+	}
+	return link, nil
+}
+
+func (r *ShareLinkRepository) Delete(ctx context.Context, id int, userID string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM share_links WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete share link: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return models.ErrShareNotFound
+	}
+	return nil
+}
+
+func scanShareLink(row rowScanner) (*models.ShareLink, error) {
+	var link models.ShareLink
+	var noteID, folderID sql.NullInt64
+	var expiresAt sql.NullTime
+	err := row.Scan(
+		&link.ID,
+		&link.Prefix,
+		&noteID,
+		&folderID,
+		&link.UserID,
+		&link.PasswordHash,
+		&expiresAt,
+		&link.Created,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if noteID.Valid {
+		id := int(noteID.Int64)
+		link.NoteID = &id
+	}
+	if folderID.Valid {
+		id := int(folderID.Int64)
+		link.FolderID = &id
+	}
+	if expiresAt.Valid {
+		link.ExpiresAt = &expiresAt.Time
+	}
+	link.HasPassword = link.PasswordHash != ""
+	return &link, nil
+}
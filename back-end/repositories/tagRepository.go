@@ -0,0 +1,99 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/lib/pq"
+)
+
+type TagRepository struct {
+	db *sql.DB
+}
+
+func NewTagRepository(db *sql.DB) *TagRepository {
+	return &TagRepository{db: db}
+}
+
+// GetAll returns the user's tags with the number of live notes carrying each.
+func (r *TagRepository) GetAll(ctx context.Context, userID string) ([]*models.Tag, error) {
+	query := `
+		SELECT t.id, t.name, t.created, count(n.id)
+		FROM tags t
+		LEFT JOIN note_tags nt ON nt.tag_id = t.id
+		LEFT JOIN notes n ON n.id = nt.note_id AND n.deleted_at IS NULL
+		WHERE t.user_id = $1
+		GROUP BY t.id
+		ORDER BY lower(t.name)
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+	defer rows.Close()
+
+	tags := make([]*models.Tag, 0)
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.ID, &tag.Name, &tag.Created, &tag.NoteCount); err != nil {
+			return nil, fmt.Errorf("failed to scan tag: %w", err)
+		}
+		tags = append(tags, &tag)
+	}
+	return tags, rows.Err()
+}
+
+// Delete removes the tag and takes it off every note.
+func (r *TagRepository) Delete(ctx context.Context, id int, userID string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM tags WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("tag not found")
+	}
+	return nil
+}
+
+// replaceNoteTags sets the note's tags to names, creating the tags the user
+// does not have yet. Names match existing tags case-insensitively, so the
+// first spelling used for a tag is the one kept.
+func replaceNoteTags(ctx context.Context, tx *sql.Tx, noteID int, names []string, userID string) error {
+	lower := make([]string, len(names))
+	for i, name := range names {
+		lower[i] = strings.ToLower(name)
+	}
+
+	if len(names) > 0 {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO tags (user_id, name) SELECT $1, unnest($2::text[])
+			ON CONFLICT (user_id, lower(name)) DO NOTHING
+		`, userID, pq.Array(names))
+		if err != nil {
+			return fmt.Errorf("failed to create tags: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM note_tags WHERE note_id = $1`, noteID); err != nil {
+		return fmt.Errorf("failed to update note tags: %w", err)
+	}
+
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO note_tags (note_id, tag_id)
+		SELECT $1, id FROM tags WHERE user_id = $2 AND lower(name) = ANY($3::text[])
+	`, noteID, userID, pq.Array(lower))
+	if err != nil {
+		return fmt.Errorf("failed to update note tags: %w", err)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 
 	"github.com/RiadMefti/TimeTracker/back-end/models"
@@ -14,9 +15,9 @@ func NewTimeBoxEntryRepository(db *sql.DB) *TimeBoxEntryRepository {
 	return &TimeBoxEntryRepository{db: db}
 }
 
-func (r *TimeBoxEntryRepository) GetUserTimeBoxEntries(userID string) ([]models.TimeBoxEntry, error) {
-	rows, err := r.db.Query(
-		`SELECT id, description, project_id, start_date, end_date 
+func (r *TimeBoxEntryRepository) GetUserTimeBoxEntries(ctx context.Context, userID string) ([]models.TimeBoxEntry, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, description, project_id, start_date, end_date, version 
          FROM timeBoxes WHERE user_id = $1`, userID)
 	if err != nil {
 		return nil, err
@@ -26,7 +27,7 @@ func (r *TimeBoxEntryRepository) GetUserTimeBoxEntries(userID string) ([]models.
 	var entries []models.TimeBoxEntry
 	for rows.Next() {
 		var entry models.TimeBoxEntry
-		err := rows.Scan(&entry.ID, &entry.Description, &entry.ProjectID, &entry.StartDate, &entry.EndDate)
+		err := rows.Scan(&entry.ID, &entry.Description, &entry.ProjectID, &entry.StartDate, &entry.EndDate, &entry.Version)
 		if err != nil {
 			return nil, err
 		}
@@ -44,46 +45,69 @@ func (r *TimeBoxEntryRepository) GetUserTimeBoxEntries(userID string) ([]models.
 	return entries, nil
 }
 
-func (r *TimeBoxEntryRepository) CreateTimeBoxEntry(entry models.TimeBoxEntryCreate, userID string) ([]models.TimeBoxEntry, error) {
-	_, err := r.db.Exec(
+func (r *TimeBoxEntryRepository) CreateTimeBoxEntry(ctx context.Context, entry models.TimeBoxEntryCreate, userID string) (models.TimeBoxEntry, error) {
+	var created models.TimeBoxEntry
+	err := r.db.QueryRowContext(ctx,
 		`INSERT INTO timeBoxes (description, project_id, start_date, end_date, user_id)
-         VALUES ($1, $2, $3, $4, $5)`,
+         VALUES ($1, $2, $3, $4, $5)
+         RETURNING id, description, project_id, start_date, end_date, version`,
 		entry.Description, entry.ProjectID, entry.StartDate, entry.EndDate, userID,
-	)
-	if err != nil {
-		return nil, err
-	}
-	return r.GetUserTimeBoxEntries(userID)
+	).Scan(&created.ID, &created.Description, &created.ProjectID, &created.StartDate, &created.EndDate, &created.Version)
+	return created, err
 }
 
-func (r *TimeBoxEntryRepository) UpdateTimeBoxEntry(entry models.TimeBoxEntry, userID string) ([]models.TimeBoxEntry, error) {
-	_, err := r.db.Exec(
-		`UPDATE timeBoxes SET description = $1, project_id = $2, start_date = $3, end_date = $4
-         WHERE id = $5 AND user_id = $6`,
-		entry.Description, entry.ProjectID, entry.StartDate, entry.EndDate, entry.ID, userID,
-	)
-	if err != nil {
-		return nil, err
+func (r *TimeBoxEntryRepository) GetTimeBoxEntry(ctx context.Context, timeBoxEntryID int, userID string) (models.TimeBoxEntry, error) {
+	var entry models.TimeBoxEntry
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, description, project_id, start_date, end_date, version
+         FROM timeBoxes WHERE id = $1 AND user_id = $2`, timeBoxEntryID, userID,
+	).Scan(&entry.ID, &entry.Description, &entry.ProjectID, &entry.StartDate, &entry.EndDate, &entry.Version)
+	return entry, err
+}
+
+// UpdateTimeBoxEntry overwrites the time box and bumps its version, returning
+// the row this write produced. When expectedVersion is set the write only
+// happens if the stored version still matches it.
+func (r *TimeBoxEntryRepository) UpdateTimeBoxEntry(ctx context.Context, entry models.TimeBoxEntry, userID string, expectedVersion *int) (models.TimeBoxEntry, error) {
+	var updated models.TimeBoxEntry
+	err := r.db.QueryRowContext(ctx,
+		`UPDATE timeBoxes SET description = $1, project_id = $2, start_date = $3, end_date = $4, version = version + 1
+         WHERE id = $5 AND user_id = $6 AND ($7::int IS NULL OR version = $7)
+         RETURNING id, description, project_id, start_date, end_date, version`,
+		entry.Description, entry.ProjectID, entry.StartDate, entry.EndDate, entry.ID, userID, expectedVersion,
+	).Scan(&updated.ID, &updated.Description, &updated.ProjectID, &updated.StartDate, &updated.EndDate, &updated.Version)
+	if err == sql.ErrNoRows {
+		if _, err := r.GetTimeBoxEntry(ctx, entry.ID, userID); err != nil {
+			return models.TimeBoxEntry{}, err
+		}
+		return models.TimeBoxEntry{}, models.ErrVersionConflict
 	}
-	return r.GetUserTimeBoxEntries(userID)
+	return updated, err
 }
 
-func (r *TimeBoxEntryRepository) DeleteTimeBoxEntry(timeBoxEntryID int, userID string) ([]models.TimeBoxEntry, error) {
-	_, err := r.db.Exec(
+func (r *TimeBoxEntryRepository) DeleteTimeBoxEntry(ctx context.Context, timeBoxEntryID int, userID string) ([]models.TimeBoxEntry, error) {
+	_, err := r.db.ExecContext(ctx,
 		`DELETE FROM timeBoxes WHERE id = $1 AND user_id = $2`, timeBoxEntryID, userID,
 	)
 	if err != nil {
 		return nil, err
 	}
-	return r.GetUserTimeBoxEntries(userID)
+	return r.GetUserTimeBoxEntries(ctx, userID)
 }
 
-func (r *TimeBoxEntryRepository) AssignProjectToTimeBox(timeBoxEntryID int, projectID *int, userID string) ([]models.TimeBoxEntry, error) {
-	_, err := r.db.Exec(
-		`UPDATE timeBoxes SET project_id = $1 WHERE id = $2 AND user_id = $3`, projectID, timeBoxEntryID, userID,
-	)
+// AssignProjectToTimeBox sets the project of the time box and returns the
+// updated time box, or nil when the user has no such time box.
+func (r *TimeBoxEntryRepository) AssignProjectToTimeBox(ctx context.Context, timeBoxEntryID int, projectID *int, userID string) (*models.TimeBoxEntry, error) {
+	var updated models.TimeBoxEntry
+	err := r.db.QueryRowContext(ctx,
+		`UPDATE timeBoxes SET project_id = $1, version = version + 1 WHERE id = $2 AND user_id = $3
+         RETURNING id, description, project_id, start_date, end_date, version`, projectID, timeBoxEntryID, userID,
+	).Scan(&updated.ID, &updated.Description, &updated.ProjectID, &updated.StartDate, &updated.EndDate, &updated.Version)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	return r.GetUserTimeBoxEntries(userID)
+	return &updated, nil
 }
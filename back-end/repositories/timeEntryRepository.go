@@ -1,7 +1,9 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
+	"time"
 
 	"github.com/RiadMefti/TimeTracker/back-end/models"
 )
@@ -14,10 +16,10 @@ func NewTimeEntryRepository(db *sql.DB) *TimeEntryRepository {
 	return &TimeEntryRepository{db: db}
 }
 
-func (r *TimeEntryRepository) GetUserTimeEntries(userID string) ([]models.TimeEntry, error) {
-	rows, err := r.db.Query(
-		`SELECT id, description, project_id, start_date, end_date 
-         FROM times WHERE user_id = $1`, userID)
+func (r *TimeEntryRepository) GetUserTimeEntries(ctx context.Context, userID string) ([]models.TimeEntry, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT id, description, project_id, start_date, end_date, version 
+         FROM times WHERE user_id = $1 AND deleted_at IS NULL`, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -26,7 +28,7 @@ func (r *TimeEntryRepository) GetUserTimeEntries(userID string) ([]models.TimeEn
 	var entries []models.TimeEntry
 	for rows.Next() {
 		var entry models.TimeEntry
-		err := rows.Scan(&entry.ID, &entry.Description, &entry.ProjectID, &entry.StartDate, &entry.EndDate)
+		err := rows.Scan(&entry.ID, &entry.Description, &entry.ProjectID, &entry.StartDate, &entry.EndDate, &entry.Version)
 		if err != nil {
 			return nil, err
 		}
@@ -44,46 +46,70 @@ func (r *TimeEntryRepository) GetUserTimeEntries(userID string) ([]models.TimeEn
 	return entries, nil
 }
 
-func (r *TimeEntryRepository) CreateTimeEntry(entry models.TimeEntryCreate, userID string) ([]models.TimeEntry, error) {
-	_, err := r.db.Exec(
+func (r *TimeEntryRepository) CreateTimeEntry(ctx context.Context, entry models.TimeEntryCreate, userID string) (models.TimeEntry, error) {
+	var created models.TimeEntry
+	err := r.db.QueryRowContext(ctx,
 		`INSERT INTO times (description, project_id, start_date, end_date, user_id)
-         VALUES ($1, $2, $3, $4, $5)`,
+         VALUES ($1, $2, $3, $4, $5)
+         RETURNING id, description, project_id, start_date, end_date, version`,
 		entry.Description, entry.ProjectID, entry.StartDate, entry.EndDate, userID,
-	)
-	if err != nil {
-		return nil, err
-	}
-	return r.GetUserTimeEntries(userID)
+	).Scan(&created.ID, &created.Description, &created.ProjectID, &created.StartDate, &created.EndDate, &created.Version)
+	return created, err
 }
 
-func (r *TimeEntryRepository) UpdateTimeEntry(entry models.TimeEntry, userID string) ([]models.TimeEntry, error) {
-	_, err := r.db.Exec(
-		`UPDATE times SET description = $1, project_id = $2, start_date = $3, end_date = $4
-         WHERE id = $5 AND user_id = $6`,
-		entry.Description, entry.ProjectID, entry.StartDate, entry.EndDate, entry.ID, userID,
-	)
-	if err != nil {
-		return nil, err
+func (r *TimeEntryRepository) GetTimeEntry(ctx context.Context, timeEntryID int, userID string) (models.TimeEntry, error) {
+	var entry models.TimeEntry
+	err := r.db.QueryRowContext(ctx,
+		`SELECT id, description, project_id, start_date, end_date, version
+         FROM times WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`, timeEntryID, userID,
+	).Scan(&entry.ID, &entry.Description, &entry.ProjectID, &entry.StartDate, &entry.EndDate, &entry.Version)
+	return entry, err
+}
+
+// UpdateTimeEntry overwrites the entry and bumps its version, returning the
+// row this write produced. When expectedVersion is set the write only happens
+// if the stored version still matches it.
+func (r *TimeEntryRepository) UpdateTimeEntry(ctx context.Context, entry models.TimeEntry, userID string, expectedVersion *int) (models.TimeEntry, error) {
+	var updated models.TimeEntry
+	err := r.db.QueryRowContext(ctx,
+		`UPDATE times SET description = $1, project_id = $2, start_date = $3, end_date = $4, version = version + 1
+         WHERE id = $5 AND user_id = $6 AND deleted_at IS NULL AND ($7::int IS NULL OR version = $7)
+         RETURNING id, description, project_id, start_date, end_date, version`,
+		entry.Description, entry.ProjectID, entry.StartDate, entry.EndDate, entry.ID, userID, expectedVersion,
+	).Scan(&updated.ID, &updated.Description, &updated.ProjectID, &updated.StartDate, &updated.EndDate, &updated.Version)
+	if err == sql.ErrNoRows {
+		if _, err := r.GetTimeEntry(ctx, entry.ID, userID); err != nil {
+			return models.TimeEntry{}, err
+		}
+		return models.TimeEntry{}, models.ErrVersionConflict
 	}
-	return r.GetUserTimeEntries(userID)
+	return updated, err
 }
 
-func (r *TimeEntryRepository) DeleteTimeEntry(timeEntryID int, userID string) ([]models.TimeEntry, error) {
-	_, err := r.db.Exec(
-		`DELETE FROM times WHERE id = $1 AND user_id = $2`, timeEntryID, userID,
+// DeleteTimeEntry moves the entry to the trash.
+func (r *TimeEntryRepository) DeleteTimeEntry(ctx context.Context, timeEntryID int, userID string) ([]models.TimeEntry, error) {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE times SET deleted_at = $1 WHERE id = $2 AND user_id = $3 AND deleted_at IS NULL`, time.Now(), timeEntryID, userID,
 	)
 	if err != nil {
 		return nil, err
 	}
-	return r.GetUserTimeEntries(userID)
+	return r.GetUserTimeEntries(ctx, userID)
 }
 
-func (r *TimeEntryRepository) AssignProjectToTime(timeEntryID int, projectID *int, userID string) ([]models.TimeEntry, error) {
-	_, err := r.db.Exec(
-		`UPDATE times SET project_id = $1 WHERE id = $2 AND user_id = $3`, projectID, timeEntryID, userID,
-	)
+// AssignProjectToTime sets the project of the entry and returns the updated
+// entry, or nil when the user has no such entry.
+func (r *TimeEntryRepository) AssignProjectToTime(ctx context.Context, timeEntryID int, projectID *int, userID string) (*models.TimeEntry, error) {
+	var updated models.TimeEntry
+	err := r.db.QueryRowContext(ctx,
+		`UPDATE times SET project_id = $1, version = version + 1 WHERE id = $2 AND user_id = $3 AND deleted_at IS NULL
+         RETURNING id, description, project_id, start_date, end_date, version`, projectID, timeEntryID, userID,
+	).Scan(&updated.ID, &updated.Description, &updated.ProjectID, &updated.StartDate, &updated.EndDate, &updated.Version)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	return r.GetUserTimeEntries(userID)
+	return &updated, nil
 }
@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+)
+
+// TimerRepository stores the running timer of each user. A user has at most
+// one.
+type TimerRepository struct {
+	db *sql.DB
+}
+
+func NewTimerRepository(db *sql.DB) *TimerRepository {
+	return &TimerRepository{db: db}
+}
+
+// Get returns the user's running timer, or nil when none is running.
+func (r *TimerRepository) Get(ctx context.Context, userID string) (*models.RunningTimer, error) {
+	var timer models.RunningTimer
+	err := r.db.QueryRowContext(ctx,
+		`SELECT description, project_id, started_at FROM running_timers WHERE user_id = $1`, userID,
+	).Scan(&timer.Description, &timer.ProjectID, &timer.StartDate)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get timer: %w", err)
+	}
+	return &timer, nil
+}
+
+func (r *TimerRepository) Start(ctx context.Context, timer *models.RunningTimer, userID string) error {
+	result, err := r.db.ExecContext(ctx,
+		`INSERT INTO running_timers (user_id, description, project_id, started_at)
+         VALUES ($1, $2, $3, $4)
+         ON CONFLICT (user_id) DO NOTHING`,
+		userID, timer.Description, timer.ProjectID, timer.StartDate,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to start timer: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return models.ErrTimerRunning
+	}
+	return nil
+}
+
+func (r *TimerRepository) Update(ctx context.Context, timer *models.RunningTimer, userID string) error {
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE running_timers SET description = $1, project_id = $2, started_at = $3 WHERE user_id = $4`,
+		timer.Description, timer.ProjectID, timer.StartDate, userID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update timer: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return models.ErrTimerNotRunning
+	}
+	return nil
+}
+
+// Stop removes the running timer and records it as a time entry ending at
+// end, or at the start of the timer if that is later.
+func (r *TimerRepository) Stop(ctx context.Context, end time.Time, userID string) (models.TimeEntry, error) {
+	var entry models.TimeEntry
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return entry, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		`DELETE FROM running_timers WHERE user_id = $1
+         RETURNING description, project_id, started_at`, userID,
+	).Scan(&entry.Description, &entry.ProjectID, &entry.StartDate)
+	if err == sql.ErrNoRows {
+		return entry, models.ErrTimerNotRunning
+	}
+	if err != nil {
+		return entry, fmt.Errorf("failed to stop timer: %w", err)
+	}
+
+	entry.EndDate = end
+	if entry.EndDate.Before(entry.StartDate) {
+		entry.EndDate = entry.StartDate
+	}
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO times (description, project_id, start_date, end_date, user_id)
+         VALUES ($1, $2, $3, $4, $5)
+         RETURNING id, version`,
+		entry.Description, entry.ProjectID, entry.StartDate, entry.EndDate, userID,
+	).Scan(&entry.ID, &entry.Version)
+	if err != nil {
+		return entry, fmt.Errorf("failed to record time entry: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return entry, fmt.Errorf("failed to stop timer: %w", err)
+	}
+	return entry, nil
+}
@@ -0,0 +1,418 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/lib/pq"
+)
+
+// TrashRepository lists, restores and purges soft-deleted notes, folders,
+// time entries and projects. Rows trashed by the same call share their
+// deleted_at, which is how restores find what belongs together.
+type TrashRepository struct {
+	db *sql.DB
+}
+
+func NewTrashRepository(db *sql.DB) *TrashRepository {
+	return &TrashRepository{db: db}
+}
+
+func (r *TrashRepository) GetTrash(ctx context.Context, userID string) (*models.Trash, error) {
+	trash := &models.Trash{}
+	var err error
+	if trash.Notes, err = r.trashedNotes(ctx, userID); err != nil {
+		return nil, err
+	}
+	if trash.Folders, err = r.trashedFolders(ctx, userID); err != nil {
+		return nil, err
+	}
+	if trash.TimeEntries, err = r.trashedTimeEntries(ctx, userID); err != nil {
+		return nil, err
+	}
+	if trash.Projects, err = r.trashedProjects(ctx, userID); err != nil {
+		return nil, err
+	}
+	return trash, nil
+}
+
+func (r *TrashRepository) trashedNotes(ctx context.Context, userID string) ([]*models.TrashedNote, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT `+noteColumns+`, deleted_at
+		FROM notes
+		WHERE user_id = $1 AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trashed notes: %w", err)
+	}
+	defer rows.Close()
+
+	notes := make([]*models.TrashedNote, 0)
+	for rows.Next() {
+		var deletedAt time.Time
+		note, err := scanNote(rows, &deletedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan note: %w", err)
+		}
+		notes = append(notes, &models.TrashedNote{Note: *note, DeletedAt: deletedAt})
+	}
+	return notes, rows.Err()
+}
+
+func (r *TrashRepository) trashedFolders(ctx context.Context, userID string) ([]*models.TrashedFolder, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, name, parent_id, user_id, position, version, created, updated, deleted_at
+		FROM folders
+		WHERE user_id = $1 AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trashed folders: %w", err)
+	}
+	defer rows.Close()
+
+	folders := make([]*models.TrashedFolder, 0)
+	for rows.Next() {
+		var folder models.TrashedFolder
+		var parentID sql.NullInt64
+		err := rows.Scan(&folder.ID, &folder.Name, &parentID, &folder.UserID, &folder.Position, &folder.Version, &folder.Created, &folder.Updated, &folder.DeletedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan folder: %w", err)
+		}
+		if parentID.Valid {
+			pid := int(parentID.Int64)
+			folder.ParentID = &pid
+		}
+		folders = append(folders, &folder)
+	}
+	return folders, rows.Err()
+}
+
+func (r *TrashRepository) trashedTimeEntries(ctx context.Context, userID string) ([]*models.TrashedTimeEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, description, project_id, start_date, end_date, version, deleted_at
+		FROM times
+		WHERE user_id = $1 AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trashed time entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]*models.TrashedTimeEntry, 0)
+	for rows.Next() {
+		var entry models.TrashedTimeEntry
+		err := rows.Scan(&entry.ID, &entry.Description, &entry.ProjectID, &entry.StartDate, &entry.EndDate, &entry.Version, &entry.DeletedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan time entry: %w", err)
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, rows.Err()
+}
+
+func (r *TrashRepository) trashedProjects(ctx context.Context, userID string) ([]*models.TrashedProject, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, name, description, color, version, deleted_at
+		FROM projects
+		WHERE user_id = $1 AND deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC, id
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trashed projects: %w", err)
+	}
+	defer rows.Close()
+
+	projects := make([]*models.TrashedProject, 0)
+	for rows.Next() {
+		var project models.TrashedProject
+		err := rows.Scan(&project.ID, &project.Name, &project.Description, &project.Color, &project.Version, &project.DeletedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan project: %w", err)
+		}
+		projects = append(projects, &project)
+	}
+	return projects, rows.Err()
+}
+
+// RestoreNote takes the note out of the trash, along with any trashed
+// folders above it.
+func (r *TrashRepository) RestoreNote(ctx context.Context, id int, userID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var folderID sql.NullInt64
+	err = tx.QueryRowContext(ctx, `
+		UPDATE notes SET deleted_at = NULL, version = version + 1
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+		RETURNING folder_id
+	`, id, userID).Scan(&folderID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("note not found in trash")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore note: %w", err)
+	}
+
+	if folderID.Valid {
+		if err := restoreAncestors(ctx, tx, int(folderID.Int64), userID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to restore note: %w", err)
+	}
+	return nil
+}
+
+// RestoreFolder takes the folder out of the trash together with the
+// subfolders and notes that were deleted with it, and any trashed folders
+// above it.
+func (r *TrashRepository) RestoreFolder(ctx context.Context, id int, userID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var parentID sql.NullInt64
+	var deletedAt time.Time
+	err = tx.QueryRowContext(ctx, `
+		SELECT parent_id, deleted_at FROM folders
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+		FOR UPDATE
+	`, id, userID).Scan(&parentID, &deletedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("folder not found in trash")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore folder: %w", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		WITH RECURSIVE tree AS (
+			SELECT id FROM folders WHERE id = $1
+			UNION ALL
+			SELECT f.id FROM folders f JOIN tree t ON f.parent_id = t.id WHERE f.deleted_at = $2
+		)
+		SELECT id FROM tree
+	`, id, deletedAt)
+	if err != nil {
+		return fmt.Errorf("failed to restore folder: %w", err)
+	}
+	var ids []int64
+	for rows.Next() {
+		var folderID int64
+		if err := rows.Scan(&folderID); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to restore folder: %w", err)
+		}
+		ids = append(ids, folderID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to restore folder: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE folders SET deleted_at = NULL, version = version + 1
+		WHERE id = ANY($1) AND user_id = $2
+	`, pq.Array(ids), userID)
+	if err != nil {
+		return fmt.Errorf("failed to restore folder: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE notes SET deleted_at = NULL, version = version + 1
+		WHERE folder_id = ANY($1) AND user_id = $2 AND deleted_at = $3
+	`, pq.Array(ids), userID, deletedAt)
+	if err != nil {
+		return fmt.Errorf("failed to restore folder notes: %w", err)
+	}
+
+	if parentID.Valid {
+		if err := restoreAncestors(ctx, tx, int(parentID.Int64), userID); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to restore folder: %w", err)
+	}
+	return nil
+}
+
+// restoreAncestors untrashes folderID and every folder above it. Their other
+// contents stay in the trash.
+func restoreAncestors(ctx context.Context, tx *sql.Tx, folderID int, userID string) error {
+	_, err := tx.ExecContext(ctx, `
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM folders WHERE id = $1 AND user_id = $2
+			UNION ALL
+			SELECT f.id, f.parent_id FROM folders f JOIN ancestors a ON f.id = a.parent_id
+		)
+		UPDATE folders SET deleted_at = NULL, version = version + 1
+		WHERE id IN (SELECT id FROM ancestors) AND deleted_at IS NOT NULL
+	`, folderID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to restore parent folders: %w", err)
+	}
+	return nil
+}
+
+// RestoreTimeEntry takes the entry out of the trash, and its project too if
+// that was trashed.
+func (r *TrashRepository) RestoreTimeEntry(ctx context.Context, id int, userID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var projectID sql.NullInt64
+	err = tx.QueryRowContext(ctx, `
+		UPDATE times SET deleted_at = NULL, version = version + 1
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+		RETURNING project_id
+	`, id, userID).Scan(&projectID)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("time entry not found in trash")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore time entry: %w", err)
+	}
+
+	if projectID.Valid {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE projects SET deleted_at = NULL, version = version + 1
+			WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+		`, projectID.Int64, userID)
+		if err != nil {
+			return fmt.Errorf("failed to restore project: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to restore time entry: %w", err)
+	}
+	return nil
+}
+
+// RestoreProject takes the project out of the trash together with the time
+// entries that were deleted with it.
+func (r *TrashRepository) RestoreProject(ctx context.Context, id int, userID string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var deletedAt time.Time
+	err = tx.QueryRowContext(ctx, `
+		SELECT deleted_at FROM projects
+		WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+		FOR UPDATE
+	`, id, userID).Scan(&deletedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("project not found in trash")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to restore project: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE projects SET deleted_at = NULL, version = version + 1 WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to restore project: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE times SET deleted_at = NULL, version = version + 1
+		WHERE project_id = $1 AND user_id = $2 AND deleted_at = $3
+	`, id, userID, deletedAt)
+	if err != nil {
+		return fmt.Errorf("failed to restore project time entries: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to restore project: %w", err)
+	}
+	return nil
+}
+
+// purgedFolderHomes maps each folder trashed before $1 to the nearest of its
+// ancestors that is not in the trash, or NULL for the root, by walking up
+// through trashed ancestors.
+const purgedFolderHomes = `
+	WITH RECURSIVE chain (purged_id, ancestor_id) AS (
+		SELECT id, parent_id FROM folders WHERE deleted_at < $1
+		UNION ALL
+		SELECT c.purged_id, f.parent_id
+		FROM chain c JOIN folders f ON f.id = c.ancestor_id AND f.deleted_at IS NOT NULL
+	), homes (purged_id, home_id) AS (
+		SELECT purged_id, ancestor_id FROM chain c
+		WHERE c.ancestor_id IS NULL
+			OR EXISTS (SELECT 1 FROM folders f WHERE f.id = c.ancestor_id AND f.deleted_at IS NULL)
+	)`
+
+// Purge permanently deletes everything trashed before the cutoff, for all
+// users. Rows still pointing at a purged folder or project are moved first
+// so the cascading deletes cannot take them along: time boxes, live time
+// entries and those trashed after the cutoff lose their project, and notes
+// and folders move to the nearest ancestor folder that is not in the trash,
+// or to the root when there is none.
+func (r *TrashRepository) Purge(ctx context.Context, before time.Time) (*models.PurgeResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	detach := []string{
+		`UPDATE times SET project_id = NULL
+		 WHERE (deleted_at IS NULL OR deleted_at >= $1) AND project_id IN (SELECT id FROM projects WHERE deleted_at < $1)`,
+		`UPDATE timeBoxes SET project_id = NULL
+		 WHERE project_id IN (SELECT id FROM projects WHERE deleted_at < $1)`,
+		purgedFolderHomes + `
+		UPDATE notes n SET folder_id = h.home_id FROM homes h
+		WHERE n.deleted_at IS NULL AND n.folder_id = h.purged_id`,
+		purgedFolderHomes + `
+		UPDATE folders f SET parent_id = h.home_id FROM homes h
+		WHERE f.deleted_at IS NULL AND f.parent_id = h.purged_id`,
+	}
+	for _, query := range detach {
+		if _, err := tx.ExecContext(ctx, query, before); err != nil {
+			return nil, fmt.Errorf("failed to purge trash: %w", err)
+		}
+	}
+
+	result := &models.PurgeResult{}
+	for _, item := range []struct {
+		table string
+		count *int64
+	}{
+		{"times", &result.TimeEntries},
+		{"notes", &result.Notes},
+		{"folders", &result.Folders},
+		{"projects", &result.Projects},
+	} {
+		res, err := tx.ExecContext(ctx, `DELETE FROM `+item.table+` WHERE deleted_at < $1`, before)
+		if err != nil {
+			return nil, fmt.Errorf("failed to purge %s: %w", item.table, err)
+		}
+		if *item.count, err = res.RowsAffected(); err != nil {
+			return nil, fmt.Errorf("failed to get affected rows: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to purge trash: %w", err)
+	}
+	return result, nil
+}
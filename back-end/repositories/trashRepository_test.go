@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/db"
+)
+
+// testDB connects to the database named by TEST_DATABASE_URL, a lib/pq
+// connection string, and applies the migrations. Tests that need Postgres
+// are skipped without one.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	conn, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if err := db.Migrate(context.Background(), conn, "up", t.Logf); err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+// Purging a project must not take along what still points at it: time boxes,
+// which are never trashed, and time entries trashed after the cutoff.
+func TestPurgeKeepsRowsOfPurgedProject(t *testing.T) {
+	conn := testDB(t)
+	ctx := context.Background()
+	userID := "purge-test-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	t.Cleanup(func() { conn.Exec(`DELETE FROM users WHERE id = $1`, userID) })
+
+	exec := func(query string, args ...any) int {
+		t.Helper()
+		var id int
+		if err := conn.QueryRowContext(ctx, query, args...).Scan(&id); err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		return id
+	}
+	now := time.Now().UTC()
+	start, end := now.Add(-3*time.Hour), now.Add(-2*time.Hour)
+
+	if _, err := conn.ExecContext(ctx, `INSERT INTO users (id, email) VALUES ($1, $2)`, userID, userID+"@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	projectID := exec(`INSERT INTO projects (name, color, user_id, deleted_at) VALUES ('Old', '#000000', $1, $2) RETURNING id`,
+		userID, now.Add(-48*time.Hour))
+	timeBoxID := exec(`INSERT INTO timeBoxes (start_date, end_date, user_id, project_id) VALUES ($1, $2, $3, $4) RETURNING id`,
+		start, end, userID, projectID)
+	liveEntryID := exec(`INSERT INTO times (start_date, end_date, user_id, project_id) VALUES ($1, $2, $3, $4) RETURNING id`,
+		start, end, userID, projectID)
+	recentEntryID := exec(`INSERT INTO times (start_date, end_date, user_id, project_id, deleted_at) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		start, end, userID, projectID, now.Add(-time.Hour))
+	oldEntryID := exec(`INSERT INTO times (start_date, end_date, user_id, project_id, deleted_at) VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		start, end, userID, projectID, now.Add(-36*time.Hour))
+
+	if _, err := NewTrashRepository(conn).Purge(ctx, now.Add(-24*time.Hour)); err != nil {
+		t.Fatalf("Purge: %v", err)
+	}
+
+	for _, row := range []struct {
+		table string
+		id    int
+		kept  bool
+	}{
+		{"projects", projectID, false},
+		{"timeBoxes", timeBoxID, true},
+		{"times", liveEntryID, true},
+		{"times", recentEntryID, true},
+		{"times", oldEntryID, false},
+	} {
+		var project sql.NullInt64
+		err := conn.QueryRowContext(ctx, `SELECT project_id FROM `+row.table+` WHERE id = $1`, row.id).Scan(&project)
+		switch {
+		case !row.kept && err != sql.ErrNoRows:
+			t.Errorf("%s %d was not purged (%v)", row.table, row.id, err)
+		case row.kept && err != nil:
+			t.Errorf("%s %d was deleted: %v", row.table, row.id, err)
+		case row.kept && project.Valid:
+			t.Errorf("%s %d still points at the purged project", row.table, row.id)
+		}
+	}
+}
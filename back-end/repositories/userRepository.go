@@ -1,6 +1,7 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 
 	"github.com/RiadMefti/TimeTracker/back-end/models"
@@ -18,13 +19,44 @@ func NewUserRepository(db *sql.DB) *UserRepository {
 
 }
 
-func (r *UserRepository) CreateUser(user models.User) error {
-	_, err := r.db.Exec("INSERT INTO users (id, email) VALUES ($1, $2)", user.ID, user.Email)
+func (r *UserRepository) CreateUser(ctx context.Context, user models.User) error {
+	_, err := r.db.ExecContext(ctx, "INSERT INTO users (id, email) VALUES ($1, $2)", user.ID, user.Email)
 	return err
 }
 
-func (r *UserRepository) GetUserBy(id string) (bool, error) {
+func (r *UserRepository) GetUserBy(ctx context.Context, id string) (bool, error) {
 	exists := false
-	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", id).Scan(&exists)
+	err := r.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", id).Scan(&exists)
 	return exists, err
 }
+
+// CreateLocalUser creates an account of the local auth provider. It fails
+// with models.ErrEmailTaken when the email is already registered.
+func (r *UserRepository) CreateLocalUser(ctx context.Context, user models.User, passwordHash string) error {
+	result, err := r.db.ExecContext(ctx,
+		"INSERT INTO users (id, email, password_hash) VALUES ($1, $2, $3) ON CONFLICT (email) DO NOTHING",
+		user.ID, user.Email, passwordHash,
+	)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return models.ErrEmailTaken
+	}
+	return nil
+}
+
+// GetByEmail returns the user with the email and its password hash, which
+// is empty for accounts of other auth providers.
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (models.User, string, error) {
+	var user models.User
+	var passwordHash sql.NullString
+	err := r.db.QueryRowContext(ctx,
+		"SELECT id, email, password_hash FROM users WHERE lower(email) = lower($1)", email,
+	).Scan(&user.ID, &user.Email, &passwordHash)
+	return user, passwordHash.String, err
+}
@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+)
+
+type UserSettingsRepository struct {
+	db *sql.DB
+}
+
+func NewUserSettingsRepository(db *sql.DB) *UserSettingsRepository {
+	return &UserSettingsRepository{db: db}
+}
+
+// Get returns the user's settings, or the defaults when none were saved.
+func (r *UserSettingsRepository) Get(ctx context.Context, userID string) (*models.UserSettings, error) {
+	query := `
+		SELECT timezone, week_start, working_hours, default_project_id,
+			rounding_mode, rounding_minutes, date_format, currency, updated
+		FROM user_settings WHERE user_id = $1
+	`
+
+	var settings models.UserSettings
+	var workingHours []byte
+	var updated time.Time
+	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+		&settings.Timezone, &settings.WeekStart, &workingHours, &settings.DefaultProjectID,
+		&settings.Rounding.Mode, &settings.Rounding.Minutes, &settings.DateFormat, &settings.Currency, &updated,
+	)
+	if err == sql.ErrNoRows {
+		return DefaultUserSettings(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user settings: %w", err)
+	}
+
+	if err := json.Unmarshal(workingHours, &settings.WorkingHours); err != nil {
+		return nil, fmt.Errorf("failed to read working hours: %w", err)
+	}
+	settings.Updated = &updated
+	return &settings, nil
+}
+
+func (r *UserSettingsRepository) Save(ctx context.Context, settings *models.UserSettings, userID string) error {
+	query := `
+		INSERT INTO user_settings (user_id, timezone, week_start, working_hours, default_project_id,
+			rounding_mode, rounding_minutes, date_format, currency, updated)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (user_id) DO UPDATE
+		SET timezone = EXCLUDED.timezone, week_start = EXCLUDED.week_start,
+			working_hours = EXCLUDED.working_hours, default_project_id = EXCLUDED.default_project_id,
+			rounding_mode = EXCLUDED.rounding_mode, rounding_minutes = EXCLUDED.rounding_minutes,
+			date_format = EXCLUDED.date_format, currency = EXCLUDED.currency, updated = EXCLUDED.updated
+	`
+
+	workingHours, err := json.Marshal(settings.WorkingHours)
+	if err != nil {
+		return fmt.Errorf("failed to encode working hours: %w", err)
+	}
+
+	updated := time.Now()
+	_, err = r.db.ExecContext(ctx, query, userID, settings.Timezone, settings.WeekStart, workingHours, settings.DefaultProjectID,
+		settings.Rounding.Mode, settings.Rounding.Minutes, settings.DateFormat, settings.Currency, updated)
+	if err != nil {
+		return fmt.Errorf("failed to save user settings: %w", err)
+	}
+	settings.Updated = &updated
+	return nil
+}
+
+// DefaultUserSettings returns the settings of users who saved none: the
+// column defaults and a Monday to Friday, nine to five week.
+func DefaultUserSettings() *models.UserSettings {
+	workingHours := make([]models.WorkingHours, 0, 5)
+	for _, day := range []string{"monday", "tuesday", "wednesday", "thursday", "friday"} {
+		workingHours = append(workingHours, models.WorkingHours{Weekday: day, Start: "09:00", End: "17:00"})
+	}
+	return &models.UserSettings{
+		Timezone:     "UTC",
+		WeekStart:    "monday",
+		WorkingHours: workingHours,
+		Rounding:     models.DurationRounding{Mode: models.RoundingNone},
+		DateFormat:   "YYYY-MM-DD",
+		Currency:     "USD",
+	}
+}
@@ -0,0 +1,279 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/lib/pq"
+)
+
+const webhookColumns = `id, url, events, active, created, updated`
+
+const deliveryColumns = `
+	d.id, d.webhook_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+	d.next_attempt_at, d.response_status, d.last_error, d.replay_of, d.created, d.delivered_at`
+
+type WebhookRepository struct {
+	db *sql.DB
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) GetAll(ctx context.Context, userID string) ([]*models.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE user_id = $1 ORDER BY id ASC`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	hooks := make([]*models.Webhook, 0)
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook: %w", err)
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+func (r *WebhookRepository) GetByID(ctx context.Context, id int, userID string) (*models.Webhook, error) {
+	row := r.db.QueryRowContext(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE id = $1 AND user_id = $2`, id, userID)
+	hook, err := scanWebhook(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook not found")
+		}
+		return nil, fmt.Errorf("failed to get webhook: %w", err)
+	}
+	return hook, nil
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, hook *models.WebhookCreate, secret string, userID string) (*models.Webhook, error) {
+	row := r.db.QueryRowContext(ctx,
+		`INSERT INTO webhooks (user_id, url, events, secret) VALUES ($1, $2, $3, $4)
+         RETURNING `+webhookColumns,
+		userID, hook.URL, pq.Array(hook.Events), secret,
+	)
+	created, err := scanWebhook(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+	return created, nil
+}
+
+func (r *WebhookRepository) Update(ctx context.Context, id int, hook *models.WebhookUpdate, userID string) (*models.Webhook, error) {
+	row := r.db.QueryRowContext(ctx,
+		`UPDATE webhooks SET url = $1, events = $2, active = COALESCE($3, active), updated = $4
+         WHERE id = $5 AND user_id = $6
+         RETURNING `+webhookColumns,
+		hook.URL, pq.Array(hook.Events), hook.Active, time.Now(), id, userID,
+	)
+	updated, err := scanWebhook(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("webhook not found")
+		}
+		return nil, fmt.Errorf("failed to update webhook: %w", err)
+	}
+	return updated, nil
+}
+
+// Delete removes the webhook along with its delivery log.
+func (r *WebhookRepository) Delete(ctx context.Context, id int, userID string) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("webhook not found")
+	}
+	return nil
+}
+
+// Enqueue records a pending delivery of the event for each active webhook of
+// the user subscribed to its type, and returns how many there are.
+func (r *WebhookRepository) Enqueue(ctx context.Context, userID, eventID, eventType string, payload []byte) (int64, error) {
+	result, err := r.db.ExecContext(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, next_attempt_at)
+         SELECT id, $2, $3, $4, $5 FROM webhooks
+         WHERE user_id = $1 AND active AND ($3 = ANY(events) OR '*' = ANY(events))`,
+		userID, eventID, eventType, string(payload), time.Now(),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to enqueue webhook deliveries: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// GetDeliveries returns the latest deliveries of the webhook, newest first,
+// optionally only those with the given status.
+func (r *WebhookRepository) GetDeliveries(ctx context.Context, webhookID int, status string, limit int, userID string) ([]*models.WebhookDelivery, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT `+deliveryColumns+`
+         FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+         WHERE d.webhook_id = $1 AND w.user_id = $2 AND ($3 = '' OR d.status = $3)
+         ORDER BY d.created DESC, d.id DESC
+         LIMIT $4`,
+		webhookID, userID, status, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := make([]*models.WebhookDelivery, 0)
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// Replay queues a new delivery with the payload of a finished one.
+func (r *WebhookRepository) Replay(ctx context.Context, deliveryID, webhookID int, userID string) (*models.WebhookDelivery, error) {
+	var status string
+	err := r.db.QueryRowContext(ctx,
+		`SELECT d.status FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+         WHERE d.id = $1 AND d.webhook_id = $2 AND w.user_id = $3`,
+		deliveryID, webhookID, userID,
+	).Scan(&status)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("webhook delivery not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+	if status == models.DeliveryPending {
+		return nil, fmt.Errorf("webhook delivery is still pending")
+	}
+
+	row := r.db.QueryRowContext(ctx,
+		`WITH d AS (
+			INSERT INTO webhook_deliveries (webhook_id, event_id, event_type, payload, next_attempt_at, replay_of)
+			SELECT webhook_id, event_id, event_type, payload, $2, id FROM webhook_deliveries WHERE id = $1
+			RETURNING *
+		)
+		SELECT `+deliveryColumns+` FROM d`,
+		deliveryID, time.Now(),
+	)
+	delivery, err := scanDelivery(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay webhook delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+// ClaimDue returns up to limit pending deliveries whose next attempt is due,
+// pushing that attempt back by lease so that no other worker sends them
+// meanwhile.
+func (r *WebhookRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]*models.WebhookTarget, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	rows, err := tx.QueryContext(ctx,
+		`SELECT d.id, w.url, w.secret, d.event_type, d.payload, d.attempts
+         FROM webhook_deliveries d JOIN webhooks w ON w.id = d.webhook_id
+         WHERE d.status = $1 AND d.next_attempt_at <= $2
+         ORDER BY d.next_attempt_at ASC
+         LIMIT $3
+         FOR UPDATE OF d SKIP LOCKED`,
+		models.DeliveryPending, now, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get due webhook deliveries: %w", err)
+	}
+
+	var targets []*models.WebhookTarget
+	var ids []int64
+	for rows.Next() {
+		var target models.WebhookTarget
+		var payload string
+		if err := rows.Scan(&target.DeliveryID, &target.URL, &target.Secret, &target.EventType, &payload, &target.Attempts); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan webhook delivery: %w", err)
+		}
+		target.Payload = []byte(payload)
+		targets = append(targets, &target)
+		ids = append(ids, int64(target.DeliveryID))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get due webhook deliveries: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE webhook_deliveries SET next_attempt_at = $1 WHERE id = ANY($2)`, now.Add(lease), pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+	return targets, nil
+}
+
+// RecordAttempt stores the outcome of a delivery attempt. nextAttempt is nil
+// once the delivery has succeeded or given up.
+func (r *WebhookRepository) RecordAttempt(ctx context.Context, deliveryID int, status string, responseStatus *int, lastError *string, nextAttempt *time.Time) error {
+	var deliveredAt *time.Time
+	if status == models.DeliverySucceeded {
+		now := time.Now()
+		deliveredAt = &now
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE webhook_deliveries
+         SET status = $1, attempts = attempts + 1, response_status = $2, last_error = $3,
+             next_attempt_at = $4, delivered_at = $5
+         WHERE id = $6`,
+		status, responseStatus, lastError, nextAttempt, deliveredAt, deliveryID,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record webhook delivery: %w", err)
+	}
+	return nil
+}
+
+func scanWebhook(row rowScanner) (*models.Webhook, error) {
+	var hook models.Webhook
+	err := row.Scan(&hook.ID, &hook.URL, pq.Array(&hook.Events), &hook.Active, &hook.Created, &hook.Updated)
+	if err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+func scanDelivery(row rowScanner) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	var payload string
+	err := row.Scan(
+		&delivery.ID, &delivery.WebhookID, &delivery.EventID, &delivery.EventType, &payload,
+		&delivery.Status, &delivery.Attempts, &delivery.NextAttemptAt, &delivery.ResponseStatus,
+		&delivery.LastError, &delivery.ReplayOf, &delivery.Created, &delivery.DeliveredAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	delivery.Payload = []byte(payload)
+	return &delivery, nil
+}
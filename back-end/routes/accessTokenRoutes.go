@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/RiadMefti/TimeTracker/back-end/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupAccessTokenRoutes(app *fiber.App, controller *controllers.AccessTokenController) {
+	tokens := app.Group("/me/tokens")
+
+	tokens.Get("/", controller.GetTokens)
+	tokens.Post("/", controller.CreateToken)
+	tokens.Delete("/:id", controller.RevokeToken)
+}
@@ -0,0 +1,10 @@
+package routes
+
+import (
+	"github.com/RiadMefti/TimeTracker/back-end/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupAccountRoutes(app *fiber.App, controller *controllers.AccountController) {
+	app.Delete("/me", controller.DeleteAccount)
+}
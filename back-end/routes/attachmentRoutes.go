@@ -0,0 +1,25 @@
+package routes
+
+import (
+	"github.com/RiadMefti/TimeTracker/back-end/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupPublicAttachmentRoutes registers the signed download route. It must
+// run before the authorization middleware is installed.
+func SetupPublicAttachmentRoutes(app *fiber.App, controller *controllers.AttachmentController) {
+	app.Get("/attachments/:id/download", controller.DownloadAttachment)
+}
+
+func SetupAttachmentRoutes(app *fiber.App, controller *controllers.AttachmentController) {
+	attachments := app.Group("/attachments")
+
+	attachments.Get("/usage", controller.GetUsage)
+	attachments.Get("/:id", controller.GetAttachment)
+	attachments.Delete("/:id", controller.DeleteAttachment)
+
+	notes := app.Group("/notes")
+
+	notes.Post("/:id/attachments", controller.UploadAttachment)
+	notes.Get("/:id/attachments", controller.GetNoteAttachments)
+}
@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/RiadMefti/TimeTracker/back-end/controllers"
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupCollabRoutes(app *fiber.App, controller *controllers.CollabController) {
+	app.Get("/notes/:id/collab", controller.Upgrade, websocket.New(controller.Session))
+}
@@ -0,0 +1,10 @@
+package routes
+
+import (
+	"github.com/RiadMefti/TimeTracker/back-end/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupEventRoutes(app *fiber.App, controller *controllers.EventController) {
+	app.Get("/events", controller.Stream)
+}
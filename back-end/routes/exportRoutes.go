@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"github.com/RiadMefti/TimeTracker/back-end/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupPublicExportRoutes registers the signed download route. It must run
+// before the authorization middleware is installed.
+func SetupPublicExportRoutes(app *fiber.App, controller *controllers.ExportController) {
+	app.Get("/me/export/:id/download", controller.DownloadExport)
+}
+
+func SetupExportRoutes(app *fiber.App, controller *controllers.ExportController) {
+	export := app.Group("/me/export")
+
+	export.Post("/", controller.CreateExport)
+	export.Get("/:id", controller.GetExport)
+}
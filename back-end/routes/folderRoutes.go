@@ -11,7 +11,10 @@ func SetupFolderRoutes(app *fiber.App, controller *controllers.FolderController)
 	folders.Post("/", controller.CreateFolder)
 	folders.Get("/", controller.GetAllFolders)
 	folders.Get("/by-parent", controller.GetFoldersByParent)
+	folders.Post("/import-archive", controller.ImportFolderArchive)
+	folders.Put("/order", controller.ReorderFolders)
 	folders.Get("/:id", controller.GetFolder)
+	folders.Get("/:id/archive", controller.ExportFolderArchive)
 	folders.Put("/:id", controller.UpdateFolder)
 	folders.Delete("/:id", controller.DeleteFolder)
 }
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/RiadMefti/TimeTracker/back-end/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupLocalAuthRoutes registers the public account endpoints of the local
+// auth provider. Without signup, accounts can only log in.
+func SetupLocalAuthRoutes(app *fiber.App, controller *controllers.LocalAuthController, signup bool) {
+	local := app.Group("/auth/local")
+
+	if signup {
+		local.Post("/signup", controller.Signup)
+	}
+	local.Post("/login", controller.Login)
+}
@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/RiadMefti/TimeTracker/back-end/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupNoteRelationRoutes(app *fiber.App, controller *controllers.NoteRelationController) {
+	notes := app.Group("/notes")
+
+	notes.Get("/:id/relations", controller.GetRelations)
+	notes.Post("/:id/projects/:targetId", controller.AttachProject)
+	notes.Delete("/:id/projects/:targetId", controller.DetachProject)
+	notes.Post("/:id/time-entries/:targetId", controller.AttachTimeEntry)
+	notes.Delete("/:id/time-entries/:targetId", controller.DetachTimeEntry)
+	notes.Post("/:id/time-boxes/:targetId", controller.AttachTimeBox)
+	notes.Delete("/:id/time-boxes/:targetId", controller.DetachTimeBox)
+
+	projects := app.Group("/projects")
+
+	projects.Get("/:id/notes", controller.GetProjectNotes)
+}
@@ -11,7 +11,15 @@ func SetupNoteRoutes(app *fiber.App, controller *controllers.NoteController) {
 	notes.Post("/", controller.CreateNote)
 	notes.Get("/", controller.GetAllNotes)
 	notes.Get("/by-folder", controller.GetNotesByFolder)
+	notes.Post("/import", controller.ImportNote)
+	notes.Put("/order", controller.ReorderNotes)
+	notes.Get("/tasks", controller.GetTasks)
+	notes.Patch("/tasks/:id", controller.UpdateTask)
 	notes.Get("/:id", controller.GetNote)
+	notes.Get("/:id/export", controller.ExportNote)
+	notes.Get("/:id/backlinks", controller.GetBacklinks)
 	notes.Put("/:id", controller.UpdateNote)
+	notes.Post("/:id/pin", controller.PinNote)
+	notes.Delete("/:id/pin", controller.UnpinNote)
 	notes.Delete("/:id", controller.DeleteNote)
 }
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/RiadMefti/TimeTracker/back-end/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupNoteTemplateRoutes must run before SetupNoteRoutes so that these
+// paths are not captured by /notes/:id.
+func SetupNoteTemplateRoutes(app *fiber.App, controller *controllers.NoteTemplateController) {
+	notes := app.Group("/notes")
+
+	notes.Get("/templates", controller.GetTemplates)
+	notes.Post("/templates/:id/instantiate", controller.CreateFromTemplate)
+	notes.Post("/daily", controller.GetOrCreateDailyNote)
+	notes.Get("/daily/settings", controller.GetDailySettings)
+	notes.Put("/daily/settings", controller.UpdateDailySettings)
+}
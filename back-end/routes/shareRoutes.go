@@ -0,0 +1,22 @@
+package routes
+
+import (
+	"github.com/RiadMefti/TimeTracker/back-end/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+// SetupPublicShareRoutes registers the unauthenticated share view. It must
+// run before the authorization middleware is installed.
+func SetupPublicShareRoutes(app *fiber.App, controller *controllers.ShareController) {
+	app.Get("/share/:token", controller.ViewShare)
+	// The password form posts back to the page.
+	app.Post("/share/:token", controller.ViewShare)
+}
+
+func SetupShareRoutes(app *fiber.App, controller *controllers.ShareController) {
+	shares := app.Group("/shares")
+
+	shares.Post("/", controller.CreateShareLink)
+	shares.Get("/", controller.GetShareLinks)
+	shares.Delete("/:id", controller.RevokeShareLink)
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/RiadMefti/TimeTracker/back-end/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupTagRoutes(app *fiber.App, controller *controllers.TagController) {
+	tags := app.Group("/tags")
+
+	tags.Get("/", controller.GetTags)
+	tags.Delete("/:id", controller.DeleteTag)
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/RiadMefti/TimeTracker/back-end/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupTimerRoutes(app *fiber.App, controller *controllers.TimerController) {
+	timer := app.Group("/timer")
+
+	timer.Get("/", controller.GetTimer)
+	timer.Put("/", controller.UpdateTimer)
+	timer.Post("/start", controller.StartTimer)
+	timer.Post("/stop", controller.StopTimer)
+}
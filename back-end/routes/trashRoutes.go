@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/RiadMefti/TimeTracker/back-end/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupTrashRoutes(app *fiber.App, controller *controllers.TrashController) {
+	trash := app.Group("/trash")
+
+	trash.Get("/", controller.GetTrash)
+	trash.Post("/notes/:id/restore", controller.RestoreNote)
+	trash.Post("/folders/:id/restore", controller.RestoreFolder)
+	trash.Post("/time-entries/:id/restore", controller.RestoreTimeEntry)
+	trash.Post("/projects/:id/restore", controller.RestoreProject)
+}
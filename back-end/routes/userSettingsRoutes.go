@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/RiadMefti/TimeTracker/back-end/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupUserSettingsRoutes(app *fiber.App, controller *controllers.UserSettingsController) {
+	settings := app.Group("/me/settings")
+
+	settings.Get("/", controller.GetSettings)
+	settings.Put("/", controller.UpdateSettings)
+}
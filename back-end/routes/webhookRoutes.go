@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/RiadMefti/TimeTracker/back-end/controllers"
+	"github.com/gofiber/fiber/v2"
+)
+
+func SetupWebhookRoutes(app *fiber.App, controller *controllers.WebhookController) {
+	webhooks := app.Group("/webhooks")
+
+	webhooks.Get("/", controller.GetWebhooks)
+	webhooks.Post("/", controller.CreateWebhook)
+	webhooks.Put("/:id", controller.UpdateWebhook)
+	webhooks.Delete("/:id", controller.DeleteWebhook)
+	webhooks.Get("/:id/deliveries", controller.GetDeliveries)
+	webhooks.Post("/:id/deliveries/:deliveryId/replay", controller.ReplayDelivery)
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/repositories"
+)
+
+// AccessTokenPrefix starts every personal access token, which tells them
+// apart from Firebase ID tokens.
+const AccessTokenPrefix = "ttp_"
+
+const maxTokenNameLength = 100
+
+// scopeResources maps the first path segment of the endpoints covered by the
+// resource scopes to their resource. Note tasks, templates and daily notes
+// live under /notes. Trash endpoints take the resource of the segment after
+// /trash. Webhooks, events, exports and settings span every resource, so only
+// the generic read and write scopes cover them.
+var scopeResources = map[string]string{
+	"time-entries":     "time",
+	"time-box-entries": "time",
+	"timer":            "time",
+	"projects":         "time",
+	"notes":            "notes",
+	"folders":          "notes",
+	"tags":             "notes",
+	"attachments":      "notes",
+	"shares":           "notes",
+}
+
+var tokenScopes = []string{
+	models.ScopeRead, models.ScopeWrite,
+	models.ScopeTimeRead, models.ScopeTimeWrite,
+	models.ScopeNotesRead, models.ScopeNotesWrite,
+}
+
+type AccessTokenService struct {
+	repo *repositories.AccessTokenRepository
+}
+
+func NewAccessTokenService(repo *repositories.AccessTokenRepository) *AccessTokenService {
+	return &AccessTokenService{repo: repo}
+}
+
+func (s *AccessTokenService) GetTokens(ctx context.Context, userID string) ([]*models.PersonalAccessToken, error) {
+	return s.repo.GetAll(ctx, userID)
+}
+
+// CreateToken issues a token and returns it with its value, which is not
+// shown again.
+func (s *AccessTokenService) CreateToken(ctx context.Context, token *models.PersonalAccessTokenCreate, userID string) (*models.PersonalAccessToken, error) {
+	token.Name = strings.TrimSpace(token.Name)
+	if token.Name == "" {
+		return nil, fmt.Errorf("token name cannot be empty")
+	}
+	if len(token.Name) > maxTokenNameLength {
+		return nil, fmt.Errorf("token name cannot be longer than %d characters", maxTokenNameLength)
+	}
+	if token.ExpiresAt != nil {
+		if !token.ExpiresAt.After(time.Now()) {
+			return nil, fmt.Errorf("token expiry must be in the future")
+		}
+		expiresAt := token.ExpiresAt.UTC()
+		token.ExpiresAt = &expiresAt
+	}
+
+	scopes, err := normalizeScopes(token.Scopes)
+	if err != nil {
+		return nil, err
+	}
+	token.Scopes = scopes
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	value := AccessTokenPrefix + hex.EncodeToString(secret)
+
+	created, err := s.repo.Create(ctx, token, hashToken(value), value[:len(AccessTokenPrefix)+8], userID)
+	if err != nil {
+		return nil, err
+	}
+	created.Token = value
+	return created, nil
+}
+
+func (s *AccessTokenService) RevokeToken(ctx context.Context, id int, userID string) error {
+	return s.repo.Revoke(ctx, id, userID)
+}
+
+// IsAccessToken reports whether a bearer token is a personal access token.
+func IsAccessToken(token string) bool {
+	return strings.HasPrefix(token, AccessTokenPrefix)
+}
+
+// Authenticate returns the live token matching value and records its use.
+// It fails with models.ErrInvalidToken when there is none.
+func (s *AccessTokenService) Authenticate(ctx context.Context, value string) (*models.PersonalAccessToken, error) {
+	token, err := s.repo.GetActiveByHash(ctx, hashToken(value))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.TouchLastUsed(ctx, token.ID); err != nil {
+		log.Printf("access token %d: %v", token.ID, err)
+	}
+	return token, nil
+}
+
+// Allows reports whether the token's scopes cover a request. Tokens cannot
+// manage tokens or delete the account, and the resource scopes only cover
+// their own endpoints.
+func (s *AccessTokenService) Allows(token *models.PersonalAccessToken, method, path string) bool {
+	// Routes match regardless of case, so the path is checked lower-cased.
+	segments := strings.Split(strings.Trim(strings.ToLower(path), "/"), "/")
+	if len(segments) >= 2 && segments[0] == "me" && segments[1] == "tokens" {
+		return false
+	}
+	if len(segments) == 1 && segments[0] == "me" && method == http.MethodDelete {
+		return false
+	}
+
+	write := method != http.MethodGet && method != http.MethodHead
+	resource := scopeResource(segments)
+	for _, scope := range token.Scopes {
+		switch {
+		case scope == models.ScopeWrite:
+			return true
+		case scope == models.ScopeRead && !write:
+			return true
+		case resource != "" && scope == resource+":write":
+			return true
+		case resource != "" && scope == resource+":read" && !write:
+			return true
+		}
+	}
+	return false
+}
+
+// scopeResource returns the resource of a request path, or "" for paths only
+// the generic scopes cover. Listing the trash shows items of every resource.
+func scopeResource(segments []string) string {
+	if segments[0] == "trash" {
+		if len(segments) < 2 {
+			return ""
+		}
+		return scopeResources[segments[1]]
+	}
+	return scopeResources[segments[0]]
+}
+
+func normalizeScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("token must have at least one scope")
+	}
+
+	seen := map[string]bool{}
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !isTokenScope(scope) {
+			return nil, fmt.Errorf("unknown scope %q", scope)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+	return normalized, nil
+}
+
+func isTokenScope(scope string) bool {
+	for _, known := range tokenScopes {
+		if known == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// hashToken hashes an access token or share token for storage. Tokens are
+// random, so a plain SHA-256 is enough.
+func hashToken(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
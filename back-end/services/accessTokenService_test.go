@@ -0,0 +1,77 @@
+package services
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+)
+
+func TestAccessTokenAllows(t *testing.T) {
+	s := NewAccessTokenService(nil)
+	tokens := map[string]*models.PersonalAccessToken{
+		"write":       {Scopes: []string{models.ScopeWrite}},
+		"read":        {Scopes: []string{models.ScopeRead}},
+		"notes:write": {Scopes: []string{models.ScopeNotesWrite}},
+		"time:read":   {Scopes: []string{models.ScopeTimeRead}},
+	}
+
+	tests := []struct {
+		token  string
+		method string
+		path   string
+		want   bool
+	}{
+		{"write", http.MethodPost, "/me/tokens", false},
+		{"write", http.MethodGet, "/me/tokens", false},
+		{"write", http.MethodDelete, "/me", false},
+		{"write", http.MethodGet, "/me", true},
+		{"read", http.MethodGet, "/notes", true},
+		{"read", http.MethodPost, "/notes", false},
+		{"notes:write", http.MethodPost, "/notes", true},
+		{"notes:write", http.MethodPatch, "/notes/tasks/3", true},
+		{"notes:write", http.MethodPost, "/time-entries", false},
+		{"notes:write", http.MethodPost, "/trash/notes/3/restore", true},
+		{"notes:write", http.MethodPost, "/trash/projects/3/restore", false},
+		{"notes:write", http.MethodGet, "/trash", false},
+		{"notes:write", http.MethodGet, "/webhooks", false},
+		{"time:read", http.MethodGet, "/timer", true},
+		{"time:read", http.MethodPost, "/trash/time-entries/3/restore", false},
+	}
+	for _, test := range tests {
+		if got := s.Allows(tokens[test.token], test.method, test.path); got != test.want {
+			t.Errorf("%s token, %s %s: Allows = %v, want %v", test.token, test.method, test.path, got, test.want)
+		}
+	}
+}
+
+// Routes match regardless of case, so the checks must too.
+func TestAccessTokenAllowsMixedCase(t *testing.T) {
+	s := NewAccessTokenService(nil)
+	write := &models.PersonalAccessToken{Scopes: []string{models.ScopeWrite}}
+	notes := &models.PersonalAccessToken{Scopes: []string{models.ScopeNotesWrite}}
+
+	for _, denied := range []struct {
+		token  *models.PersonalAccessToken
+		method string
+		path   string
+	}{
+		{write, http.MethodPost, "/ME/tokens"},
+		{write, http.MethodPost, "/Me/Tokens/"},
+		{write, http.MethodDelete, "/me/TOKENS/3"},
+		{write, http.MethodDelete, "/Me"},
+		{write, http.MethodDelete, "/ME/"},
+		{notes, http.MethodPost, "/TIME-ENTRIES"},
+		{notes, http.MethodPost, "/Trash/Projects/3/restore"},
+	} {
+		if s.Allows(denied.token, denied.method, denied.path) {
+			t.Errorf("%v allowed %s %s", denied.token.Scopes, denied.method, denied.path)
+		}
+	}
+
+	for _, path := range []string{"/NOTES", "/Notes/3", "/Trash/Notes/3/restore", "/FOLDERS"} {
+		if !s.Allows(notes, http.MethodPost, path) {
+			t.Errorf("notes:write denied POST %s", path)
+		}
+	}
+}
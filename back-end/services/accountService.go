@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/repositories"
+)
+
+// AccountService deletes accounts once their grace period is over. With the
+// user row locked, it deletes the identity at the auth provider first, so the
+// user cannot sign back in to a half deleted account, then the exports and
+// the database rows. Every step is written to the account audit log.
+type AccountService struct {
+	repo          *repositories.AccountRepository
+	exports       *ExportService
+	attachments   *AttachmentService
+	authenticator Authenticator
+	grace         time.Duration
+}
+
+func NewAccountService(repo *repositories.AccountRepository, exports *ExportService, attachments *AttachmentService, authenticator Authenticator, grace time.Duration) *AccountService {
+	return &AccountService{repo: repo, exports: exports, attachments: attachments, authenticator: authenticator, grace: grace}
+}
+
+// ScheduleDeletion schedules the account for deletion after the grace
+// period. Asking again keeps the first schedule.
+func (s *AccountService) ScheduleDeletion(ctx context.Context, userID string) (*models.AccountDeletion, error) {
+	scheduled, err := s.repo.ScheduleDeletion(ctx, time.Now().Add(s.grace), userID)
+	if err != nil {
+		return nil, err
+	}
+	s.audit(ctx, userID, models.AuditDeletionRequested, "scheduled for "+scheduled.UTC().Format(time.RFC3339))
+	return &models.AccountDeletion{ScheduledAt: scheduled.UTC()}, nil
+}
+
+// CancelDeletion cancels a scheduled deletion whose grace period is not
+// over and tells whether there was one.
+func (s *AccountService) CancelDeletion(ctx context.Context, userID string) (bool, error) {
+	cancelled, err := s.repo.CancelDeletion(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if cancelled {
+		s.audit(ctx, userID, models.AuditDeletionCancelled, "cancelled by login")
+	}
+	return cancelled, nil
+}
+
+// StartPurgeJob runs Purge right away and then every interval, until ctx is
+// cancelled.
+func (s *AccountService) StartPurgeJob(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			s.Purge(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Purge deletes the accounts whose grace period is over. An account that
+// fails is left scheduled and retried on the next run. Accounts another
+// server is purging are skipped.
+func (s *AccountService) Purge(ctx context.Context) {
+	userIDs, err := s.repo.GetDueDeletions(ctx)
+	if err != nil {
+		log.Printf("account purge failed: %v", err)
+		return
+	}
+
+	purged := 0
+	for _, userID := range userIDs {
+		err := s.purge(ctx, userID)
+		if errors.Is(err, models.ErrDeletionNotDue) {
+			continue
+		}
+		if err != nil {
+			log.Printf("account purge failed for %s: %v", userID, err)
+			s.audit(ctx, userID, models.AuditPurgeFailed, err.Error())
+			continue
+		}
+		purged++
+	}
+	if purged > 0 {
+		log.Printf("account purge removed %d accounts", purged)
+		s.attachments.CleanupOrphans(ctx)
+	}
+}
+
+func (s *AccountService) purge(ctx context.Context, userID string) error {
+	err := s.repo.DeleteUser(ctx, userID, func() error {
+		if err := s.authenticator.DeleteIdentity(ctx, userID); err != nil {
+			return err
+		}
+		s.audit(ctx, userID, models.AuditIdentityDeleted, "")
+		return s.exports.DeleteUserExports(ctx, userID)
+	})
+	if err != nil {
+		return err
+	}
+	s.audit(ctx, userID, models.AuditAccountPurged, "")
+	return nil
+}
+
+// audit writes to the audit log. A failed write is logged rather than
+// undoing the step it describes.
+func (s *AccountService) audit(ctx context.Context, userID string, action string, detail string) {
+	if err := s.repo.Audit(ctx, userID, action, detail); err != nil {
+		log.Printf("account audit: %v", err)
+	}
+}
@@ -0,0 +1,213 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/config"
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/repositories"
+	"github.com/RiadMefti/TimeTracker/back-end/storage"
+)
+
+// allowedAttachmentTypes lists the content types accepted for upload, as
+// sniffed from the file content rather than taken from the client.
+var allowedAttachmentTypes = map[string]bool{
+	"image/png":       true,
+	"image/jpeg":      true,
+	"image/gif":       true,
+	"image/webp":      true,
+	"application/pdf": true,
+	"application/zip": true,
+	"text/plain":      true,
+}
+
+type AttachmentConfig struct {
+	MaxBytes   int64  // largest accepted upload
+	QuotaBytes int64  // total storage per user
+	URLSecret  []byte // key for signing download URLs
+	URLTTL     time.Duration
+}
+
+// NewAttachmentConfig builds the attachment settings. Without a URL secret a
+// random one is generated, so download URLs stop working when the server
+// restarts.
+func NewAttachmentConfig(settings config.Attachments) (AttachmentConfig, error) {
+	config := AttachmentConfig{
+		MaxBytes:   settings.MaxBytes,
+		QuotaBytes: settings.QuotaBytes,
+		URLTTL:     time.Hour,
+	}
+
+	if settings.URLSecret != "" {
+		config.URLSecret = []byte(settings.URLSecret)
+	} else {
+		log.Println("ATTACHMENT_URL_SECRET is not set, download links will not survive a restart")
+		config.URLSecret = make([]byte, 32)
+		if _, err := rand.Read(config.URLSecret); err != nil {
+			return config, fmt.Errorf("failed to generate attachment URL secret: %w", err)
+		}
+	}
+	return config, nil
+}
+
+type AttachmentService struct {
+	repo     *repositories.AttachmentRepository
+	noteRepo *repositories.NoteRepository
+	store    storage.BlobStore
+	config   AttachmentConfig
+}
+
+func NewAttachmentService(repo *repositories.AttachmentRepository, noteRepo *repositories.NoteRepository, store storage.BlobStore, config AttachmentConfig) *AttachmentService {
+	return &AttachmentService{repo: repo, noteRepo: noteRepo, store: store, config: config}
+}
+
+func (s *AttachmentService) MaxBytes() int64 {
+	return s.config.MaxBytes
+}
+
+// Upload stores the file and attaches it to the note. Content already stored
+// for any user is not written again, and only counts against the quota once
+// per user.
+func (s *AttachmentService) Upload(ctx context.Context, noteID int, fileName string, r io.Reader, userID string) (*models.Attachment, error) {
+	// Check if note exists and belongs to user
+	if _, err := s.noteRepo.GetByID(ctx, noteID, userID); err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, s.config.MaxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	if int64(len(data)) > s.config.MaxBytes {
+		return nil, models.ErrAttachmentTooLarge
+	}
+	if len(data) == 0 {
+		return nil, models.ErrEmptyAttachment
+	}
+
+	mimeType, _, _ := mime.ParseMediaType(http.DetectContentType(data))
+	if !allowedAttachmentTypes[mimeType] {
+		return nil, models.ErrUnsupportedMediaType
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	size := int64(len(data))
+
+	attachment, err := s.repo.Create(ctx, &models.Attachment{
+		NoteID:   noteID,
+		UserID:   userID,
+		FileName: fileName,
+		MimeType: mimeType,
+		Size:     size,
+		Hash:     hash,
+	}, s.config.QuotaBytes, func() error {
+		return s.store.Put(ctx, hash, bytes.NewReader(data), size)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.sign(attachment)
+	return attachment, nil
+}
+
+func (s *AttachmentService) GetAttachment(ctx context.Context, id int, userID string) (*models.Attachment, error) {
+	attachment, err := s.repo.GetByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.sign(attachment)
+	return attachment, nil
+}
+
+func (s *AttachmentService) GetNoteAttachments(ctx context.Context, noteID int, userID string) ([]*models.Attachment, error) {
+	// Check if note exists and belongs to user
+	if _, err := s.noteRepo.GetByID(ctx, noteID, userID); err != nil {
+		return nil, err
+	}
+
+	attachments, err := s.repo.GetByNote(ctx, noteID, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, attachment := range attachments {
+		s.sign(attachment)
+	}
+	return attachments, nil
+}
+
+func (s *AttachmentService) GetUsage(ctx context.Context, userID string) (*models.AttachmentUsage, error) {
+	used, err := s.repo.GetUsage(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.AttachmentUsage{Used: used, Quota: s.config.QuotaBytes}, nil
+}
+
+func (s *AttachmentService) DeleteAttachment(ctx context.Context, id int, userID string) error {
+	if err := s.repo.Delete(ctx, id, userID); err != nil {
+		return err
+	}
+	s.CleanupOrphans(ctx)
+	return nil
+}
+
+// Open checks a signed download URL and returns the attachment with a reader
+// for its content, which the caller must close.
+func (s *AttachmentService) Open(ctx context.Context, id int, expires int64, signature string) (*models.Attachment, io.ReadCloser, error) {
+	expected := s.signature(id, expires)
+	given, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(given, expected) || time.Now().Unix() > expires {
+		return nil, nil, models.ErrInvalidDownloadLink
+	}
+
+	attachment, err := s.repo.GetForDownload(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The content is streamed after the handler returns, so reading it must
+	// not depend on the request
+	content, err := s.store.Get(context.Background(), attachment.Hash)
+	if err != nil {
+		return nil, nil, err
+	}
+	return attachment, content, nil
+}
+
+// CleanupOrphans removes blobs that are no longer attached to any note. It
+// runs after deletions and only logs failures, since the rows that triggered
+// it are already gone; blobs it could not remove are retried next time.
+func (s *AttachmentService) CleanupOrphans(ctx context.Context) {
+	err := s.repo.DeleteOrphanBlobs(ctx, func(ctx context.Context, hash string) error {
+		return s.store.Delete(context.WithoutCancel(ctx), hash)
+	})
+	if err != nil {
+		log.Printf("attachment cleanup failed: %v", err)
+	}
+}
+
+// sign fills in a download URL that is valid for the configured TTL. The
+// URL is relative to the API root.
+func (s *AttachmentService) sign(attachment *models.Attachment) {
+	expires := time.Now().Add(s.config.URLTTL).Unix()
+	attachment.URL = fmt.Sprintf("/attachments/%d/download?expires=%d&signature=%s",
+		attachment.ID, expires, hex.EncodeToString(s.signature(attachment.ID, expires)))
+	attachment.URLExpires = time.Unix(expires, 0).UTC()
+}
+
+func (s *AttachmentService) signature(id int, expires int64) []byte {
+	mac := hmac.New(sha256.New, s.config.URLSecret)
+	fmt.Fprintf(mac, "%d:%d", id, expires)
+	return mac.Sum(nil)
+}
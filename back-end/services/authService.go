@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"github.com/RiadMefti/TimeTracker/back-end/models"
 	"github.com/RiadMefti/TimeTracker/back-end/repositories"
 )
@@ -14,9 +15,9 @@ func NewAuthService(userRepository *repositories.UserRepository) *AuthService {
 		userRepository: userRepository,
 	}
 }
-func (u *AuthService) RegisterUser(user models.User) (bool, error) {
+func (u *AuthService) RegisterUser(ctx context.Context, user models.User) (bool, error) {
 	// Check if the user already exists
-	exists, err := u.userRepository.GetUserBy(user.ID)
+	exists, err := u.userRepository.GetUserBy(ctx, user.ID)
 	if err != nil {
 		return false, err
 	}
@@ -26,7 +27,7 @@ func (u *AuthService) RegisterUser(user models.User) (bool, error) {
 	}
 
 	// Create the user since it does not exist.
-	err = u.userRepository.CreateUser(user)
+	err = u.userRepository.CreateUser(ctx, user)
 	if err != nil {
 		return false, err
 	}
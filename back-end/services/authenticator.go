@@ -0,0 +1,51 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RiadMefti/TimeTracker/back-end/config"
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/repositories"
+)
+
+// Authenticator identifies the user a bearer token was issued to.
+type Authenticator interface {
+	// Verify returns the identity in the token's claims. It fails with
+	// models.ErrInvalidToken for tokens that are not valid, and with
+	// models.ErrAuthUnavailable when the token could not be checked.
+	Verify(ctx context.Context, token string) (*models.Identity, error)
+	// DeleteIdentity removes the provider's record of a user whose account
+	// is deleted. An identity that does not exist is not an error.
+	DeleteIdentity(ctx context.Context, uid string) error
+}
+
+// NewAuthenticator builds the authenticator of the configured provider:
+// "firebase", "local" for accounts stored in the users table, or "oidc" for
+// tokens from any OpenID Connect provider.
+func NewAuthenticator(config config.Auth, userRepository *repositories.UserRepository) (Authenticator, error) {
+	switch config.Provider {
+	case "firebase":
+		app, err := NewFirebaseService()
+		if err != nil {
+			return nil, err
+		}
+		return NewFirebaseAuthenticator(app)
+	case "local":
+		return NewLocalAuthenticator(userRepository, LocalAuthConfig{
+			Algorithm:      config.JWT.Algorithm,
+			Secret:         config.JWT.Secret,
+			PrivateKeyFile: config.JWT.PrivateKeyFile,
+			Issuer:         config.JWT.Issuer,
+			TTL:            config.JWT.TTL,
+		})
+	case "oidc":
+		return NewOIDCAuthenticator(OIDCConfig{
+			Issuer:   config.OIDC.Issuer,
+			Audience: config.OIDC.Audience,
+			JWKS:     config.OIDC.JWKS,
+		})
+	default:
+		return nil, fmt.Errorf("unknown auth provider %q", config.Provider)
+	}
+}
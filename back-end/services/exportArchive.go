@@ -0,0 +1,152 @@
+package services
+
+import (
+	"archive/zip"
+	_ "embed"
+	"encoding/json"
+	"io"
+	"path"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/tiptap"
+	"github.com/RiadMefti/TimeTracker/back-end/utils"
+)
+
+// exportFormat documents the archive layout. It is shipped as README.md in
+// every export.
+//
+//go:embed exportFormat.md
+var exportFormat string
+
+// exportData is everything written to an export archive.
+type exportData struct {
+	manifest  models.ExportManifest
+	user      *models.ExportUser
+	projects  []models.ExportProject
+	times     []models.ExportTimeEntry
+	timeBoxes []models.ExportTimeEntry
+	folders   []models.ExportFolder
+	notes     []models.ExportNote
+}
+
+// writeExportArchive writes data as a zip in the layout of exportFormat.md.
+func writeExportArchive(w io.Writer, data *exportData) error {
+	zw := zip.NewWriter(w)
+
+	setNotePaths(data.folders, data.notes)
+	data.manifest.Counts.Projects = len(data.projects)
+	data.manifest.Counts.Times = len(data.times)
+	data.manifest.Counts.TimeBoxes = len(data.timeBoxes)
+	data.manifest.Counts.Folders = len(data.folders)
+	data.manifest.Counts.Notes = len(data.notes)
+
+	files := []struct {
+		name  string
+		value interface{}
+	}{
+		{"manifest.json", data.manifest},
+		{"user.json", data.user},
+		{"projects.json", data.projects},
+		{"times.json", data.times},
+		{"timeBoxes.json", data.timeBoxes},
+		{"folders.json", data.folders},
+		{"notes.json", data.notes},
+	}
+	for _, file := range files {
+		if err := writeExportJSON(zw, file.name, file.value); err != nil {
+			return err
+		}
+	}
+
+	readme, err := zw.Create("README.md")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(readme, exportFormat); err != nil {
+		return err
+	}
+
+	for _, note := range data.notes {
+		if note.Path == "" {
+			continue
+		}
+		markdown := note.Content
+		if doc, err := tiptap.ParseStored(note.Content); err == nil {
+			markdown = tiptap.ToMarkdown(doc)
+		}
+		file, err := zw.Create(note.Path)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(file, markdown); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func writeExportJSON(zw *zip.Writer, name string, value interface{}) error {
+	file, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// setNotePaths gives each live note a Markdown path under notes/ that
+// mirrors its folder. Notes that are trashed, or in a trashed folder, get
+// none.
+func setNotePaths(folders []models.ExportFolder, notes []models.ExportNote) {
+	byID := map[int]*models.ExportFolder{}
+	for i := range folders {
+		byID[folders[i].ID] = &folders[i]
+	}
+
+	dirs := map[int]string{}
+	taken := map[string]map[string]bool{}
+	names := func(dir string) map[string]bool {
+		if taken[dir] == nil {
+			taken[dir] = map[string]bool{}
+		}
+		return taken[dir]
+	}
+
+	// dir resolves the directory of a folder, or "" when it or one of its
+	// parents is trashed.
+	var dir func(id int) string
+	dir = func(id int) string {
+		if d, ok := dirs[id]; ok {
+			return d
+		}
+		dirs[id] = "" // guards against parent cycles
+		folder := byID[id]
+		if folder == nil || folder.DeletedAt != nil {
+			return ""
+		}
+		parent := "notes"
+		if folder.ParentID != nil {
+			if parent = dir(*folder.ParentID); parent == "" {
+				return ""
+			}
+		}
+		d := path.Join(parent, uniqueName(utils.SafeFileName(folder.Name, "folder"), names(parent)))
+		dirs[id] = d
+		return d
+	}
+
+	for i := range notes {
+		note := &notes[i]
+		if note.DeletedAt != nil {
+			continue
+		}
+		parent := "notes"
+		if note.FolderID != nil {
+			if parent = dir(*note.FolderID); parent == "" {
+				continue
+			}
+		}
+		note.Path = path.Join(parent, uniqueName(utils.SafeFileName(note.Title, "note"), names(parent))+".md")
+	}
+}
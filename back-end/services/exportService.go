@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/repositories"
+	"github.com/RiadMefti/TimeTracker/back-end/storage"
+)
+
+const (
+	exportLease     = 30 * time.Minute
+	exportRetention = 7 * 24 * time.Hour
+	exportURLTTL    = time.Hour
+)
+
+// ExportService builds data exports in the background. Archives are kept in
+// the blob store for a week and downloaded through signed URLs, like
+// attachments.
+type ExportService struct {
+	repo      *repositories.ExportRepository
+	store     storage.BlobStore
+	urlSecret []byte
+	wake      chan struct{}
+}
+
+func NewExportService(repo *repositories.ExportRepository, store storage.BlobStore, urlSecret []byte) *ExportService {
+	return &ExportService{repo: repo, store: store, urlSecret: urlSecret, wake: make(chan struct{}, 1)}
+}
+
+// CreateExport queues an export of all the user's data. A user has at most
+// one export in progress; asking again returns it.
+func (s *ExportService) CreateExport(ctx context.Context, userID string) (*models.DataExport, error) {
+	export, err := s.repo.GetOpen(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if export == nil {
+		if export, err = s.repo.Create(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return export, nil
+}
+
+// GetExport returns the export with a fresh download URL once it is done.
+func (s *ExportService) GetExport(ctx context.Context, id int, userID string) (*models.DataExport, error) {
+	export, err := s.repo.GetByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if export.Status == models.ExportDone {
+		s.sign(export)
+	}
+	return export, nil
+}
+
+// Open checks a signed download URL and returns the export with a reader for
+// its archive, which the caller must close.
+func (s *ExportService) Open(ctx context.Context, id int, expires int64, signature string) (*models.DataExport, io.ReadCloser, error) {
+	given, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(given, s.signature(id, expires)) || time.Now().Unix() > expires {
+		return nil, nil, models.ErrInvalidDownloadLink
+	}
+
+	export, err := s.repo.GetDone(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The archive is streamed after the handler returns, so reading it must
+	// not depend on the request
+	content, err := s.store.Get(context.Background(), export.BlobKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return export, content, nil
+}
+
+// StartExportJob builds queued exports as soon as they are requested, and
+// every interval removes expired archives and picks up exports left behind
+// by a stopped server, until ctx is cancelled.
+func (s *ExportService) StartExportJob(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			s.deleteExpired(ctx)
+			s.runQueued(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.wake:
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (s *ExportService) runQueued(ctx context.Context) {
+	for {
+		export, err := s.repo.ClaimNext(ctx, exportLease)
+		if err != nil {
+			log.Printf("exports: %v", err)
+			return
+		}
+		if export == nil {
+			return
+		}
+
+		if err := s.build(ctx, export); err != nil {
+			log.Printf("exports: export %d failed: %v", export.ID, err)
+			if err := s.repo.Fail(ctx, export.ID, "the export could not be built"); err != nil {
+				log.Printf("exports: %v", err)
+			}
+		}
+	}
+}
+
+// build writes the archive to a temporary file first, since the blob store
+// needs its size up front.
+func (s *ExportService) build(ctx context.Context, export *models.DataExport) error {
+	data, err := s.load(ctx, export.UserID)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp("", "export-*.zip")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if err := writeExportArchive(file, data); err != nil {
+		return err
+	}
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 16)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	key := fmt.Sprintf("export-%d-%s", export.ID, hex.EncodeToString(suffix))
+	if err := s.store.Put(ctx, key, file, size); err != nil {
+		return err
+	}
+
+	if err := s.repo.Finish(ctx, export.ID, key, size, time.Now().Add(exportRetention)); err != nil {
+		s.store.Delete(context.WithoutCancel(ctx), key)
+		return err
+	}
+	return nil
+}
+
+func (s *ExportService) load(ctx context.Context, userID string) (*exportData, error) {
+	data := &exportData{}
+	data.manifest.Format = "timetracker-export"
+	data.manifest.Version = models.ExportFormatVersion
+	data.manifest.Exported = time.Now().UTC()
+	data.manifest.UserID = userID
+
+	var err error
+	if data.user, err = s.repo.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	if data.projects, err = s.repo.GetProjects(ctx, userID); err != nil {
+		return nil, err
+	}
+	if data.times, err = s.repo.GetTimes(ctx, userID); err != nil {
+		return nil, err
+	}
+	if data.timeBoxes, err = s.repo.GetTimeBoxes(ctx, userID); err != nil {
+		return nil, err
+	}
+	if data.folders, err = s.repo.GetFolders(ctx, userID); err != nil {
+		return nil, err
+	}
+	if data.notes, err = s.repo.GetNotes(ctx, userID); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// DeleteUserExports removes the user's exports and their archives, before
+// the account itself is deleted. Like for expired exports, archives that
+// could not be deleted are only logged.
+func (s *ExportService) DeleteUserExports(ctx context.Context, userID string) error {
+	keys, err := s.repo.DeleteByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	s.deleteBlobs(ctx, keys)
+	return nil
+}
+
+// deleteExpired removes archives past their retention. Failures are only
+// logged; the blob of a deleted row is not retried.
+func (s *ExportService) deleteExpired(ctx context.Context) {
+	keys, err := s.repo.DeleteExpired(ctx)
+	if err != nil {
+		log.Printf("exports: %v", err)
+		return
+	}
+	s.deleteBlobs(ctx, keys)
+}
+
+// deleteBlobs finishes even when ctx is cancelled, since the rows of the
+// blobs are already gone.
+func (s *ExportService) deleteBlobs(ctx context.Context, keys []string) {
+	ctx = context.WithoutCancel(ctx)
+	for _, key := range keys {
+		if err := s.store.Delete(ctx, key); err != nil {
+			log.Printf("exports: failed to delete blob %s: %v", key, err)
+		}
+	}
+}
+
+// sign fills in a download URL that is valid for an hour. The URL is
+// relative to the API root.
+func (s *ExportService) sign(export *models.DataExport) {
+	expires := time.Now().Add(exportURLTTL).Unix()
+	export.URL = fmt.Sprintf("/me/export/%d/download?expires=%d&signature=%s",
+		export.ID, expires, hex.EncodeToString(s.signature(export.ID, expires)))
+	urlExpires := time.Unix(expires, 0).UTC()
+	export.URLExpires = &urlExpires
+}
+
+// signature covers the "export" prefix so that attachment signatures, made
+// with the same secret, cannot be replayed here.
+func (s *ExportService) signature(id int, expires int64) []byte {
+	mac := hmac.New(sha256.New, s.urlSecret)
+	fmt.Fprintf(mac, "export:%d:%d", id, expires)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,71 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	firebase "firebase.google.com/go"
+	"firebase.google.com/go/auth"
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+)
+
+const tokenCacheSize = 10000
+
+// FirebaseAuthenticator checks Firebase ID tokens. Verified tokens are
+// cached until they expire, so only the first request with a token pays for
+// the verification.
+type FirebaseAuthenticator struct {
+	client *auth.Client
+	cache  *tokenCache
+}
+
+func NewFirebaseAuthenticator(app *firebase.App) (*FirebaseAuthenticator, error) {
+	client, err := app.Auth(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("error initializing Firebase auth client: %w", err)
+	}
+	return &FirebaseAuthenticator{client: client, cache: newTokenCache(tokenCacheSize)}, nil
+}
+
+func (v *FirebaseAuthenticator) Verify(ctx context.Context, idToken string) (*models.Identity, error) {
+	if identity, ok := v.cache.get(idToken); ok {
+		return identity, nil
+	}
+
+	token, err := v.client.VerifyIDToken(ctx, idToken)
+	if err != nil {
+		if isAuthBackendError(err) {
+			return nil, fmt.Errorf("%w: %v", models.ErrAuthUnavailable, err)
+		}
+		return nil, fmt.Errorf("%w: %v", models.ErrInvalidToken, err)
+	}
+
+	identity := models.Identity{UID: token.UID}
+	identity.Email, _ = token.Claims["email"].(string)
+	identity.Name, _ = token.Claims["name"].(string)
+
+	v.cache.put(idToken, identity, time.Unix(token.Expires, 0))
+	return &identity, nil
+}
+
+// DeleteIdentity deletes the Firebase user and forgets its cached tokens.
+func (v *FirebaseAuthenticator) DeleteIdentity(ctx context.Context, uid string) error {
+	if err := v.client.DeleteUser(ctx, uid); err != nil && !auth.IsUserNotFound(err) {
+		return fmt.Errorf("failed to delete Firebase user: %w", err)
+	}
+	v.cache.removeUser(uid)
+	return nil
+}
+
+// isAuthBackendError tells failures to fetch the Firebase signing keys
+// apart from token errors. The Firebase SDK does not type its errors.
+func isAuthBackendError(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		strings.Contains(err.Error(), "while retrieving public keys")
+}
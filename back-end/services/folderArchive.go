@@ -0,0 +1,356 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/RiadMefti/TimeTracker/back-end/events"
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/tiptap"
+	"github.com/RiadMefti/TimeTracker/back-end/utils"
+)
+
+const (
+	maxArchiveEntries = 10000
+	maxArchiveBytes   = 100 << 20
+)
+
+// FolderArchive is a snapshot of a folder subtree ready to be written as a
+// zip. It is loaded up front so that lookup errors can still be reported
+// before the response starts streaming.
+type FolderArchive struct {
+	Root     *models.Folder
+	children map[int][]*models.Folder
+	notes    map[int][]*models.Note
+}
+
+func (s *FolderService) GetFolderArchive(ctx context.Context, id int, userID string) (*FolderArchive, error) {
+	root, err := s.repo.GetByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	folders, err := s.repo.GetAllByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	notes, err := s.noteRepo.GetAllByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &FolderArchive{
+		Root:     root,
+		children: map[int][]*models.Folder{},
+		notes:    map[int][]*models.Note{},
+	}
+	for _, folder := range folders {
+		if folder.ParentID != nil {
+			archive.children[*folder.ParentID] = append(archive.children[*folder.ParentID], folder)
+		}
+	}
+	for _, note := range notes {
+		if note.FolderID != nil {
+			archive.notes[*note.FolderID] = append(archive.notes[*note.FolderID], note)
+		}
+	}
+	return archive, nil
+}
+
+// Write streams the archive as a zip whose directories mirror the folder
+// tree. Each note is written as Markdown plus a JSON sidecar.
+func (a *FolderArchive) Write(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	if err := a.writeFolder(zw, a.Root, utils.SafeFileName(a.Root.Name, "folder")); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func (a *FolderArchive) writeFolder(zw *zip.Writer, folder *models.Folder, dir string) error {
+	if _, err := zw.Create(dir + "/"); err != nil {
+		return err
+	}
+
+	used := map[string]bool{}
+	for _, note := range a.notes[folder.ID] {
+		name := uniqueName(utils.SafeFileName(note.Title, "note"), used)
+		if err := writeArchiveNote(zw, note, path.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+
+	for _, child := range a.children[folder.ID] {
+		name := uniqueName(utils.SafeFileName(child.Name, "folder"), used)
+		if err := a.writeFolder(zw, child, path.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeArchiveNote(zw *zip.Writer, note *models.Note, base string) error {
+	markdown := note.Content
+	if doc, err := tiptap.ParseStored(note.Content); err == nil {
+		markdown = tiptap.ToMarkdown(doc)
+	}
+
+	mdFile, err := zw.Create(base + ".md")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(mdFile, markdown); err != nil {
+		return err
+	}
+
+	meta, err := json.MarshalIndent(models.NoteArchiveMeta{
+		ID:      note.ID,
+		Title:   note.Title,
+		Content: note.Content,
+		Created: note.Created,
+		Updated: note.Updated,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	metaFile, err := zw.Create(base + ".json")
+	if err != nil {
+		return err
+	}
+	_, err = metaFile.Write(meta)
+	return err
+}
+
+type archiveNote struct {
+	markdown []byte
+	meta     *models.NoteArchiveMeta
+}
+
+// ImportArchive recreates the folders and notes of a zip produced by
+// FolderArchive.Write under parentID (nil for the root). Folders and notes
+// whose names are already taken are renamed with a numeric suffix. The whole
+// tree is created in one transaction, then the notes are indexed and
+// announced like notes created one by one.
+func (s *FolderService) ImportArchive(ctx context.Context, r io.ReaderAt, size int64, parentID *int, userID string) (*models.ArchiveImportResult, error) {
+	if parentID != nil {
+		if _, err := s.repo.GetByID(ctx, *parentID, userID); err != nil {
+			return nil, fmt.Errorf("parent folder not found")
+		}
+	}
+
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip archive")
+	}
+	if len(zr.File) > maxArchiveEntries {
+		return nil, fmt.Errorf("archive has too many entries")
+	}
+
+	dirs := map[string]bool{}
+	notes := map[string]*archiveNote{}
+	var total int64
+	for _, file := range zr.File {
+		name, ok := cleanArchivePath(file.Name)
+		if !ok {
+			return nil, fmt.Errorf("invalid path in archive: %s", file.Name)
+		}
+		if name == "" {
+			continue
+		}
+		if file.FileInfo().IsDir() {
+			dirs[name] = true
+			continue
+		}
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+
+		ext := strings.ToLower(path.Ext(name))
+		if ext != ".md" && ext != ".json" {
+			continue
+		}
+		data, err := readArchiveFile(file, maxArchiveBytes-total)
+		if err != nil {
+			return nil, err
+		}
+		total += int64(len(data))
+
+		key := strings.TrimSuffix(name, path.Ext(name))
+		note := notes[key]
+		if note == nil {
+			note = &archiveNote{}
+			notes[key] = note
+		}
+		if ext == ".md" {
+			note.markdown = data
+		} else {
+			var meta models.NoteArchiveMeta
+			if err := json.Unmarshal(data, &meta); err != nil {
+				return nil, fmt.Errorf("invalid note metadata in %s", file.Name)
+			}
+			note.meta = &meta
+		}
+	}
+
+	taken := map[string]map[string]bool{}
+	siblingNames := func(dir string, folderID *int) (map[string]bool, error) {
+		if names, ok := taken[dir]; ok {
+			return names, nil
+		}
+		names := map[string]bool{}
+		folders, err := s.repo.GetByParent(ctx, folderID, userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, folder := range folders {
+			names[folder.Name] = true
+		}
+		notes, err := s.noteRepo.GetByFolder(ctx, folderID, userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, note := range notes {
+			names[note.Title] = true
+		}
+		taken[dir] = names
+		return names, nil
+	}
+
+	// Parents sort before their children, so each directory's parent is
+	// planned by the time it is needed.
+	sortedDirs := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		sortedDirs = append(sortedDirs, dir)
+	}
+	sort.Strings(sortedDirs)
+
+	// Folders are referenced by their index in folders, -1 being parentID.
+	var folders []models.ArchiveFolder
+	folderIndex := map[string]int{".": -1}
+	folderIDs := map[string]*int{".": parentID}
+	for _, dir := range sortedDirs {
+		parent := path.Dir(dir)
+		names, err := siblingNames(parent, folderIDs[parent])
+		if err != nil {
+			return nil, err
+		}
+		folderIndex[dir] = len(folders)
+		folders = append(folders, models.ArchiveFolder{
+			Name:   uniqueName(path.Base(dir), names),
+			Parent: folderIndex[parent],
+		})
+		taken[dir] = map[string]bool{}
+	}
+
+	keys := make([]string, 0, len(notes))
+	for key := range notes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	archiveNotes := make([]models.ArchiveNote, 0, len(keys))
+	for _, key := range keys {
+		note := notes[key]
+		title := path.Base(key)
+		var content string
+		switch {
+		case note.meta != nil:
+			if note.meta.Title != "" {
+				title = note.meta.Title
+			}
+			content = note.meta.Content
+		case note.markdown != nil:
+			doc, err := tiptap.FromMarkdown(note.markdown)
+			if err != nil {
+				return nil, err
+			}
+			content = doc.String()
+		}
+
+		dir := path.Dir(key)
+		names, err := siblingNames(dir, folderIDs[dir])
+		if err != nil {
+			return nil, err
+		}
+		archiveNotes = append(archiveNotes, models.ArchiveNote{
+			Title:   uniqueName(title, names),
+			Content: content,
+			Folder:  folderIndex[dir],
+		})
+	}
+
+	createdFolders, createdNotes, err := s.repo.ImportTree(ctx, parentID, folders, archiveNotes, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ArchiveImportResult{
+		Folders:     []*models.Folder{},
+		FolderCount: len(createdFolders),
+		NoteCount:   len(createdNotes),
+	}
+	for i, folder := range createdFolders {
+		if folders[i].Parent < 0 {
+			result.Folders = append(result.Folders, folder)
+		}
+		s.bus.Publish(userID, events.FolderCreated, folder)
+	}
+	for _, note := range createdNotes {
+		s.noteService.noteCreated(ctx, note)
+	}
+
+	return result, nil
+}
+
+// cleanArchivePath normalises a zip entry name and rejects names that would
+// escape the archive root.
+func cleanArchivePath(name string) (string, bool) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	if strings.HasPrefix(name, "/") {
+		return "", false
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return "", false
+		}
+	}
+	cleaned := path.Clean(name)
+	if cleaned == "." {
+		return "", true
+	}
+	return cleaned, true
+}
+
+func readArchiveFile(file *zip.File, remaining int64) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file.Name, err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, remaining+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file.Name, err)
+	}
+	if int64(len(data)) > remaining {
+		return nil, fmt.Errorf("archive is too large")
+	}
+	return data, nil
+}
+
+// uniqueName returns name, or name with the first free " (n)" suffix, and
+// records the result as taken.
+func uniqueName(name string, taken map[string]bool) string {
+	candidate := name
+	for i := 2; taken[candidate]; i++ {
+		candidate = fmt.Sprintf("%s (%d)", name, i)
+	}
+	taken[candidate] = true
+	return candidate
+}
@@ -1,20 +1,25 @@
 package services
 
 import (
+	"context"
 	"fmt"
+	"github.com/RiadMefti/TimeTracker/back-end/events"
 	"github.com/RiadMefti/TimeTracker/back-end/models"
 	"github.com/RiadMefti/TimeTracker/back-end/repositories"
 )
 
 type FolderService struct {
-	repo *repositories.FolderRepository
+	repo        *repositories.FolderRepository
+	noteRepo    *repositories.NoteRepository
+	noteService *NoteService
+	bus         *events.Bus
 }
 
-func NewFolderService(repo *repositories.FolderRepository) *FolderService {
-	return &FolderService{repo: repo}
+func NewFolderService(repo *repositories.FolderRepository, noteRepo *repositories.NoteRepository, noteService *NoteService, bus *events.Bus) *FolderService {
+	return &FolderService{repo: repo, noteRepo: noteRepo, noteService: noteService, bus: bus}
 }
 
-func (s *FolderService) CreateFolder(folder *models.FolderCreate, userID string) (*models.Folder, error) {
+func (s *FolderService) CreateFolder(ctx context.Context, folder *models.FolderCreate, userID string) (*models.Folder, error) {
 	// Validate folder name
 	if folder.Name == "" {
 		return nil, fmt.Errorf("folder name cannot be empty")
@@ -22,35 +27,40 @@ func (s *FolderService) CreateFolder(folder *models.FolderCreate, userID string)
 
 	// If parent folder is specified, check if it exists and belongs to user
 	if folder.ParentID != nil {
-		_, err := s.repo.GetByID(*folder.ParentID, userID)
+		_, err := s.repo.GetByID(ctx, *folder.ParentID, userID)
 		if err != nil {
 			return nil, fmt.Errorf("parent folder not found")
 		}
 	}
 
-	return s.repo.Create(folder, userID)
+	created, err := s.repo.Create(ctx, folder, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.bus.Publish(userID, events.FolderCreated, created)
+	return created, nil
 }
 
-func (s *FolderService) GetFolder(id int, userID string) (*models.Folder, error) {
-	return s.repo.GetByID(id, userID)
+func (s *FolderService) GetFolder(ctx context.Context, id int, userID string) (*models.Folder, error) {
+	return s.repo.GetByID(ctx, id, userID)
 }
 
-func (s *FolderService) GetAllFolders(userID string) ([]*models.Folder, error) {
-	return s.repo.GetAllByUser(userID)
+func (s *FolderService) GetAllFolders(ctx context.Context, userID string) ([]*models.Folder, error) {
+	return s.repo.GetAllByUser(ctx, userID)
 }
 
-func (s *FolderService) GetFoldersByParent(parentID *int, userID string) ([]*models.Folder, error) {
-	return s.repo.GetByParent(parentID, userID)
+func (s *FolderService) GetFoldersByParent(ctx context.Context, parentID *int, userID string) ([]*models.Folder, error) {
+	return s.repo.GetByParent(ctx, parentID, userID)
 }
 
-func (s *FolderService) UpdateFolder(id int, folder *models.FolderUpdate, userID string) (*models.Folder, error) {
+func (s *FolderService) UpdateFolder(ctx context.Context, id int, folder *models.FolderUpdate, userID string, expectedVersion *int) (*models.Folder, error) {
 	// Validate folder name
 	if folder.Name == "" {
 		return nil, fmt.Errorf("folder name cannot be empty")
 	}
 
 	// Check if folder exists and belongs to user
-	_, err := s.repo.GetByID(id, userID)
+	_, err := s.repo.GetByID(ctx, id, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -62,21 +72,34 @@ func (s *FolderService) UpdateFolder(id int, folder *models.FolderUpdate, userID
 			return nil, fmt.Errorf("a folder cannot be its own parent")
 		}
 
-		_, err := s.repo.GetByID(*folder.ParentID, userID)
+		_, err := s.repo.GetByID(ctx, *folder.ParentID, userID)
 		if err != nil {
 			return nil, fmt.Errorf("parent folder not found")
 		}
 	}
 
-	return s.repo.Update(id, folder, userID)
+	updated, err := s.repo.Update(ctx, id, folder, userID, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+	s.bus.Publish(userID, events.FolderUpdated, updated)
+	return updated, nil
+}
+
+func (s *FolderService) ReorderFolders(ctx context.Context, order *models.FolderOrder, userID string) error {
+	return s.repo.Reorder(ctx, order.ParentID, order.FolderIDs, userID)
 }
 
-func (s *FolderService) DeleteFolder(id int, userID string) error {
+func (s *FolderService) DeleteFolder(ctx context.Context, id int, userID string) error {
 	// Check if folder exists and belongs to user
-	_, err := s.repo.GetByID(id, userID)
+	_, err := s.repo.GetByID(ctx, id, userID)
 	if err != nil {
 		return err
 	}
 
-	return s.repo.Delete(id, userID)
+	if err := s.repo.Delete(ctx, id, userID); err != nil {
+		return err
+	}
+	s.bus.Publish(userID, events.FolderDeleted, events.Ref{ID: id})
+	return nil
 }
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"database/sql"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/repositories"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	minPasswordLength = 8
+	// maxPasswordLength is the most bcrypt takes into account.
+	maxPasswordLength = 72
+)
+
+// dummyPasswordHash is compared against on logins with an unknown email, so
+// that they take as long as logins with a wrong password.
+const dummyPasswordHash = "$2a$10$GEUZYTu/dkRrhMfno8d59uEjFs24hGNNJbP5jNoAVaeU1MpSuMNVi"
+
+// LocalAuthConfig configures the local auth provider. Algorithm is HS256,
+// signing with Secret, or RS256, signing with the PEM RSA key in
+// PrivateKeyFile.
+type LocalAuthConfig struct {
+	Algorithm      string
+	Secret         string
+	PrivateKeyFile string
+	Issuer         string
+	TTL            time.Duration
+}
+
+// LocalAuthenticator keeps accounts in the users table, with bcrypt hashed
+// passwords, and issues its own JWTs. It lets the backend run without any
+// external identity provider, for development and tests.
+type LocalAuthenticator struct {
+	users     *repositories.UserRepository
+	config    LocalAuthConfig
+	algorithm jose.SignatureAlgorithm
+	signer    jose.Signer
+	verifyKey interface{}
+}
+
+type localClaims struct {
+	Email string `json:"email"`
+}
+
+func NewLocalAuthenticator(users *repositories.UserRepository, config LocalAuthConfig) (*LocalAuthenticator, error) {
+	a := &LocalAuthenticator{users: users, config: config}
+
+	var signingKey interface{}
+	switch config.Algorithm {
+	case "HS256":
+		if len(config.Secret) < 32 {
+			return nil, fmt.Errorf("AUTH_JWT_SECRET must be at least 32 characters")
+		}
+		a.algorithm = jose.HS256
+		signingKey = []byte(config.Secret)
+		a.verifyKey = []byte(config.Secret)
+	case "RS256":
+		key, err := readRSAPrivateKey(config.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		a.algorithm = jose.RS256
+		signingKey = key
+		a.verifyKey = &key.PublicKey
+	default:
+		return nil, fmt.Errorf("unsupported AUTH_JWT_ALG %q", config.Algorithm)
+	}
+
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: a.algorithm, Key: signingKey}, (&jose.SignerOptions{}).WithType("JWT"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWT signer: %w", err)
+	}
+	a.signer = signer
+	return a, nil
+}
+
+func (a *LocalAuthenticator) Verify(ctx context.Context, token string) (*models.Identity, error) {
+	parsed, err := jwt.ParseSigned(token, []jose.SignatureAlgorithm{a.algorithm})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrInvalidToken, err)
+	}
+
+	var claims jwt.Claims
+	var extra localClaims
+	if err := parsed.Claims(a.verifyKey, &claims, &extra); err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrInvalidToken, err)
+	}
+	if err := claims.Validate(jwt.Expected{Issuer: a.config.Issuer, Time: time.Now()}); err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrInvalidToken, err)
+	}
+	if claims.Subject == "" || claims.Expiry == nil {
+		return nil, fmt.Errorf("%w: missing sub or exp claim", models.ErrInvalidToken)
+	}
+
+	return &models.Identity{UID: claims.Subject, Email: extra.Email}, nil
+}
+
+// DeleteIdentity does nothing: local identities are the users rows, which
+// are deleted with the account.
+func (a *LocalAuthenticator) DeleteIdentity(ctx context.Context, uid string) error {
+	return nil
+}
+
+// Signup creates an account and returns a token for it.
+func (a *LocalAuthenticator) Signup(ctx context.Context, credentials *models.Credentials) (*models.AuthToken, error) {
+	address, err := mail.ParseAddress(strings.TrimSpace(credentials.Email))
+	if err != nil || address.Name != "" {
+		return nil, fmt.Errorf("invalid email address")
+	}
+	if len(credentials.Password) < minPasswordLength || len(credentials.Password) > maxPasswordLength {
+		return nil, fmt.Errorf("password must be between %d and %d characters", minPasswordLength, maxPasswordLength)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(credentials.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("failed to generate user ID: %w", err)
+	}
+
+	user := models.User{ID: hex.EncodeToString(id), Email: strings.ToLower(address.Address)}
+	if err := a.users.CreateLocalUser(ctx, user, string(hash)); err != nil {
+		return nil, err
+	}
+	return a.issue(user)
+}
+
+// Login checks the credentials and returns a new token for the account.
+func (a *LocalAuthenticator) Login(ctx context.Context, credentials *models.Credentials) (*models.AuthToken, error) {
+	user, hash, err := a.users.GetByEmail(ctx, strings.TrimSpace(credentials.Email))
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if hash == "" {
+		bcrypt.CompareHashAndPassword([]byte(dummyPasswordHash), []byte(credentials.Password))
+		return nil, models.ErrInvalidCredentials
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(credentials.Password)) != nil {
+		return nil, models.ErrInvalidCredentials
+	}
+	return a.issue(user)
+}
+
+func (a *LocalAuthenticator) issue(user models.User) (*models.AuthToken, error) {
+	now := time.Now()
+	expires := now.Add(a.config.TTL)
+	claims := jwt.Claims{
+		Subject:  user.ID,
+		Issuer:   a.config.Issuer,
+		IssuedAt: jwt.NewNumericDate(now),
+		Expiry:   jwt.NewNumericDate(expires),
+	}
+
+	token, err := jwt.Signed(a.signer).Claims(claims).Claims(localClaims{Email: user.Email}).Serialize()
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign token: %w", err)
+	}
+	return &models.AuthToken{Token: token, ExpiresAt: time.Unix(expires.Unix(), 0), User: user}, nil
+}
+
+func readRSAPrivateKey(path string) (*rsa.PrivateKey, error) {
+	if path == "" {
+		return nil, fmt.Errorf("AUTH_JWT_PRIVATE_KEY_FILE is required for RS256")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT private key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("JWT private key is not PEM encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("JWT private key is not an RSA key")
+	}
+	return key, nil
+}
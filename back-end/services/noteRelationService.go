@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/repositories"
+)
+
+type NoteRelationService struct {
+	relationRepo     *repositories.NoteRelationRepository
+	noteRepo         *repositories.NoteRepository
+	projectRepo      *repositories.ProjectRepository
+	timeEntryRepo    *repositories.TimeEntryRepository
+	timeBoxEntryRepo *repositories.TimeBoxEntryRepository
+}
+
+func NewNoteRelationService(
+	relationRepo *repositories.NoteRelationRepository,
+	noteRepo *repositories.NoteRepository,
+	projectRepo *repositories.ProjectRepository,
+	timeEntryRepo *repositories.TimeEntryRepository,
+	timeBoxEntryRepo *repositories.TimeBoxEntryRepository,
+) *NoteRelationService {
+	return &NoteRelationService{
+		relationRepo:     relationRepo,
+		noteRepo:         noteRepo,
+		projectRepo:      projectRepo,
+		timeEntryRepo:    timeEntryRepo,
+		timeBoxEntryRepo: timeBoxEntryRepo,
+	}
+}
+
+func (s *NoteRelationService) Attach(ctx context.Context, target repositories.NoteRelationTarget, noteID int, targetID int, userID string) (*models.NoteRelations, error) {
+	// Check if note and target exist and belong to user
+	if _, err := s.noteRepo.GetByID(ctx, noteID, userID); err != nil {
+		return nil, err
+	}
+	if err := s.checkTarget(ctx, target, targetID, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.relationRepo.Attach(ctx, target, noteID, targetID); err != nil {
+		return nil, err
+	}
+	return s.relationRepo.GetRelations(ctx, noteID)
+}
+
+func (s *NoteRelationService) Detach(ctx context.Context, target repositories.NoteRelationTarget, noteID int, targetID int, userID string) (*models.NoteRelations, error) {
+	// Check if note exists and belongs to user
+	if _, err := s.noteRepo.GetByID(ctx, noteID, userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.relationRepo.Detach(ctx, target, noteID, targetID); err != nil {
+		return nil, err
+	}
+	return s.relationRepo.GetRelations(ctx, noteID)
+}
+
+func (s *NoteRelationService) GetRelations(ctx context.Context, noteID int, userID string) (*models.NoteRelations, error) {
+	// Check if note exists and belongs to user
+	if _, err := s.noteRepo.GetByID(ctx, noteID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.relationRepo.GetRelations(ctx, noteID)
+}
+
+func (s *NoteRelationService) GetProjectNotes(ctx context.Context, projectID int, userID string) ([]*models.NoteStub, error) {
+	if err := s.checkTarget(ctx, repositories.NoteProjectRelation, projectID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.relationRepo.GetNoteStubs(ctx, repositories.NoteProjectRelation, projectID, userID)
+}
+
+func (s *NoteRelationService) checkTarget(ctx context.Context, target repositories.NoteRelationTarget, targetID int, userID string) error {
+	var err error
+	switch target {
+	case repositories.NoteProjectRelation:
+		_, err = s.projectRepo.GetUserProject(ctx, targetID, userID)
+	case repositories.NoteTimeEntryRelation:
+		_, err = s.timeEntryRepo.GetTimeEntry(ctx, targetID, userID)
+	case repositories.NoteTimeBoxRelation:
+		_, err = s.timeBoxEntryRepo.GetTimeBoxEntry(ctx, targetID, userID)
+	}
+	if err != nil {
+		return fmt.Errorf("%s not found", target.Label())
+	}
+	return nil
+}
@@ -1,61 +1,255 @@
 package services
 
 import (
+	"context"
 	"fmt"
+	"log"
+	"strings"
+
+	"github.com/RiadMefti/TimeTracker/back-end/events"
 	"github.com/RiadMefti/TimeTracker/back-end/models"
 	"github.com/RiadMefti/TimeTracker/back-end/repositories"
+	"github.com/RiadMefti/TimeTracker/back-end/tiptap"
 )
 
 type NoteService struct {
-	repo *repositories.NoteRepository
+	repo     *repositories.NoteRepository
+	linkRepo *repositories.NoteLinkRepository
+	taskRepo *repositories.NoteTaskRepository
+	bus      *events.Bus
 }
 
-func NewNoteService(repo *repositories.NoteRepository) *NoteService {
-	return &NoteService{repo: repo}
+func NewNoteService(repo *repositories.NoteRepository, linkRepo *repositories.NoteLinkRepository, taskRepo *repositories.NoteTaskRepository, bus *events.Bus) *NoteService {
+	return &NoteService{repo: repo, linkRepo: linkRepo, taskRepo: taskRepo, bus: bus}
 }
 
-func (s *NoteService) CreateNote(note *models.NoteCreate, userID string) (*models.Note, error) {
+func (s *NoteService) CreateNote(ctx context.Context, note *models.NoteCreate, userID string) (*models.Note, error) {
 	// Validate note title
 	if note.Title == "" {
 		return nil, fmt.Errorf("note title cannot be empty")
 	}
 
-	return s.repo.Create(note, userID)
+	tags, err := normalizeTags(note.Tags)
+	if err != nil {
+		return nil, err
+	}
+	note.Tags = tags
+
+	created, err := s.repo.Create(ctx, note, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.noteCreated(ctx, created)
+	return created, nil
+}
+
+// noteCreated indexes and announces a new note. Archive imports and daily
+// notes create theirs without CreateNote and call it themselves.
+func (s *NoteService) noteCreated(ctx context.Context, note *models.Note) {
+	s.indexSaved(ctx, note)
+	s.bus.Publish(note.UserID, events.NoteCreated, note)
 }
 
-func (s *NoteService) GetNote(id int, userID string) (*models.Note, error) {
-	return s.repo.GetByID(id, userID)
+func (s *NoteService) GetNote(ctx context.Context, id int, userID string) (*models.Note, error) {
+	return s.repo.GetByID(ctx, id, userID)
 }
 
-func (s *NoteService) GetAllNotes(userID string) ([]*models.Note, error) {
-	return s.repo.GetAllByUser(userID)
+// GetNotes returns the user's notes, narrowed by filter. Tag names match
+// case-insensitively.
+func (s *NoteService) GetNotes(ctx context.Context, filter *models.NoteFilter, userID string) ([]*models.Note, error) {
+	tags, err := normalizeTags(filter.Tags)
+	if err != nil {
+		return nil, err
+	}
+	for i, tag := range tags {
+		tags[i] = strings.ToLower(tag)
+	}
+
+	return s.repo.GetFiltered(ctx, &models.NoteFilter{Tags: tags, Pinned: filter.Pinned}, userID)
 }
 
-func (s *NoteService) GetNotesByFolder(folderID *int, userID string) ([]*models.Note, error) {
-	return s.repo.GetByFolder(folderID, userID)
+func (s *NoteService) GetNotesByFolder(ctx context.Context, folderID *int, userID string) ([]*models.Note, error) {
+	return s.repo.GetByFolder(ctx, folderID, userID)
 }
 
-func (s *NoteService) UpdateNote(id int, note *models.NoteUpdate, userID string) (*models.Note, error) {
+func (s *NoteService) UpdateNote(ctx context.Context, id int, note *models.NoteUpdate, userID string, expectedVersion *int) (*models.Note, error) {
 	// Validate note title
 	if note.Title == "" {
 		return nil, fmt.Errorf("note title cannot be empty")
 	}
 
+	if note.Tags != nil {
+		tags, err := normalizeTags(*note.Tags)
+		if err != nil {
+			return nil, err
+		}
+		note.Tags = &tags
+	}
+
 	// Check if note exists and belongs to user
-	_, err := s.repo.GetByID(id, userID)
+	_, err := s.repo.GetByID(ctx, id, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	return s.repo.Update(id, note, userID)
+	updated, err := s.repo.Update(ctx, id, note, userID, expectedVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	s.indexSaved(ctx, updated)
+	s.bus.Publish(userID, events.NoteUpdated, updated)
+	return updated, nil
 }
 
-func (s *NoteService) DeleteNote(id int, userID string) error {
-	// Check if note exists and belongs to user
-	_, err := s.repo.GetByID(id, userID)
+// SaveContent stores content written by a collaborative editing session and
+// returns the new note version. Title and folder are left alone.
+func (s *NoteService) SaveContent(ctx context.Context, id int, content string, userID string) (int, error) {
+	if _, err := tiptap.Parse(content); err != nil {
+		return 0, err
+	}
+
+	updated, err := s.repo.UpdateContent(ctx, id, content, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	s.indexSaved(ctx, updated)
+	s.bus.Publish(userID, events.NoteUpdated, updated)
+	return updated.Version, nil
+}
+
+func (s *NoteService) SetPinned(ctx context.Context, id int, pinned bool, userID string) (*models.Note, error) {
+	note, err := s.repo.SetPinned(ctx, id, pinned, userID)
 	if err != nil {
+		return nil, err
+	}
+	s.bus.Publish(userID, events.NoteUpdated, note)
+	return note, nil
+}
+
+func (s *NoteService) ReorderNotes(ctx context.Context, order *models.NoteOrder, userID string) error {
+	return s.repo.Reorder(ctx, order.FolderID, order.NoteIDs, userID)
+}
+
+// indexContent refreshes the link and task indexes for the note's current
+// content. Content that is not a TipTap document has no links or tasks.
+func (s *NoteService) indexContent(ctx context.Context, note *models.Note) error {
+	var links []models.NoteLink
+	var tasks []models.NoteTask
+	if doc, err := tiptap.ParseStored(note.Content); err == nil {
+		for _, link := range tiptap.NoteLinks(doc) {
+			links = append(links, models.NoteLink{TargetID: link.TargetID, Context: link.Context})
+		}
+		for _, item := range tiptap.TaskItems(doc) {
+			tasks = append(tasks, models.NoteTask{Path: item.Path, Text: item.Text, Checked: item.Checked})
+		}
+	}
+
+	if err := s.linkRepo.ReplaceLinks(ctx, note.ID, links, note.UserID); err != nil {
+		return err
+	}
+	if err := s.taskRepo.ReplaceTasks(ctx, note.ID, tasks); err != nil {
 		return err
 	}
+	return s.repo.MarkIndexed(ctx, note.ID, note.Version)
+}
+
+// indexSaved indexes a note whose write is already committed. A failure is
+// only logged: the note stays marked as not indexed and IndexUnindexedNotes
+// picks it up on the next start.
+func (s *NoteService) indexSaved(ctx context.Context, note *models.Note) {
+	if err := s.indexContent(ctx, note); err != nil {
+		log.Printf("failed to index note %d: %v", note.ID, err)
+	}
+}
+
+const indexBatchSize = 100
+
+// IndexUnindexedNotes indexes the links and tasks of every note whose
+// current version is not indexed: notes written before the indexes existed,
+// and notes whose indexing failed after they were saved. It is safe to run
+// on every start.
+func (s *NoteService) IndexUnindexedNotes(ctx context.Context) {
+	afterID := 0
+	for ctx.Err() == nil {
+		notes, err := s.repo.GetUnindexed(ctx, afterID, indexBatchSize)
+		if err != nil {
+			log.Printf("note index backfill failed: %v", err)
+			return
+		}
+		if len(notes) == 0 {
+			return
+		}
+		for _, note := range notes {
+			if err := s.indexContent(ctx, note); err != nil {
+				log.Printf("note index backfill failed for note %d: %v", note.ID, err)
+			}
+			afterID = note.ID
+		}
+	}
+}
+
+func (s *NoteService) GetBacklinks(ctx context.Context, id int, userID string) ([]*models.Backlink, error) {
+	// Check if note exists and belongs to user
+	_, err := s.repo.GetByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.linkRepo.GetBacklinks(ctx, id, userID)
+}
+
+// DeleteNote moves the note to the trash and reports the notes whose links
+// to it are now dangling.
+func (s *NoteService) DeleteNote(ctx context.Context, id int, userID string) (*models.NoteDeleteResult, error) {
+	// Check if note exists and belongs to user
+	_, err := s.repo.GetByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	backlinks, err := s.linkRepo.GetBacklinks(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Delete(ctx, id, userID); err != nil {
+		return nil, err
+	}
+	s.bus.Publish(userID, events.NoteDeleted, events.Ref{ID: id})
+	return &models.NoteDeleteResult{DanglingLinks: backlinks}, nil
+}
+
+// ExportNote renders the note content as Markdown ("md") or as a standalone
+// HTML page ("html").
+func (s *NoteService) ExportNote(note *models.Note, format string) (string, error) {
+	doc, err := tiptap.ParseStored(note.Content)
+	if err != nil {
+		return "", err
+	}
+
+	switch format {
+	case "md":
+		return tiptap.ToMarkdown(doc), nil
+	case "html":
+		return tiptap.ToHTMLDocument(note.Title, doc), nil
+	}
+	return "", fmt.Errorf("unsupported export format %q", format)
+}
+
+// ImportMarkdown converts a Markdown file to TipTap content and stores it as
+// a new note.
+func (s *NoteService) ImportMarkdown(ctx context.Context, title string, markdown []byte, folderID *int, userID string) (*models.Note, error) {
+	doc, err := tiptap.FromMarkdown(markdown)
+	if err != nil {
+		return nil, err
+	}
 
-	return s.repo.Delete(id, userID)
+	return s.CreateNote(ctx, &models.NoteCreate{
+		Title:    title,
+		Content:  doc.String(),
+		FolderID: folderID,
+	}, userID)
 }
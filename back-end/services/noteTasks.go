@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/tiptap"
+)
+
+// GetTasks returns the task items of the user's notes. status is "open",
+// "done" or "all".
+func (s *NoteService) GetTasks(ctx context.Context, status string, userID string) ([]*models.NoteTask, error) {
+	var checked *bool
+	switch status {
+	case "", "all":
+	case "open":
+		checked = new(bool)
+	case "done":
+		checked = new(bool)
+		*checked = true
+	default:
+		return nil, fmt.Errorf("%w %q", models.ErrUnknownTaskStatus, status)
+	}
+	return s.taskRepo.GetTasks(ctx, checked, userID)
+}
+
+// UpdateTask checks or unchecks a task item by rewriting its note. The note
+// is written against the version the task was read from, so a concurrent
+// edit of the note yields models.ErrVersionConflict instead of being lost.
+func (s *NoteService) UpdateTask(ctx context.Context, id int, update *models.NoteTaskUpdate, userID string) (*models.NoteTask, error) {
+	task, err := s.taskRepo.GetByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	note, err := s.repo.GetByID(ctx, task.NoteID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	checked := !task.Checked
+	if update.Checked != nil {
+		checked = *update.Checked
+	}
+
+	doc, err := tiptap.ParseStored(note.Content)
+	if err != nil {
+		return nil, err
+	}
+	if !taskMatches(doc, task) {
+		return nil, fmt.Errorf("task has changed since it was indexed")
+	}
+	if err := tiptap.SetTaskChecked(doc, task.Path, checked); err != nil {
+		return nil, err
+	}
+
+	version := note.Version
+	_, err = s.UpdateNote(ctx, note.ID, &models.NoteUpdate{
+		Title:    note.Title,
+		Content:  doc.String(),
+		FolderID: note.FolderID,
+	}, userID, &version)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.taskRepo.GetByPath(ctx, note.ID, task.Path, userID)
+}
+
+// taskMatches reports whether the document still has the indexed task at
+// its path.
+func taskMatches(doc *tiptap.Node, task *models.NoteTask) bool {
+	for _, item := range tiptap.TaskItems(doc) {
+		if item.Path == task.Path {
+			return item.Text == task.Text
+		}
+	}
+	return false
+}
@@ -0,0 +1,175 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+	// Embed the zone database so user timezones resolve on hosts without one.
+	_ "time/tzdata"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/repositories"
+	"github.com/RiadMefti/TimeTracker/back-end/tiptap"
+)
+
+const dateLayout = "2006-01-02"
+
+type NoteTemplateService struct {
+	notes       *NoteService
+	noteRepo    *repositories.NoteRepository
+	dailyRepo   *repositories.DailyNoteRepository
+	folderRepo  *repositories.FolderRepository
+	projectRepo *repositories.ProjectRepository
+}
+
+func NewNoteTemplateService(
+	notes *NoteService,
+	noteRepo *repositories.NoteRepository,
+	dailyRepo *repositories.DailyNoteRepository,
+	folderRepo *repositories.FolderRepository,
+	projectRepo *repositories.ProjectRepository,
+) *NoteTemplateService {
+	return &NoteTemplateService{
+		notes:       notes,
+		noteRepo:    noteRepo,
+		dailyRepo:   dailyRepo,
+		folderRepo:  folderRepo,
+		projectRepo: projectRepo,
+	}
+}
+
+func (s *NoteTemplateService) GetTemplates(ctx context.Context, userID string) ([]*models.Note, error) {
+	return s.noteRepo.GetTemplates(ctx, userID)
+}
+
+// CreateFromTemplate creates a note from the template with its placeholders
+// filled in for the current day.
+func (s *NoteTemplateService) CreateFromTemplate(ctx context.Context, templateID int, req *models.NoteFromTemplate, settings *models.UserSettings, userID string) (*models.Note, error) {
+	template, err := s.getTemplate(ctx, templateID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	location := SettingsLocation(settings)
+	if req.Timezone != "" {
+		if location, err = time.LoadLocation(req.Timezone); err != nil {
+			return nil, fmt.Errorf("unknown timezone %q", req.Timezone)
+		}
+	}
+
+	values := placeholderValues(time.Now().In(location))
+	if req.ProjectID != nil {
+		project, err := s.projectRepo.GetUserProject(ctx, *req.ProjectID, userID)
+		if err != nil {
+			return nil, fmt.Errorf("project not found")
+		}
+		values["project"] = project.Name
+	}
+	if req.FolderID != nil {
+		if _, err := s.folderRepo.GetByID(ctx, *req.FolderID, userID); err != nil {
+			return nil, fmt.Errorf("folder not found")
+		}
+	}
+
+	title := req.Title
+	if title == "" {
+		title = template.Title
+	}
+	return s.notes.CreateNote(ctx, &models.NoteCreate{
+		Title:    tiptap.ExpandPlaceholders(title, values),
+		Content:  fillTemplate(template.Content, values),
+		FolderID: req.FolderID,
+	}, userID)
+}
+
+func (s *NoteTemplateService) GetDailySettings(ctx context.Context, userID string) (*models.DailyNoteSettings, error) {
+	return s.dailyRepo.GetSettings(ctx, userID)
+}
+
+func (s *NoteTemplateService) UpdateDailySettings(ctx context.Context, settings *models.DailyNoteSettings, userID string) (*models.DailyNoteSettings, error) {
+	if settings.FolderID != nil {
+		if _, err := s.folderRepo.GetByID(ctx, *settings.FolderID, userID); err != nil {
+			return nil, fmt.Errorf("folder not found")
+		}
+	}
+	if settings.TemplateID != nil {
+		if _, err := s.getTemplate(ctx, *settings.TemplateID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.dailyRepo.SaveSettings(ctx, settings, userID); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// GetOrCreateDailyNote returns the note for today in the user's timezone,
+// creating it from the configured template on the first call of the day.
+func (s *NoteTemplateService) GetOrCreateDailyNote(ctx context.Context, userSettings *models.UserSettings, userID string) (*models.DailyNote, error) {
+	settings, err := s.dailyRepo.GetSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().In(SettingsLocation(userSettings))
+	day := now.Format(dateLayout)
+
+	content := tiptap.NewDoc().String()
+	if settings.TemplateID != nil {
+		// A template that has since been trashed or unflagged falls back to
+		// an empty note rather than blocking the daily note.
+		if template, err := s.getTemplate(ctx, *settings.TemplateID, userID); err == nil {
+			content = fillTemplate(template.Content, placeholderValues(now))
+		}
+	}
+
+	noteID, created, err := s.dailyRepo.GetOrCreate(ctx, day, &models.NoteCreate{
+		Title:    day,
+		Content:  content,
+		FolderID: settings.FolderID,
+	}, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	note, err := s.noteRepo.GetByID(ctx, noteID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		s.notes.noteCreated(ctx, note)
+	}
+	return &models.DailyNote{Date: day, Created: created, Note: note}, nil
+}
+
+func (s *NoteTemplateService) getTemplate(ctx context.Context, id int, userID string) (*models.Note, error) {
+	template, err := s.noteRepo.GetByID(ctx, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("template not found")
+	}
+	if !template.IsTemplate {
+		return nil, fmt.Errorf("note %d is not a template", id)
+	}
+	return template, nil
+}
+
+func placeholderValues(now time.Time) map[string]string {
+	return map[string]string{
+		"date":    now.Format(dateLayout),
+		"time":    now.Format("15:04"),
+		"weekday": now.Weekday().String(),
+		"project": "",
+	}
+}
+
+// fillTemplate expands placeholders in TipTap content. Templates saved as
+// HTML come back as TipTap JSON, and content that cannot be parsed is
+// treated as plain text.
+func fillTemplate(content string, values map[string]string) string {
+	doc, err := tiptap.ParseStored(content)
+	if err != nil {
+		return tiptap.ExpandPlaceholders(content, values)
+	}
+	tiptap.FillPlaceholders(doc, values)
+	return doc.String()
+}
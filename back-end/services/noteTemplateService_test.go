@@ -0,0 +1,56 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+)
+
+// The daily note and template placeholders take the day from the user's
+// timezone, so the day changes at local midnight on either side of a DST
+// change, when the offset to UTC is not the same.
+func TestPlaceholderDayAcrossDST(t *testing.T) {
+	settings := &models.UserSettings{Timezone: "America/New_York"}
+	location := SettingsLocation(settings)
+	if location == time.UTC {
+		t.Skip("timezone data not available")
+	}
+
+	tests := []struct {
+		instant string
+		date    string
+		time    string
+		weekday string
+	}{
+		{"2026-03-08T04:59:00Z", "2026-03-07", "23:59", "Saturday"},
+		{"2026-03-08T05:00:00Z", "2026-03-08", "00:00", "Sunday"},
+		{"2026-03-08T06:59:00Z", "2026-03-08", "01:59", "Sunday"},
+		{"2026-03-08T07:00:00Z", "2026-03-08", "03:00", "Sunday"},
+		{"2026-03-09T03:59:00Z", "2026-03-08", "23:59", "Sunday"},
+		{"2026-03-09T04:00:00Z", "2026-03-09", "00:00", "Monday"},
+		{"2026-11-01T03:59:00Z", "2026-10-31", "23:59", "Saturday"},
+		{"2026-11-01T04:00:00Z", "2026-11-01", "00:00", "Sunday"},
+		{"2026-11-01T05:30:00Z", "2026-11-01", "01:30", "Sunday"},
+		{"2026-11-01T06:30:00Z", "2026-11-01", "01:30", "Sunday"},
+		{"2026-11-02T04:59:00Z", "2026-11-01", "23:59", "Sunday"},
+		{"2026-11-02T05:00:00Z", "2026-11-02", "00:00", "Monday"},
+	}
+	for _, test := range tests {
+		now, err := time.Parse(time.RFC3339, test.instant)
+		if err != nil {
+			t.Fatal(err)
+		}
+		values := placeholderValues(now.In(location))
+		if values["date"] != test.date || values["time"] != test.time || values["weekday"] != test.weekday {
+			t.Errorf("%s: got %s %s %s, want %s %s %s", test.instant,
+				values["date"], values["time"], values["weekday"], test.date, test.time, test.weekday)
+		}
+	}
+}
+
+func TestSettingsLocationFallsBackToUTC(t *testing.T) {
+	if location := SettingsLocation(&models.UserSettings{Timezone: "Not/AZone"}); location != time.UTC {
+		t.Errorf("unknown timezone gave %s, want UTC", location)
+	}
+}
@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/go-jose/go-jose/v4"
+	"github.com/go-jose/go-jose/v4/jwt"
+)
+
+const (
+	// jwksMaxAge is how long keys fetched from a JWKS URL are used before
+	// fetching them again.
+	jwksMaxAge = time.Hour
+	// jwksMinRefresh limits refetches for tokens signed with unknown keys
+	// and retries after failed fetches.
+	jwksMinRefresh = time.Minute
+	jwksMaxBytes   = 1 << 20
+)
+
+var oidcAlgorithms = []jose.SignatureAlgorithm{
+	jose.RS256, jose.RS384, jose.RS512,
+	jose.PS256, jose.PS384, jose.PS512,
+	jose.ES256, jose.ES384, jose.ES512,
+	jose.EdDSA,
+}
+
+// OIDCConfig configures the OpenID Connect provider. JWKS is the path of a
+// JSON Web Key Set file, or an http(s) URL serving one.
+type OIDCConfig struct {
+	Issuer   string
+	Audience string
+	JWKS     string
+}
+
+// OIDCAuthenticator accepts ID tokens of any OpenID Connect provider, checked
+// against the provider's published signing keys.
+type OIDCAuthenticator struct {
+	config OIDCConfig
+	remote bool
+	client *http.Client
+
+	mu        sync.Mutex
+	keys      *jose.JSONWebKeySet
+	fetched   time.Time
+	attempted time.Time
+}
+
+type oidcClaims struct {
+	Email string `json:"email"`
+	Name  string `json:"name"`
+}
+
+func NewOIDCAuthenticator(config OIDCConfig) (*OIDCAuthenticator, error) {
+	if config.Issuer == "" || config.Audience == "" || config.JWKS == "" {
+		return nil, fmt.Errorf("AUTH_OIDC_ISSUER, AUTH_OIDC_AUDIENCE and AUTH_OIDC_JWKS are required")
+	}
+
+	a := &OIDCAuthenticator{
+		config: config,
+		remote: strings.HasPrefix(config.JWKS, "https://") || strings.HasPrefix(config.JWKS, "http://"),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+	if !a.remote {
+		data, err := os.ReadFile(config.JWKS)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+		}
+		var keys jose.JSONWebKeySet
+		if err := json.Unmarshal(data, &keys); err != nil {
+			return nil, fmt.Errorf("failed to parse JWKS file: %w", err)
+		}
+		a.keys = &keys
+		return a, nil
+	}
+
+	// The provider may be down at startup; tokens get 503 until its keys
+	// can be fetched.
+	a.mu.Lock()
+	if err := a.refresh(context.Background()); err != nil {
+		log.Printf("oidc: %v", err)
+	}
+	a.mu.Unlock()
+	return a, nil
+}
+
+func (a *OIDCAuthenticator) Verify(ctx context.Context, token string) (*models.Identity, error) {
+	parsed, err := jwt.ParseSigned(token, oidcAlgorithms)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrInvalidToken, err)
+	}
+
+	keys, err := a.signingKeys(ctx, parsed.Headers[0].KeyID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrAuthUnavailable, err)
+	}
+
+	var claims jwt.Claims
+	var extra oidcClaims
+	verified := false
+	for _, key := range keys {
+		if parsed.Claims(key, &claims, &extra) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, fmt.Errorf("%w: signature does not match any known key", models.ErrInvalidToken)
+	}
+
+	expected := jwt.Expected{Issuer: a.config.Issuer, AnyAudience: jwt.Audience{a.config.Audience}, Time: time.Now()}
+	if err := claims.Validate(expected); err != nil {
+		return nil, fmt.Errorf("%w: %v", models.ErrInvalidToken, err)
+	}
+	if claims.Subject == "" || claims.Expiry == nil {
+		return nil, fmt.Errorf("%w: missing sub or exp claim", models.ErrInvalidToken)
+	}
+
+	return &models.Identity{UID: claims.Subject, Email: extra.Email, Name: extra.Name}, nil
+}
+
+// DeleteIdentity does nothing: identities belong to the OpenID Connect
+// provider, which offers no standard way to delete them. They stay with the
+// provider after the account is deleted.
+func (a *OIDCAuthenticator) DeleteIdentity(ctx context.Context, uid string) error {
+	return nil
+}
+
+// signingKeys returns the keys that may have signed a token with the key ID,
+// fetching the key set again when it is stale or does not know the ID.
+func (a *OIDCAuthenticator) signingKeys(ctx context.Context, keyID string) ([]jose.JSONWebKey, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.remote && (a.keys == nil || time.Since(a.fetched) > jwksMaxAge) && time.Since(a.attempted) > jwksMinRefresh {
+		if err := a.refresh(ctx); err != nil {
+			if a.keys == nil {
+				return nil, err
+			}
+			log.Printf("oidc: using stale keys: %v", err)
+		}
+	}
+	if a.keys == nil {
+		return nil, fmt.Errorf("signing keys are not available yet")
+	}
+
+	keys := a.matchingKeys(keyID)
+	if len(keys) == 0 && a.remote && time.Since(a.attempted) > jwksMinRefresh {
+		if err := a.refresh(ctx); err != nil {
+			log.Printf("oidc: %v", err)
+		}
+		keys = a.matchingKeys(keyID)
+	}
+	return keys, nil
+}
+
+func (a *OIDCAuthenticator) matchingKeys(keyID string) []jose.JSONWebKey {
+	if keyID == "" {
+		return a.keys.Keys
+	}
+	return a.keys.Key(keyID)
+}
+
+// refresh fetches the key set. The caller must hold a.mu.
+func (a *OIDCAuthenticator) refresh(ctx context.Context) error {
+	a.attempted = time.Now()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.config.JWKS, nil)
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch JWKS: %s", resp.Status)
+	}
+
+	var keys jose.JSONWebKeySet
+	if err := json.NewDecoder(io.LimitReader(resp.Body, jwksMaxBytes)).Decode(&keys); err != nil {
+		return fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+	a.keys = &keys
+	a.fetched = time.Now()
+	return nil
+}
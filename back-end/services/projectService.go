@@ -1,32 +1,62 @@
 package services
 
 import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/RiadMefti/TimeTracker/back-end/events"
 	"github.com/RiadMefti/TimeTracker/back-end/models"
 	"github.com/RiadMefti/TimeTracker/back-end/repositories"
 )
 
 type ProjectService struct {
 	projectRepository *repositories.ProjectRepository
+	bus               *events.Bus
 }
 
-func NewProjectService(projectRepository *repositories.ProjectRepository) *ProjectService {
+func NewProjectService(projectRepository *repositories.ProjectRepository, bus *events.Bus) *ProjectService {
 	return &ProjectService{
 		projectRepository: projectRepository,
+		bus:               bus,
 	}
 }
 
-func (s *ProjectService) GetUserProjects(userID string) ([]models.Project, error) {
-	return s.projectRepository.GetUserProjects(userID)
+func (s *ProjectService) GetUserProjects(ctx context.Context, userID string) ([]models.Project, error) {
+	return s.projectRepository.GetUserProjects(ctx, userID)
 }
 
-func (s *ProjectService) CreateUserProject(projectToCreate models.ProjectCreate, userID string) ([]models.Project, error) {
-	return s.projectRepository.CreateUserProject(projectToCreate, userID)
+func (s *ProjectService) CreateUserProject(ctx context.Context, projectToCreate models.ProjectCreate, userID string) ([]models.Project, error) {
+	created, err := s.projectRepository.CreateUserProject(ctx, projectToCreate, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.bus.Publish(userID, events.ProjectCreated, created)
+	return s.projectRepository.GetUserProjects(ctx, userID)
 }
 
-func (s *ProjectService) UpdateUserProject(projectToUpdate models.Project, userID string) ([]models.Project, error) {
-	return s.projectRepository.UpdateUserProject(projectToUpdate, userID)
+func (s *ProjectService) GetUserProject(ctx context.Context, projectID int, userID string) (models.Project, error) {
+	return s.projectRepository.GetUserProject(ctx, projectID, userID)
 }
 
-func (s *ProjectService) DeleteUserProject(projectId string, userID string) ([]models.Project, error) {
-	return s.projectRepository.DeleteUserProject(projectId, userID)
+func (s *ProjectService) UpdateUserProject(ctx context.Context, projectToUpdate models.Project, userID string, expectedVersion *int) (models.Project, error) {
+	result, err := s.projectRepository.UpdateUserProject(ctx, projectToUpdate, userID, expectedVersion)
+	if err != nil {
+		return models.Project{}, err
+	}
+	s.bus.Publish(userID, events.ProjectUpdated, result)
+	return result, nil
+}
+
+func (s *ProjectService) DeleteUserProject(ctx context.Context, projectId string, userID string) ([]models.Project, error) {
+	id, err := strconv.Atoi(projectId)
+	if err != nil {
+		return nil, fmt.Errorf("invalid project ID %q", projectId)
+	}
+	result, err := s.projectRepository.DeleteUserProject(ctx, projectId, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.bus.Publish(userID, events.ProjectDeleted, events.Ref{ID: id})
+	return result, nil
 }
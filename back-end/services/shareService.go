@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/repositories"
+	"github.com/RiadMefti/TimeTracker/back-end/tiptap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type ShareService struct {
+	repo          *repositories.ShareLinkRepository
+	noteRepo      *repositories.NoteRepository
+	folderService *FolderService
+}
+
+func NewShareService(repo *repositories.ShareLinkRepository, noteRepo *repositories.NoteRepository, folderService *FolderService) *ShareService {
+	return &ShareService{repo: repo, noteRepo: noteRepo, folderService: folderService}
+}
+
+func (s *ShareService) CreateShareLink(ctx context.Context, share *models.ShareLinkCreate, userID string) (*models.ShareLink, error) {
+	if (share.NoteID == nil) == (share.FolderID == nil) {
+		return nil, fmt.Errorf("exactly one of NoteID and FolderID is required")
+	}
+	if share.ExpiresAt != nil && !share.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("expiry must be in the future")
+	}
+
+	// Check if the shared note or folder exists and belongs to user
+	if share.NoteID != nil {
+		if _, err := s.noteRepo.GetByID(ctx, *share.NoteID, userID); err != nil {
+			return nil, err
+		}
+	} else {
+		if _, err := s.folderService.GetFolder(ctx, *share.FolderID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	token, err := newShareToken()
+	if err != nil {
+		return nil, err
+	}
+
+	link := &models.ShareLink{
+		Prefix:   token[:8],
+		NoteID:   share.NoteID,
+		FolderID: share.FolderID,
+		UserID:   userID,
+	}
+	if share.ExpiresAt != nil {
+		expiresAt := share.ExpiresAt.UTC()
+		link.ExpiresAt = &expiresAt
+	}
+	if share.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(share.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash password: %w", err)
+		}
+		link.PasswordHash = string(hash)
+	}
+
+	created, err := s.repo.Create(ctx, link, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	created.Token = token
+	return created, nil
+}
+
+func (s *ShareService) GetShareLinks(ctx context.Context, userID string) ([]*models.ShareLink, error) {
+	return s.repo.GetByUser(ctx, userID)
+}
+
+func (s *ShareService) RevokeShareLink(ctx context.Context, id int, userID string) error {
+	return s.repo.Delete(ctx, id, userID)
+}
+
+// GetSharedContent resolves a token to the content it shares. Expired links
+// are reported as not found, and links with a password reject any other
+// password with ErrSharePasswordRequired.
+func (s *ShareService) GetSharedContent(ctx context.Context, token string, password string) (*models.SharedContent, error) {
+	link, err := s.repo.GetByHash(ctx, hashToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if link.ExpiresAt != nil && time.Now().After(*link.ExpiresAt) {
+		return nil, models.ErrShareNotFound
+	}
+	if link.HasPassword && bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) != nil {
+		return nil, models.ErrSharePasswordRequired
+	}
+
+	if link.NoteID != nil {
+		note, err := s.noteRepo.GetByID(ctx, *link.NoteID, link.UserID)
+		if err != nil {
+			return nil, models.ErrShareNotFound
+		}
+		return &models.SharedContent{Note: sharedNote(note)}, nil
+	}
+
+	archive, err := s.folderService.GetFolderArchive(ctx, *link.FolderID, link.UserID)
+	if err != nil {
+		return nil, models.ErrShareNotFound
+	}
+	return &models.SharedContent{Folder: archive.shared(archive.Root)}, nil
+}
+
+func (a *FolderArchive) shared(folder *models.Folder) *models.SharedFolder {
+	result := &models.SharedFolder{
+		ID:      folder.ID,
+		Name:    folder.Name,
+		Notes:   []*models.SharedNote{},
+		Folders: []*models.SharedFolder{},
+	}
+	for _, note := range a.notes[folder.ID] {
+		result.Notes = append(result.Notes, sharedNote(note))
+	}
+	for _, child := range a.children[folder.ID] {
+		result.Folders = append(result.Folders, a.shared(child))
+	}
+	return result
+}
+
+func sharedNote(note *models.Note) *models.SharedNote {
+	return &models.SharedNote{
+		ID:      note.ID,
+		Title:   note.Title,
+		Content: note.Content,
+		Updated: note.Updated,
+	}
+}
+
+// RenderSharedHTML renders shared content as a standalone page. Note bodies
+// go through the TipTap renderer, which escapes text and drops unsafe URLs.
+func RenderSharedHTML(content *models.SharedContent) string {
+	var title string
+	var body strings.Builder
+	if content.Note != nil {
+		title = content.Note.Title
+		body.WriteString("<article>\n<h1>" + html.EscapeString(title) + "</h1>\n")
+		body.WriteString(sharedNoteBody(content.Note))
+		body.WriteString("\n</article>\n")
+	} else {
+		title = content.Folder.Name
+		writeSharedFolder(&body, content.Folder, 1)
+	}
+	return sharedPage(title, body.String())
+}
+
+func writeSharedFolder(sb *strings.Builder, folder *models.SharedFolder, level int) {
+	heading := min(level, 6)
+	fmt.Fprintf(sb, "<section>\n<h%d>%s</h%d>\n", heading, html.EscapeString(folder.Name), heading)
+	for _, note := range folder.Notes {
+		noteHeading := min(level+1, 6)
+		fmt.Fprintf(sb, "<article>\n<h%d>%s</h%d>\n", noteHeading, html.EscapeString(note.Title), noteHeading)
+		sb.WriteString(sharedNoteBody(note))
+		sb.WriteString("\n</article>\n")
+	}
+	for _, child := range folder.Folders {
+		writeSharedFolder(sb, child, level+1)
+	}
+	sb.WriteString("</section>\n")
+}
+
+// sharedNoteBody renders the note content, falling back to escaped text for
+// content that cannot be parsed.
+func sharedNoteBody(note *models.SharedNote) string {
+	doc, err := tiptap.ParseStored(note.Content)
+	if err != nil {
+		return "<p>" + html.EscapeString(note.Content) + "</p>"
+	}
+	return tiptap.ToHTML(doc)
+}
+
+// RenderSharePasswordHTML renders the form asking for a share link password.
+// It is posted so the password stays out of URLs, logs and history.
+func RenderSharePasswordHTML(wrongPassword bool) string {
+	var body strings.Builder
+	body.WriteString("<h1>Password required</h1>\n")
+	if wrongPassword {
+		body.WriteString("<p>The password is incorrect.</p>\n")
+	}
+	body.WriteString(`<form method="post"><input type="password" name="password" autofocus> <button type="submit">Open</button></form>` + "\n")
+	return sharedPage("Password required", body.String())
+}
+
+func sharedPage(title string, body string) string {
+	return "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n" +
+		"<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n" +
+		"<meta name=\"robots\" content=\"noindex\">\n<title>" + html.EscapeString(title) + "</title>\n" +
+		"</head>\n<body>\n" + body + "</body>\n</html>\n"
+}
+
+func newShareToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate share token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
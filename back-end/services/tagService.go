@@ -0,0 +1,57 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/repositories"
+)
+
+const (
+	maxTagLength   = 64
+	maxTagsPerNote = 50
+)
+
+type TagService struct {
+	repo *repositories.TagRepository
+}
+
+func NewTagService(repo *repositories.TagRepository) *TagService {
+	return &TagService{repo: repo}
+}
+
+func (s *TagService) GetTags(ctx context.Context, userID string) ([]*models.Tag, error) {
+	return s.repo.GetAll(ctx, userID)
+}
+
+func (s *TagService) DeleteTag(ctx context.Context, id int, userID string) error {
+	return s.repo.Delete(ctx, id, userID)
+}
+
+// normalizeTags trims the names and drops blanks and case-insensitive
+// duplicates, keeping the first spelling.
+func normalizeTags(names []string) ([]string, error) {
+	seen := map[string]bool{}
+	tags := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		if utf8.RuneCountInString(name) > maxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", name, maxTagLength)
+		}
+		if strings.Contains(name, ",") {
+			return nil, fmt.Errorf("tag %q cannot contain a comma", name)
+		}
+		seen[strings.ToLower(name)] = true
+		tags = append(tags, name)
+	}
+	if len(tags) > maxTagsPerNote {
+		return nil, fmt.Errorf("a note cannot have more than %d tags", maxTagsPerNote)
+	}
+	return tags, nil
+}
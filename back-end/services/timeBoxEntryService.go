@@ -1,36 +1,66 @@
 package services
 
 import (
+	"context"
+	"github.com/RiadMefti/TimeTracker/back-end/events"
 	"github.com/RiadMefti/TimeTracker/back-end/models"
 	"github.com/RiadMefti/TimeTracker/back-end/repositories"
 )
 
 type TimeBoxEntryService struct {
 	timeBoxEntryRepository *repositories.TimeBoxEntryRepository
+	bus                    *events.Bus
 }
 
-func NewTimeBoxEntryService(timeBoxEntryRepository *repositories.TimeBoxEntryRepository) *TimeBoxEntryService {
+func NewTimeBoxEntryService(timeBoxEntryRepository *repositories.TimeBoxEntryRepository, bus *events.Bus) *TimeBoxEntryService {
 	return &TimeBoxEntryService{
 		timeBoxEntryRepository: timeBoxEntryRepository,
+		bus:                    bus,
 	}
 }
 
-func (s *TimeBoxEntryService) GetUserTimeBoxEntries(userID string) ([]models.TimeBoxEntry, error) {
-	return s.timeBoxEntryRepository.GetUserTimeBoxEntries(userID)
+func (s *TimeBoxEntryService) GetUserTimeBoxEntries(ctx context.Context, userID string) ([]models.TimeBoxEntry, error) {
+	return s.timeBoxEntryRepository.GetUserTimeBoxEntries(ctx, userID)
 }
 
-func (s *TimeBoxEntryService) CreateTimeBoxEntry(entry models.TimeBoxEntryCreate, userID string) ([]models.TimeBoxEntry, error) {
-	return s.timeBoxEntryRepository.CreateTimeBoxEntry(entry, userID)
+func (s *TimeBoxEntryService) CreateTimeBoxEntry(ctx context.Context, entry models.TimeBoxEntryCreate, userID string) ([]models.TimeBoxEntry, error) {
+	created, err := s.timeBoxEntryRepository.CreateTimeBoxEntry(ctx, entry, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.bus.Publish(userID, events.TimeBoxCreated, created)
+	return s.timeBoxEntryRepository.GetUserTimeBoxEntries(ctx, userID)
 }
 
-func (s *TimeBoxEntryService) UpdateTimeBoxEntry(entry models.TimeBoxEntry, userID string) ([]models.TimeBoxEntry, error) {
-	return s.timeBoxEntryRepository.UpdateTimeBoxEntry(entry, userID)
+func (s *TimeBoxEntryService) GetTimeBoxEntry(ctx context.Context, timeBoxEntryID int, userID string) (models.TimeBoxEntry, error) {
+	return s.timeBoxEntryRepository.GetTimeBoxEntry(ctx, timeBoxEntryID, userID)
 }
 
-func (s *TimeBoxEntryService) DeleteTimeBoxEntry(timeBoxEntryID int, userID string) ([]models.TimeBoxEntry, error) {
-	return s.timeBoxEntryRepository.DeleteTimeBoxEntry(timeBoxEntryID, userID)
+func (s *TimeBoxEntryService) UpdateTimeBoxEntry(ctx context.Context, entry models.TimeBoxEntry, userID string, expectedVersion *int) (models.TimeBoxEntry, error) {
+	result, err := s.timeBoxEntryRepository.UpdateTimeBoxEntry(ctx, entry, userID, expectedVersion)
+	if err != nil {
+		return models.TimeBoxEntry{}, err
+	}
+	s.bus.Publish(userID, events.TimeBoxUpdated, result)
+	return result, nil
 }
 
-func (s *TimeBoxEntryService) AssignProjectToTimeBox(timeBoxEntryID int, projectID *int, userID string) ([]models.TimeBoxEntry, error) {
-	return s.timeBoxEntryRepository.AssignProjectToTimeBox(timeBoxEntryID, projectID, userID)
+func (s *TimeBoxEntryService) DeleteTimeBoxEntry(ctx context.Context, timeBoxEntryID int, userID string) ([]models.TimeBoxEntry, error) {
+	result, err := s.timeBoxEntryRepository.DeleteTimeBoxEntry(ctx, timeBoxEntryID, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.bus.Publish(userID, events.TimeBoxDeleted, events.Ref{ID: timeBoxEntryID})
+	return result, nil
+}
+
+func (s *TimeBoxEntryService) AssignProjectToTimeBox(ctx context.Context, timeBoxEntryID int, projectID *int, userID string) ([]models.TimeBoxEntry, error) {
+	updated, err := s.timeBoxEntryRepository.AssignProjectToTimeBox(ctx, timeBoxEntryID, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if updated != nil {
+		s.bus.Publish(userID, events.TimeBoxUpdated, updated)
+	}
+	return s.timeBoxEntryRepository.GetUserTimeBoxEntries(ctx, userID)
 }
@@ -1,36 +1,84 @@
 package services
 
 import (
+	"context"
+	"github.com/RiadMefti/TimeTracker/back-end/events"
 	"github.com/RiadMefti/TimeTracker/back-end/models"
 	"github.com/RiadMefti/TimeTracker/back-end/repositories"
 )
 
 type TimeEntryService struct {
-	timeEntryRepository *repositories.TimeEntryRepository
+	timeEntryRepository    *repositories.TimeEntryRepository
+	noteRelationRepository *repositories.NoteRelationRepository
+	bus                    *events.Bus
 }
 
-func NewTimeEntryService(timeEntryRepository *repositories.TimeEntryRepository) *TimeEntryService {
+func NewTimeEntryService(timeEntryRepository *repositories.TimeEntryRepository, noteRelationRepository *repositories.NoteRelationRepository, bus *events.Bus) *TimeEntryService {
 	return &TimeEntryService{
-		timeEntryRepository: timeEntryRepository,
+		timeEntryRepository:    timeEntryRepository,
+		noteRelationRepository: noteRelationRepository,
+		bus:                    bus,
 	}
 }
 
-func (s *TimeEntryService) GetUserTimeEntries(userID string) ([]models.TimeEntry, error) {
-	return s.timeEntryRepository.GetUserTimeEntries(userID)
+func (s *TimeEntryService) GetUserTimeEntries(ctx context.Context, userID string) ([]models.TimeEntry, error) {
+	return s.timeEntryRepository.GetUserTimeEntries(ctx, userID)
 }
 
-func (s *TimeEntryService) CreateTimeEntry(entry models.TimeEntryCreate, userID string) ([]models.TimeEntry, error) {
-	return s.timeEntryRepository.CreateTimeEntry(entry, userID)
+func (s *TimeEntryService) CreateTimeEntry(ctx context.Context, entry models.TimeEntryCreate, userID string) ([]models.TimeEntry, error) {
+	created, err := s.timeEntryRepository.CreateTimeEntry(ctx, entry, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.bus.Publish(userID, events.TimeEntryCreated, created)
+	return s.timeEntryRepository.GetUserTimeEntries(ctx, userID)
+}
+
+func (s *TimeEntryService) GetTimeEntry(ctx context.Context, timeEntryID int, userID string) (models.TimeEntry, error) {
+	return s.timeEntryRepository.GetTimeEntry(ctx, timeEntryID, userID)
+}
+
+func (s *TimeEntryService) UpdateTimeEntry(ctx context.Context, entry models.TimeEntry, userID string, expectedVersion *int) (models.TimeEntry, error) {
+	result, err := s.timeEntryRepository.UpdateTimeEntry(ctx, entry, userID, expectedVersion)
+	if err != nil {
+		return models.TimeEntry{}, err
+	}
+	s.bus.Publish(userID, events.TimeEntryUpdated, result)
+	return result, nil
 }
 
-func (s *TimeEntryService) UpdateTimeEntry(entry models.TimeEntry, userID string) ([]models.TimeEntry, error) {
-	return s.timeEntryRepository.UpdateTimeEntry(entry, userID)
+func (s *TimeEntryService) DeleteTimeEntry(ctx context.Context, timeEntryID int, userID string) ([]models.TimeEntry, error) {
+	result, err := s.timeEntryRepository.DeleteTimeEntry(ctx, timeEntryID, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.bus.Publish(userID, events.TimeEntryDeleted, events.Ref{ID: timeEntryID})
+	return result, nil
 }
 
-func (s *TimeEntryService) DeleteTimeEntry(timeEntryID int, userID string) ([]models.TimeEntry, error) {
-	return s.timeEntryRepository.DeleteTimeEntry(timeEntryID, userID)
+func (s *TimeEntryService) AssignProjectToTime(ctx context.Context, timeEntryID int, projectID *int, userID string) ([]models.TimeEntry, error) {
+	updated, err := s.timeEntryRepository.AssignProjectToTime(ctx, timeEntryID, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if updated != nil {
+		s.bus.Publish(userID, events.TimeEntryUpdated, updated)
+	}
+	return s.timeEntryRepository.GetUserTimeEntries(ctx, userID)
 }
 
-func (s *TimeEntryService) AssignProjectToTime(timeEntryID int, projectID *int, userID string) ([]models.TimeEntry, error) {
-	return s.timeEntryRepository.AssignProjectToTime(timeEntryID, projectID, userID)
+// IncludeNotes fills in the stubs of the notes attached to each entry.
+func (s *TimeEntryService) IncludeNotes(ctx context.Context, entries []models.TimeEntry, userID string) ([]models.TimeEntry, error) {
+	stubs, err := s.noteRelationRepository.GetNoteStubsByTarget(ctx, repositories.NoteTimeEntryRelation, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range entries {
+		entries[i].Notes = stubs[entries[i].ID]
+		if entries[i].Notes == nil {
+			entries[i].Notes = []models.NoteStub{}
+		}
+	}
+	return entries, nil
 }
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/events"
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/repositories"
+)
+
+// TimerService runs the timer on the server so that every device of the user
+// sees the same one.
+type TimerService struct {
+	repo          *repositories.TimerRepository
+	timeEntryRepo *repositories.TimeEntryRepository
+	projectRepo   *repositories.ProjectRepository
+	bus           *events.Bus
+}
+
+func NewTimerService(repo *repositories.TimerRepository, timeEntryRepo *repositories.TimeEntryRepository, projectRepo *repositories.ProjectRepository, bus *events.Bus) *TimerService {
+	return &TimerService{repo: repo, timeEntryRepo: timeEntryRepo, projectRepo: projectRepo, bus: bus}
+}
+
+// GetTimer returns the running timer, or nil when none is running.
+func (s *TimerService) GetTimer(ctx context.Context, userID string) (*models.RunningTimer, error) {
+	return s.repo.Get(ctx, userID)
+}
+
+// StartTimer starts a timer, on the default project of the user settings when
+// none is given.
+func (s *TimerService) StartTimer(ctx context.Context, start *models.TimerStart, settings *models.UserSettings, userID string) (*models.RunningTimer, error) {
+	if start.ProjectID == nil {
+		start.ProjectID = settings.DefaultProjectID
+	}
+	timer, err := s.timer(ctx, start, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Start(ctx, timer, userID); err != nil {
+		return nil, err
+	}
+	s.bus.Publish(userID, events.TimerStarted, timer)
+	return timer, nil
+}
+
+// UpdateTimer changes the description, project or start of the running
+// timer.
+func (s *TimerService) UpdateTimer(ctx context.Context, update *models.TimerStart, userID string) (*models.RunningTimer, error) {
+	current, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, models.ErrTimerNotRunning
+	}
+	if update.StartDate == nil {
+		update.StartDate = &current.StartDate
+	}
+
+	timer, err := s.timer(ctx, update, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, timer, userID); err != nil {
+		return nil, err
+	}
+	s.bus.Publish(userID, events.TimerUpdated, timer)
+	return timer, nil
+}
+
+// StopTimer stops the running timer and records it as a time entry.
+func (s *TimerService) StopTimer(ctx context.Context, stop *models.TimerStop, userID string) (*models.TimerStopResult, error) {
+	end := time.Now()
+	if stop.EndDate != nil {
+		end = *stop.EndDate
+	}
+
+	entry, err := s.repo.Stop(ctx, end, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.bus.Publish(userID, events.TimerStopped, entry)
+	s.bus.Publish(userID, events.TimeEntryCreated, entry)
+
+	entries, err := s.timeEntryRepo.GetUserTimeEntries(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.TimerStopResult{Entry: entry, Entries: entries}, nil
+}
+
+func (s *TimerService) timer(ctx context.Context, start *models.TimerStart, userID string) (*models.RunningTimer, error) {
+	if start.ProjectID != nil {
+		if _, err := s.projectRepo.GetUserProject(ctx, *start.ProjectID, userID); err != nil {
+			return nil, fmt.Errorf("project not found")
+		}
+	}
+
+	timer := &models.RunningTimer{Description: start.Description, ProjectID: start.ProjectID, StartDate: time.Now().UTC()}
+	if start.StartDate != nil {
+		if start.StartDate.After(time.Now()) {
+			return nil, fmt.Errorf("timer cannot start in the future")
+		}
+		timer.StartDate = start.StartDate.UTC()
+	}
+	return timer, nil
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+)
+
+// tokenCache remembers the identities of verified tokens until they expire,
+// evicting the least recently used ones past its size. Tokens are keyed by
+// their hash so the cache does not hold usable credentials.
+type tokenCache struct {
+	mu      sync.Mutex
+	size    int
+	entries map[[sha256.Size]byte]*list.Element
+	order   *list.List // most recently used first
+}
+
+type tokenCacheEntry struct {
+	key      [sha256.Size]byte
+	identity models.Identity
+	expires  time.Time
+}
+
+func newTokenCache(size int) *tokenCache {
+	return &tokenCache{size: size, entries: map[[sha256.Size]byte]*list.Element{}, order: list.New()}
+}
+
+func (c *tokenCache) get(token string) (*models.Identity, bool) {
+	key := sha256.Sum256([]byte(token))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*tokenCacheEntry)
+	if !time.Now().Before(entry.expires) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	identity := entry.identity
+	return &identity, true
+}
+
+func (c *tokenCache) put(token string, identity models.Identity, expires time.Time) {
+	key := sha256.Sum256([]byte(token))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value = &tokenCacheEntry{key: key, identity: identity, expires: expires}
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&tokenCacheEntry{key: key, identity: identity, expires: expires})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*tokenCacheEntry).key)
+	}
+}
+
+// removeUser drops every cached token of the user.
+func (c *tokenCache) removeUser(uid string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for element := c.order.Front(); element != nil; {
+		next := element.Next()
+		if entry := element.Value.(*tokenCacheEntry); entry.identity.UID == uid {
+			c.order.Remove(element)
+			delete(c.entries, entry.key)
+		}
+		element = next
+	}
+}
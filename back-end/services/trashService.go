@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/repositories"
+)
+
+type TrashService struct {
+	repo        *repositories.TrashRepository
+	attachments *AttachmentService
+	retention   time.Duration
+}
+
+func NewTrashService(repo *repositories.TrashRepository, attachments *AttachmentService, retention time.Duration) *TrashService {
+	return &TrashService{repo: repo, attachments: attachments, retention: retention}
+}
+
+func (s *TrashService) GetTrash(ctx context.Context, userID string) (*models.Trash, error) {
+	return s.repo.GetTrash(ctx, userID)
+}
+
+func (s *TrashService) RestoreNote(ctx context.Context, id int, userID string) (*models.Trash, error) {
+	if err := s.repo.RestoreNote(ctx, id, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetTrash(ctx, userID)
+}
+
+func (s *TrashService) RestoreFolder(ctx context.Context, id int, userID string) (*models.Trash, error) {
+	if err := s.repo.RestoreFolder(ctx, id, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetTrash(ctx, userID)
+}
+
+func (s *TrashService) RestoreTimeEntry(ctx context.Context, id int, userID string) (*models.Trash, error) {
+	if err := s.repo.RestoreTimeEntry(ctx, id, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetTrash(ctx, userID)
+}
+
+func (s *TrashService) RestoreProject(ctx context.Context, id int, userID string) (*models.Trash, error) {
+	if err := s.repo.RestoreProject(ctx, id, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetTrash(ctx, userID)
+}
+
+// Purge permanently deletes items that have been in the trash for longer
+// than the retention period, then drops blobs left without attachments.
+func (s *TrashService) Purge(ctx context.Context) (*models.PurgeResult, error) {
+	result, err := s.repo.Purge(ctx, time.Now().Add(-s.retention))
+	if err != nil {
+		return nil, err
+	}
+	s.attachments.CleanupOrphans(ctx)
+	return result, nil
+}
+
+// StartPurgeJob runs Purge right away and then every interval, until ctx is
+// cancelled.
+func (s *TrashService) StartPurgeJob(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			result, err := s.Purge(ctx)
+			if err != nil {
+				log.Printf("trash purge failed: %v", err)
+			} else if result.Notes+result.Folders+result.TimeEntries+result.Projects > 0 {
+				log.Printf("trash purge removed %d notes, %d folders, %d time entries and %d projects",
+					result.Notes, result.Folders, result.TimeEntries, result.Projects)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/repositories"
+)
+
+var (
+	weekdays = map[string]bool{
+		"sunday": true, "monday": true, "tuesday": true, "wednesday": true,
+		"thursday": true, "friday": true, "saturday": true,
+	}
+	dateFormats = map[string]bool{
+		"YYYY-MM-DD": true, "DD/MM/YYYY": true, "MM/DD/YYYY": true, "DD.MM.YYYY": true,
+	}
+	currencyCode = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+type UserSettingsService struct {
+	repo        *repositories.UserSettingsRepository
+	projectRepo *repositories.ProjectRepository
+}
+
+func NewUserSettingsService(repo *repositories.UserSettingsRepository, projectRepo *repositories.ProjectRepository) *UserSettingsService {
+	return &UserSettingsService{repo: repo, projectRepo: projectRepo}
+}
+
+func (s *UserSettingsService) GetSettings(ctx context.Context, userID string) (*models.UserSettings, error) {
+	return s.repo.Get(ctx, userID)
+}
+
+// UpdateSettings replaces the user's settings. Empty fields take their
+// default; an empty WorkingHours list means no working hours, while a missing
+// one keeps the default week.
+func (s *UserSettingsService) UpdateSettings(ctx context.Context, settings *models.UserSettings, userID string) (*models.UserSettings, error) {
+	if err := s.normalize(ctx, settings, userID); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Save(ctx, settings, userID); err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func (s *UserSettingsService) normalize(ctx context.Context, settings *models.UserSettings, userID string) error {
+	defaults := repositories.DefaultUserSettings()
+	if settings.Timezone == "" {
+		settings.Timezone = defaults.Timezone
+	}
+	if _, err := time.LoadLocation(settings.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", settings.Timezone)
+	}
+
+	settings.WeekStart = strings.ToLower(settings.WeekStart)
+	if settings.WeekStart == "" {
+		settings.WeekStart = defaults.WeekStart
+	}
+	if !weekdays[settings.WeekStart] {
+		return fmt.Errorf("unknown week start %q", settings.WeekStart)
+	}
+
+	if settings.WorkingHours == nil {
+		settings.WorkingHours = defaults.WorkingHours
+	}
+	for i := range settings.WorkingHours {
+		if err := validateWorkingHours(&settings.WorkingHours[i]); err != nil {
+			return err
+		}
+	}
+
+	if settings.DefaultProjectID != nil {
+		if _, err := s.projectRepo.GetUserProject(ctx, *settings.DefaultProjectID, userID); err != nil {
+			return fmt.Errorf("project not found")
+		}
+	}
+
+	switch settings.Rounding.Mode {
+	case "", models.RoundingNone:
+		settings.Rounding = models.DurationRounding{Mode: models.RoundingNone}
+	case models.RoundingNearest, models.RoundingUp, models.RoundingDown:
+		if settings.Rounding.Minutes < 1 || settings.Rounding.Minutes > 60 {
+			return fmt.Errorf("rounding minutes must be between 1 and 60")
+		}
+	default:
+		return fmt.Errorf("unknown rounding mode %q", settings.Rounding.Mode)
+	}
+
+	if settings.DateFormat == "" {
+		settings.DateFormat = defaults.DateFormat
+	}
+	if !dateFormats[settings.DateFormat] {
+		return fmt.Errorf("unknown date format %q", settings.DateFormat)
+	}
+
+	settings.Currency = strings.ToUpper(settings.Currency)
+	if settings.Currency == "" {
+		settings.Currency = defaults.Currency
+	}
+	if !currencyCode.MatchString(settings.Currency) {
+		return fmt.Errorf("currency must be an ISO 4217 code")
+	}
+	return nil
+}
+
+func validateWorkingHours(hours *models.WorkingHours) error {
+	hours.Weekday = strings.ToLower(hours.Weekday)
+	if !weekdays[hours.Weekday] {
+		return fmt.Errorf("unknown weekday %q", hours.Weekday)
+	}
+	start, err := time.Parse("15:04", hours.Start)
+	if err != nil {
+		return fmt.Errorf("working hours start must be HH:MM")
+	}
+	end, err := time.Parse("15:04", hours.End)
+	if err != nil {
+		return fmt.Errorf("working hours end must be HH:MM")
+	}
+	if !end.After(start) {
+		return fmt.Errorf("working hours on %s must end after they start", hours.Weekday)
+	}
+	return nil
+}
+
+// SettingsLocation returns the location of the settings' timezone. Settings
+// are validated when saved, so UTC is only a guard.
+func SettingsLocation(settings *models.UserSettings) *time.Location {
+	location, err := time.LoadLocation(settings.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
@@ -0,0 +1,448 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/events"
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+	"github.com/RiadMefti/TimeTracker/back-end/repositories"
+)
+
+const (
+	// webhookMaxAttempts spreads retries over about an hour with
+	// webhookBackoff doubling from 30 seconds.
+	webhookMaxAttempts = 8
+	webhookBackoff     = 30 * time.Second
+	webhookTimeout     = 10 * time.Second
+	webhookBatchSize   = 20
+	// webhookLease is how long a claimed delivery stays hidden from other
+	// workers; it must outlast a batch of sends.
+	webhookLease      = 5 * time.Minute
+	maxDeliveriesPage = 100
+	// webhookQueueSize is how many published events may wait to have their
+	// deliveries recorded before new ones are dropped.
+	webhookQueueSize = 1024
+)
+
+// errWebhookAddress is returned for webhook hosts that resolve to loopback,
+// private, link-local or other non-public addresses.
+var errWebhookAddress = errors.New("webhook URL must point to a public address")
+
+// nonPublicPrefixes are the special-purpose ranges netip does not classify
+// as private, loopback or link-local but which are not reachable on the
+// internet either.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+// WebhookService posts events to the URLs users registered for them. Each
+// event is recorded as a pending delivery while it is published, and a
+// background worker sends the deliveries, retrying failures with
+// exponential backoff.
+//
+// Requests carry the headers X-Webhook-Event, X-Webhook-Delivery,
+// X-Webhook-Timestamp and X-Webhook-Signature. The signature is
+// "sha256=" followed by the hex HMAC-SHA256, keyed with the webhook
+// secret, of the timestamp, a dot and the body.
+//
+// Webhooks may only point to public addresses. This is checked when they
+// are saved and again for every connection, so a host cannot be resolved to
+// an internal address later on. Redirects are not followed and proxies are
+// not used.
+type WebhookService struct {
+	repo    *repositories.WebhookRepository
+	client  *http.Client
+	allowed func(netip.Addr) bool
+	wake    chan struct{}
+
+	// queue holds the events whose deliveries are still to be recorded.
+	queue         chan queuedEvent
+	mu            sync.Mutex // guards closed
+	closed        bool
+	recording     context.Context
+	stopRecording context.CancelFunc
+	recorded      chan struct{}
+}
+
+type queuedEvent struct {
+	userID string
+	event  events.Event
+}
+
+func NewWebhookService(repo *repositories.WebhookRepository) *WebhookService {
+	s := newWebhookService(repo, isPublicAddr)
+	go s.recordEvents()
+	return s
+}
+
+// newWebhookService builds the service without starting to record events.
+// allowed decides which addresses webhooks may connect to.
+func newWebhookService(repo *repositories.WebhookRepository, allowed func(netip.Addr) bool) *WebhookService {
+	recording, stopRecording := context.WithCancel(context.Background())
+	return &WebhookService{
+		repo:          repo,
+		client:        newWebhookClient(allowed),
+		allowed:       allowed,
+		wake:          make(chan struct{}, 1),
+		queue:         make(chan queuedEvent, webhookQueueSize),
+		recording:     recording,
+		stopRecording: stopRecording,
+		recorded:      make(chan struct{}),
+	}
+}
+
+// newWebhookClient builds a client that refuses to connect to addresses
+// allowed rejects. The check runs on the resolved address of every
+// connection, including those made for retries.
+func newWebhookClient(allowed func(netip.Addr) bool) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: webhookTimeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			addr, err := netip.ParseAddr(host)
+			if err != nil || !allowed(addr.Unmap()) {
+				return fmt.Errorf("%w: %s", errWebhookAddress, host)
+			}
+			return nil
+		},
+	}
+	return &http.Client{
+		Timeout: webhookTimeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+			TLSHandshakeTimeout: webhookTimeout,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// webhookPayload is the body of a delivery.
+type webhookPayload struct {
+	ID      string          `json:"ID"`
+	Type    string          `json:"Type"`
+	Created time.Time       `json:"Created"`
+	Data    json.RawMessage `json:"Data"`
+}
+
+func (s *WebhookService) GetWebhooks(ctx context.Context, userID string) ([]*models.Webhook, error) {
+	return s.repo.GetAll(ctx, userID)
+}
+
+// CreateWebhook registers a webhook and returns it with its signing secret,
+// which is not shown again.
+func (s *WebhookService) CreateWebhook(ctx context.Context, hook *models.WebhookCreate, userID string) (*models.Webhook, error) {
+	if err := s.validateWebhook(ctx, hook.URL, hook.Events); err != nil {
+		return nil, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	encoded := hex.EncodeToString(secret)
+
+	created, err := s.repo.Create(ctx, hook, encoded, userID)
+	if err != nil {
+		return nil, err
+	}
+	created.Secret = encoded
+	return created, nil
+}
+
+func (s *WebhookService) UpdateWebhook(ctx context.Context, id int, hook *models.WebhookUpdate, userID string) (*models.Webhook, error) {
+	if err := s.validateWebhook(ctx, hook.URL, hook.Events); err != nil {
+		return nil, err
+	}
+	return s.repo.Update(ctx, id, hook, userID)
+}
+
+func (s *WebhookService) DeleteWebhook(ctx context.Context, id int, userID string) error {
+	return s.repo.Delete(ctx, id, userID)
+}
+
+// GetDeliveries returns the latest deliveries of the webhook. status is
+// empty, pending, succeeded or failed.
+func (s *WebhookService) GetDeliveries(ctx context.Context, webhookID int, status string, userID string) ([]*models.WebhookDelivery, error) {
+	switch status {
+	case "", models.DeliveryPending, models.DeliverySucceeded, models.DeliveryFailed:
+	default:
+		return nil, fmt.Errorf("invalid delivery status %q", status)
+	}
+	if _, err := s.repo.GetByID(ctx, webhookID, userID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetDeliveries(ctx, webhookID, status, maxDeliveriesPage, userID)
+}
+
+// ReplayDelivery sends the payload of a finished delivery again, as a new
+// delivery.
+func (s *WebhookService) ReplayDelivery(ctx context.Context, deliveryID, webhookID int, userID string) (*models.WebhookDelivery, error) {
+	delivery, err := s.repo.Replay(ctx, deliveryID, webhookID, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.notify()
+	return delivery, nil
+}
+
+// HandleEvent queues an event to have its deliveries recorded. It is
+// registered as an event bus listener, so it runs on the publishing request
+// and must not wait on the database. When the queue is full the event is
+// dropped.
+func (s *WebhookService) HandleEvent(userID string, event events.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		log.Printf("webhooks: stopped, dropped %s %s", event.Type, event.ID)
+		return
+	}
+	select {
+	case s.queue <- queuedEvent{userID: userID, event: event}:
+	default:
+		log.Printf("webhooks: queue is full, dropped %s %s", event.Type, event.ID)
+	}
+}
+
+// recordEvents records the deliveries of queued events until the queue is
+// closed. Events are published once their change is committed, so the
+// deliveries are recorded even when the request was cancelled.
+func (s *WebhookService) recordEvents() {
+	defer close(s.recorded)
+	for queued := range s.queue {
+		s.record(queued.userID, queued.event)
+	}
+}
+
+func (s *WebhookService) record(userID string, event events.Event) {
+	payload, err := json.Marshal(webhookPayload{ID: event.ID, Type: event.Type, Created: event.Created, Data: event.Data})
+	if err != nil {
+		log.Printf("webhooks: failed to encode %s: %v", event.Type, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(s.recording, webhookTimeout)
+	defer cancel()
+	queued, err := s.repo.Enqueue(ctx, userID, event.ID, event.Type, payload)
+	if err != nil {
+		log.Printf("webhooks: %v", err)
+		return
+	}
+	if queued > 0 {
+		s.notify()
+	}
+}
+
+// Close stops accepting events and waits for the queued ones to be
+// recorded, giving up on those left after webhookTimeout. It must be called
+// before the database is closed.
+func (s *WebhookService) Close() {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-s.recorded:
+	case <-time.After(webhookTimeout):
+		log.Printf("webhooks: %d events were not recorded before shutdown", len(s.queue))
+		s.stopRecording()
+		<-s.recorded
+	}
+}
+
+// notify wakes the delivery worker without waiting for it.
+func (s *WebhookService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// StartDeliveryJob sends due deliveries whenever new ones are queued, and
+// at least every interval for the retries, until ctx is cancelled.
+func (s *WebhookService) StartDeliveryJob(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			s.deliverDue(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-s.wake:
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (s *WebhookService) deliverDue(ctx context.Context) {
+	for {
+		targets, err := s.repo.ClaimDue(ctx, webhookBatchSize, webhookLease)
+		if err != nil {
+			log.Printf("webhooks: %v", err)
+			return
+		}
+		for _, target := range targets {
+			s.deliver(ctx, target)
+		}
+		if len(targets) < webhookBatchSize {
+			return
+		}
+	}
+}
+
+func (s *WebhookService) deliver(ctx context.Context, target *models.WebhookTarget) {
+	responseStatus, err := s.send(ctx, target)
+
+	var lastError *string
+	if err != nil {
+		message := err.Error()
+		lastError = &message
+	}
+	status, nextAttempt := deliveryOutcome(target.Attempts+1, err, time.Now())
+
+	if err := s.repo.RecordAttempt(ctx, target.DeliveryID, status, responseStatus, lastError, nextAttempt); err != nil {
+		log.Printf("webhooks: %v", err)
+	}
+}
+
+// deliveryOutcome returns the status of a delivery whose attempt-th attempt
+// ended with err, and when to try again if it is still pending.
+func deliveryOutcome(attempt int, err error, now time.Time) (string, *time.Time) {
+	if err == nil {
+		return models.DeliverySucceeded, nil
+	}
+	if attempt >= webhookMaxAttempts {
+		return models.DeliveryFailed, nil
+	}
+	next := now.Add(webhookBackoff << (attempt - 1))
+	return models.DeliveryPending, &next
+}
+
+// send posts the delivery and returns the response status, if there was a
+// response. Any status outside 2xx is an error.
+func (s *WebhookService) send(ctx context.Context, target *models.WebhookTarget) (*int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(target.Payload))
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TimeTracker-Webhooks/1.0")
+	req.Header.Set("X-Webhook-Event", target.EventType)
+	req.Header.Set("X-Webhook-Delivery", strconv.Itoa(target.DeliveryID))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+SignWebhook(target.Secret, timestamp, target.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &resp.StatusCode, fmt.Errorf("receiver responded with %s", resp.Status)
+	}
+	return &resp.StatusCode, nil
+}
+
+// SignWebhook returns the hex signature of a delivery body sent at
+// timestamp, for receivers to compare against X-Webhook-Signature.
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *WebhookService) validateWebhook(ctx context.Context, rawURL string, types []string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Hostname() == "" {
+		return fmt.Errorf("webhook URL must be an absolute http or https URL")
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", parsed.Hostname())
+	if err != nil {
+		return fmt.Errorf("webhook host %q could not be resolved", parsed.Hostname())
+	}
+	for _, addr := range addrs {
+		if !s.allowed(addr.Unmap()) {
+			return errWebhookAddress
+		}
+	}
+
+	if len(types) == 0 {
+		return fmt.Errorf("webhook must subscribe to at least one event type")
+	}
+	for _, eventType := range types {
+		if eventType != "*" && !isEventType(eventType) {
+			return fmt.Errorf("unknown event type %q", eventType)
+		}
+	}
+	return nil
+}
+
+// isPublicAddr reports whether addr is a unicast address reachable on the
+// internet, as opposed to loopback, private, link-local (which includes
+// cloud metadata endpoints) or another special-purpose range.
+func isPublicAddr(addr netip.Addr) bool {
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() {
+		return false
+	}
+	for _, prefix := range nonPublicPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+func isEventType(eventType string) bool {
+	for _, known := range events.Types {
+		if known == eventType {
+			return true
+		}
+	}
+	return false
+}
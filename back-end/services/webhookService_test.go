@@ -0,0 +1,296 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RiadMefti/TimeTracker/back-end/events"
+	"github.com/RiadMefti/TimeTracker/back-end/models"
+)
+
+// allowLoopback lets tests deliver to httptest receivers, which listen on
+// 127.0.0.1.
+func allowLoopback(addr netip.Addr) bool {
+	return addr.IsLoopback() || isPublicAddr(addr)
+}
+
+// receivedWebhook is a request seen by a test receiver.
+type receivedWebhook struct {
+	header http.Header
+	body   []byte
+}
+
+// webhookReceiver records the requests it gets and answers each with the
+// next status of statuses, repeating the last one.
+func webhookReceiver(t *testing.T, statuses ...int) (*httptest.Server, func() []receivedWebhook) {
+	t.Helper()
+	var mu sync.Mutex
+	var received []receivedWebhook
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, receivedWebhook{header: r.Header.Clone(), body: body})
+		status := statuses[min(len(received), len(statuses))-1]
+		mu.Unlock()
+		if status == http.StatusFound {
+			w.Header().Set("Location", "http://169.254.169.254/latest/meta-data/")
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []receivedWebhook {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]receivedWebhook(nil), received...)
+	}
+}
+
+func testTarget(url string, deliveryID int) *models.WebhookTarget {
+	return &models.WebhookTarget{
+		DeliveryID: deliveryID,
+		URL:        url,
+		Secret:     "test-secret",
+		EventType:  events.NoteCreated,
+		Payload:    []byte(`{"ID":"1-1","Type":"note.created","Data":{"ID":7}}`),
+	}
+}
+
+// verifyWebhook checks a request the way a receiver would, recomputing the
+// signature from the secret.
+func verifyWebhook(t *testing.T, got receivedWebhook, target *models.WebhookTarget) {
+	t.Helper()
+	if string(got.body) != string(target.Payload) {
+		t.Errorf("body = %s, want %s", got.body, target.Payload)
+	}
+	if event := got.header.Get("X-Webhook-Event"); event != target.EventType {
+		t.Errorf("X-Webhook-Event = %q, want %q", event, target.EventType)
+	}
+	if delivery := got.header.Get("X-Webhook-Delivery"); delivery != strconv.Itoa(target.DeliveryID) {
+		t.Errorf("X-Webhook-Delivery = %q, want %d", delivery, target.DeliveryID)
+	}
+
+	timestamp := got.header.Get("X-Webhook-Timestamp")
+	sent, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || time.Since(time.Unix(sent, 0)) > time.Minute {
+		t.Errorf("X-Webhook-Timestamp = %q, want the time of sending", timestamp)
+	}
+	mac := hmac.New(sha256.New, []byte(target.Secret))
+	mac.Write([]byte(timestamp + "." + string(target.Payload)))
+	want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	if signature := got.header.Get("X-Webhook-Signature"); signature != want {
+		t.Errorf("X-Webhook-Signature = %q, want %q", signature, want)
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	server, received := webhookReceiver(t, http.StatusNoContent)
+	s := newWebhookService(nil, allowLoopback)
+	target := testTarget(server.URL, 42)
+
+	status, err := s.send(context.Background(), target)
+	if err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	if status == nil || *status != http.StatusNoContent {
+		t.Errorf("status = %v, want 204", status)
+	}
+
+	requests := received()
+	if len(requests) != 1 {
+		t.Fatalf("receiver got %d requests, want 1", len(requests))
+	}
+	verifyWebhook(t, requests[0], target)
+
+	timestamp := requests[0].header.Get("X-Webhook-Timestamp")
+	if SignWebhook("other-secret", timestamp, target.Payload) == SignWebhook(target.Secret, timestamp, target.Payload) {
+		t.Error("signature does not depend on the secret")
+	}
+}
+
+func TestWebhookRetryBackoff(t *testing.T) {
+	server, received := webhookReceiver(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK)
+	s := newWebhookService(nil, allowLoopback)
+	target := testTarget(server.URL, 1)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	want := []struct {
+		responseStatus int
+		status         string
+		retryAfter     time.Duration
+	}{
+		{http.StatusInternalServerError, models.DeliveryPending, 30 * time.Second},
+		{http.StatusBadGateway, models.DeliveryPending, time.Minute},
+		{http.StatusOK, models.DeliverySucceeded, 0},
+	}
+	for i, step := range want {
+		responseStatus, err := s.send(context.Background(), target)
+		if responseStatus == nil || *responseStatus != step.responseStatus {
+			t.Fatalf("attempt %d: response status = %v, want %d", i+1, responseStatus, step.responseStatus)
+		}
+		status, next := deliveryOutcome(i+1, err, now)
+		if status != step.status {
+			t.Errorf("attempt %d: status = %s, want %s (err %v)", i+1, status, step.status, err)
+		}
+		switch {
+		case step.retryAfter == 0 && next != nil:
+			t.Errorf("attempt %d: next attempt at %v, want none", i+1, next)
+		case step.retryAfter != 0 && (next == nil || next.Sub(now) != step.retryAfter):
+			t.Errorf("attempt %d: next attempt at %v, want after %s", i+1, next, step.retryAfter)
+		}
+	}
+	requests := received()
+	if len(requests) != 3 {
+		t.Fatalf("receiver got %d requests, want 3", len(requests))
+	}
+	for _, request := range requests {
+		verifyWebhook(t, request, target)
+	}
+
+	// The backoff doubles until the last attempt, which gives up.
+	failure := errors.New("receiver responded with 500 Internal Server Error")
+	status, next := deliveryOutcome(webhookMaxAttempts-1, failure, now)
+	if status != models.DeliveryPending || next == nil || next.Sub(now) != webhookBackoff<<(webhookMaxAttempts-2) {
+		t.Errorf("attempt %d: status %s, next %v", webhookMaxAttempts-1, status, next)
+	}
+	if status, next := deliveryOutcome(webhookMaxAttempts, failure, now); status != models.DeliveryFailed || next != nil {
+		t.Errorf("last attempt: status %s, next %v, want failed without retry", status, next)
+	}
+}
+
+// A replay is a new delivery of the stored payload, so the receiver sees
+// the same body and event under a new delivery ID, signed at the time it
+// is sent.
+func TestWebhookReplay(t *testing.T) {
+	server, received := webhookReceiver(t, http.StatusOK)
+	s := newWebhookService(nil, allowLoopback)
+	original := testTarget(server.URL, 10)
+	replay := testTarget(server.URL, 11)
+
+	for _, target := range []*models.WebhookTarget{original, replay} {
+		if _, err := s.send(context.Background(), target); err != nil {
+			t.Fatalf("send delivery %d: %v", target.DeliveryID, err)
+		}
+	}
+
+	requests := received()
+	if len(requests) != 2 {
+		t.Fatalf("receiver got %d requests, want 2", len(requests))
+	}
+	verifyWebhook(t, requests[0], original)
+	verifyWebhook(t, requests[1], replay)
+	if string(requests[0].body) != string(requests[1].body) {
+		t.Error("replay changed the payload")
+	}
+}
+
+func TestWebhookRedirectNotFollowed(t *testing.T) {
+	server, received := webhookReceiver(t, http.StatusFound)
+	s := newWebhookService(nil, allowLoopback)
+
+	status, err := s.send(context.Background(), testTarget(server.URL, 1))
+	if err == nil {
+		t.Error("a redirect counted as delivered")
+	}
+	if status == nil || *status != http.StatusFound {
+		t.Errorf("status = %v, want 302", status)
+	}
+	if len(received()) != 1 {
+		t.Errorf("receiver got %d requests, want 1", len(received()))
+	}
+}
+
+// Connections are checked after the host is resolved, so a webhook whose
+// host resolves to an internal address by the time it is sent is refused.
+func TestWebhookDialRejectsInternalAddress(t *testing.T) {
+	server, received := webhookReceiver(t, http.StatusOK)
+	s := newWebhookService(nil, isPublicAddr)
+
+	_, err := s.send(context.Background(), testTarget(server.URL, 1))
+	if !errors.Is(err, errWebhookAddress) {
+		t.Errorf("send returned %v, want errWebhookAddress", err)
+	}
+	if len(received()) != 0 {
+		t.Error("the request reached a loopback receiver")
+	}
+}
+
+func TestValidateWebhookAddress(t *testing.T) {
+	s := newWebhookService(nil, isPublicAddr)
+	ctx := context.Background()
+	types := []string{"*"}
+
+	for _, url := range []string{
+		"http://127.0.0.1/hook",
+		"http://127.1.2.3:8080/hook",
+		"http://10.0.0.5/hook",
+		"http://172.16.0.1/hook",
+		"http://192.168.1.1/hook",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://100.64.0.1/hook",
+		"http://0.0.0.0/hook",
+		"http://[::1]/hook",
+		"http://[::ffff:127.0.0.1]/hook",
+		"http://[fd00::1]/hook",
+		"http://[fe80::1]/hook",
+	} {
+		if err := s.validateWebhook(ctx, url, types); !errors.Is(err, errWebhookAddress) {
+			t.Errorf("validateWebhook(%s) = %v, want errWebhookAddress", url, err)
+		}
+	}
+
+	for _, url := range []string{"https://93.184.216.34/hook", "http://[2606:2800:220:1::]/hook"} {
+		if err := s.validateWebhook(ctx, url, types); err != nil {
+			t.Errorf("validateWebhook(%s) = %v", url, err)
+		}
+	}
+
+	for _, url := range []string{"ftp://93.184.216.34/", "/relative", "http:///path"} {
+		if err := s.validateWebhook(ctx, url, types); err == nil || errors.Is(err, errWebhookAddress) {
+			t.Errorf("validateWebhook(%s) = %v, want an invalid URL error", url, err)
+		}
+	}
+}
+
+// Publishing must not wait for the deliveries to be recorded, even when
+// the queue is full.
+func TestHandleEventDoesNotBlock(t *testing.T) {
+	s := newWebhookService(nil, isPublicAddr)
+	s.queue = make(chan queuedEvent, 1)
+
+	done := make(chan struct{})
+	go func() {
+		s.HandleEvent("user", events.Event{ID: "1-1", Type: events.NoteCreated})
+		s.HandleEvent("user", events.Event{ID: "1-2", Type: events.NoteCreated})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("HandleEvent blocked")
+	}
+	if len(s.queue) != 1 {
+		t.Errorf("queue holds %d events, want 1", len(s.queue))
+	}
+}
+
+func TestWebhookClose(t *testing.T) {
+	s := newWebhookService(nil, isPublicAddr)
+	go s.recordEvents()
+	s.Close()
+
+	// Events published after closing, by requests still running, are
+	// dropped instead of panicking on the closed queue.
+	s.HandleEvent("user", events.Event{ID: "1-1", Type: events.NoteCreated})
+	s.Close()
+}
@@ -0,0 +1,220 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	testAccessKey = "test-access-key"
+	testSecretKey = "test-secret-key"
+	testRegion    = "us-east-1"
+	testBucket    = "attachments"
+)
+
+// fakeS3 is a stand-in for an S3 bucket that keeps objects in memory and
+// rejects requests whose Signature Version 4 does not check out.
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+	methods []string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if err := verifySignature(r); err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	key, ok := strings.CutPrefix(r.URL.Path, "/"+testBucket+"/")
+	if !ok {
+		http.Error(w, "NoSuchBucket", http.StatusNotFound)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.methods = append(f.methods, r.Method)
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil || int64(len(data)) != r.ContentLength {
+			http.Error(w, "IncompleteBody", http.StatusBadRequest)
+			return
+		}
+		f.objects[key] = data
+	case http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			http.Error(w, "NoSuchKey", http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "MethodNotAllowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// verifySignature recomputes the signature of the request as received,
+// following the Signature Version 4 steps for an unsigned payload.
+func verifySignature(r *http.Request) error {
+	amzDate := r.Header.Get("x-amz-date")
+	if len(amzDate) != len("20060102T150405Z") {
+		return errors.New("missing x-amz-date")
+	}
+	if r.Header.Get("x-amz-content-sha256") != "UNSIGNED-PAYLOAD" {
+		return errors.New("unexpected x-amz-content-sha256")
+	}
+	date := amzDate[:8]
+	scope := date + "/" + testRegion + "/s3/aws4_request"
+
+	canonicalRequest := r.Method + "\n" +
+		r.URL.EscapedPath() + "\n" +
+		r.URL.RawQuery + "\n" +
+		"host:" + r.Host + "\n" +
+		"x-amz-content-sha256:UNSIGNED-PAYLOAD\n" +
+		"x-amz-date:" + amzDate + "\n" +
+		"\n" +
+		"host;x-amz-content-sha256;x-amz-date\n" +
+		"UNSIGNED-PAYLOAD"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+testSecretKey), date)
+	for _, part := range []string{testRegion, "s3", "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, part)
+	}
+	want := "AWS4-HMAC-SHA256 Credential=" + testAccessKey + "/" + scope +
+		", SignedHeaders=host;x-amz-content-sha256;x-amz-date" +
+		", Signature=" + hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+	if got := r.Header.Get("Authorization"); got != want {
+		return errors.New("SignatureDoesNotMatch")
+	}
+	return nil
+}
+
+func newTestS3(t *testing.T, secretKey string) (*S3BlobStore, *fakeS3) {
+	t.Helper()
+	fake := &fakeS3{objects: map[string][]byte{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	store, err := NewS3BlobStore(S3Config{
+		Endpoint:  server.URL + "/",
+		Region:    testRegion,
+		Bucket:    testBucket,
+		AccessKey: testAccessKey,
+		SecretKey: secretKey,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, fake
+}
+
+func TestS3BlobStoreRoundTrip(t *testing.T) {
+	store, fake := newTestS3(t, testSecretKey)
+	ctx := context.Background()
+	content := []byte("attachment content")
+
+	if err := store.Put(ctx, "abc123", bytes.NewReader(content), int64(len(content))); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if got := fake.objects["abc123"]; !bytes.Equal(got, content) {
+		t.Fatalf("stored %q, want %q", got, content)
+	}
+
+	body, err := store.Get(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, content) {
+		t.Errorf("Get returned %q, want %q", got, content)
+	}
+
+	if err := store.Delete(ctx, "abc123"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, ok := fake.objects["abc123"]; ok {
+		t.Error("Delete left the object in the bucket")
+	}
+	if _, err := store.Get(ctx, "abc123"); !errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("Get after Delete returned %v, want ErrBlobNotFound", err)
+	}
+	// Deleting what is already gone is not an error, so cleanups can retry.
+	if err := store.Delete(ctx, "abc123"); err != nil {
+		t.Errorf("second Delete: %v", err)
+	}
+}
+
+func TestS3BlobStoreRejectedSignature(t *testing.T) {
+	store, fake := newTestS3(t, "wrong-secret")
+	ctx := context.Background()
+
+	err := store.Put(ctx, "abc123", strings.NewReader("x"), 1)
+	if err == nil || !strings.Contains(err.Error(), "403") || !strings.Contains(err.Error(), "SignatureDoesNotMatch") {
+		t.Errorf("Put returned %v, want the 403 response", err)
+	}
+	if _, err := store.Get(ctx, "abc123"); err == nil || errors.Is(err, ErrBlobNotFound) {
+		t.Errorf("Get returned %v, want the 403 response", err)
+	}
+	if err := store.Delete(ctx, "abc123"); err == nil {
+		t.Error("Delete ignored the 403 response")
+	}
+	if len(fake.objects) != 0 {
+		t.Error("an unsigned request reached the bucket")
+	}
+}
+
+func TestS3BlobStoreInvalidKey(t *testing.T) {
+	store, fake := newTestS3(t, testSecretKey)
+	ctx := context.Background()
+
+	for _, key := range []string{"", "../secret", "a/b", "a b"} {
+		if err := store.Put(ctx, key, strings.NewReader("x"), 1); err == nil {
+			t.Errorf("Put accepted key %q", key)
+		}
+		if _, err := store.Get(ctx, key); err == nil {
+			t.Errorf("Get accepted key %q", key)
+		}
+		if err := store.Delete(ctx, key); err == nil {
+			t.Errorf("Delete accepted key %q", key)
+		}
+	}
+	if len(fake.methods) != 0 {
+		t.Errorf("invalid keys were sent to S3: %v", fake.methods)
+	}
+}
+
+func TestNewS3BlobStoreConfig(t *testing.T) {
+	valid := S3Config{Endpoint: "http://localhost:9000", Bucket: "b", AccessKey: "a", SecretKey: "s"}
+	if _, err := NewS3BlobStore(valid); err != nil {
+		t.Errorf("valid config: %v", err)
+	}
+
+	missingBucket := valid
+	missingBucket.Bucket = ""
+	badEndpoint := valid
+	badEndpoint.Endpoint = "localhost"
+	for name, config := range map[string]S3Config{"missing bucket": missingBucket, "bad endpoint": badEndpoint} {
+		if _, err := NewS3BlobStore(config); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package tiptap
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var htmlSpace = regexp.MustCompile(`[ \t\r\n\f]+`)
+
+// FromHTML converts the HTML the editor saved before notes were stored as
+// TipTap JSON into a document. Elements without a TipTap equivalent are
+// unwrapped and keep their text.
+func FromHTML(source string) (*Node, error) {
+	body := &html.Node{Type: html.ElementNode, DataAtom: atom.Body, Data: "body"}
+	nodes, err := html.ParseFragment(strings.NewReader(source), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse html: %w", err)
+	}
+
+	doc := NewDoc()
+	doc.Content = htmlBlocks(nodes)
+	return doc, nil
+}
+
+// htmlBlocks converts a run of sibling elements to blocks. Inline content
+// between block elements is gathered into paragraphs.
+func htmlBlocks(nodes []*html.Node) []*Node {
+	var blocks, inline []*Node
+	flush := func() {
+		if inline = trimInline(inline); len(inline) > 0 {
+			blocks = append(blocks, &Node{Type: "paragraph", Content: inline})
+		}
+		inline = nil
+	}
+
+	for _, n := range nodes {
+		if n.Type == html.ElementNode && isHTMLBlock(n.DataAtom) {
+			flush()
+			blocks = append(blocks, htmlBlock(n)...)
+			continue
+		}
+		inline = appendInline(inline, htmlInline(n, nil)...)
+	}
+	flush()
+	return blocks
+}
+
+func isHTMLBlock(a atom.Atom) bool {
+	switch a {
+	case atom.P, atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Ul, atom.Ol, atom.Li,
+		atom.Blockquote, atom.Pre, atom.Hr, atom.Table, atom.Thead, atom.Tbody, atom.Tfoot, atom.Tr,
+		atom.Div, atom.Section, atom.Article, atom.Header, atom.Footer, atom.Main, atom.Aside, atom.Nav:
+		return true
+	}
+	return false
+}
+
+func htmlBlock(n *html.Node) []*Node {
+	switch n.DataAtom {
+	case atom.P:
+		content := trimInline(htmlInlines(n, nil))
+		// A paragraph holding a single image becomes a block image.
+		if len(content) == 1 && content[0].Type == "image" {
+			return content
+		}
+		return []*Node{withTextAlign(&Node{Type: "paragraph", Content: content}, n)}
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		heading := &Node{Type: "heading", Attrs: map[string]interface{}{"level": level}, Content: trimInline(htmlInlines(n, nil))}
+		return []*Node{withTextAlign(heading, n)}
+	case atom.Ul:
+		if htmlAttr(n, "data-type") == "taskList" {
+			return []*Node{htmlList(n, "taskList", "taskItem")}
+		}
+		return []*Node{htmlList(n, "bulletList", "listItem")}
+	case atom.Ol:
+		list := htmlList(n, "orderedList", "listItem")
+		start := 1
+		if value, err := strconv.Atoi(htmlAttr(n, "start")); err == nil {
+			start = value
+		}
+		list.Attrs = map[string]interface{}{"start": start}
+		return []*Node{list}
+	case atom.Li:
+		// A list item outside of a list.
+		return listItemBlocks(n)
+	case atom.Blockquote:
+		return []*Node{{Type: "blockquote", Content: nonEmptyBlocks(htmlBlocks(children(n)))}}
+	case atom.Pre:
+		return []*Node{htmlCodeBlock(n)}
+	case atom.Hr:
+		return []*Node{{Type: "horizontalRule"}}
+	case atom.Table:
+		return []*Node{htmlTable(n)}
+	}
+	return htmlBlocks(children(n))
+}
+
+func htmlList(n *html.Node, listType, itemType string) *Node {
+	list := &Node{Type: listType}
+	for _, child := range children(n) {
+		if child.Type != html.ElementNode || child.DataAtom != atom.Li {
+			continue
+		}
+		item := &Node{Type: itemType, Content: listItemBlocks(child)}
+		if itemType == "taskItem" {
+			item.Attrs = map[string]interface{}{"checked": htmlAttr(child, "data-checked") == "true"}
+		}
+		list.Content = append(list.Content, item)
+	}
+	return list
+}
+
+// listItemBlocks converts the content of a list item. TipTap writes the
+// checkbox of a task item in a label and the text in a div, which are both
+// unwrapped here.
+func listItemBlocks(n *html.Node) []*Node {
+	var content []*html.Node
+	for _, child := range children(n) {
+		if child.Type == html.ElementNode && (child.DataAtom == atom.Label || child.DataAtom == atom.Input) {
+			continue
+		}
+		content = append(content, child)
+	}
+	return nonEmptyBlocks(htmlBlocks(content))
+}
+
+func htmlCodeBlock(n *html.Node) *Node {
+	node := &Node{Type: "codeBlock", Attrs: map[string]interface{}{"language": nil}}
+	for _, child := range children(n) {
+		if child.Type != html.ElementNode || child.DataAtom != atom.Code {
+			continue
+		}
+		for _, class := range strings.Fields(htmlAttr(child, "class")) {
+			if language := strings.TrimPrefix(class, "language-"); language != class && language != "" {
+				node.SetAttr("language", language)
+			}
+		}
+	}
+	if code := strings.TrimSuffix(htmlText(n), "\n"); code != "" {
+		node.Content = []*Node{{Type: "text", Text: code}}
+	}
+	return node
+}
+
+func htmlTable(n *html.Node) *Node {
+	table := &Node{Type: "table"}
+	var rows func(parent *html.Node)
+	rows = func(parent *html.Node) {
+		for _, child := range children(parent) {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			switch child.DataAtom {
+			case atom.Thead, atom.Tbody, atom.Tfoot:
+				rows(child)
+			case atom.Tr:
+				row := &Node{Type: "tableRow"}
+				for _, cell := range children(child) {
+					if cell.Type != html.ElementNode || (cell.DataAtom != atom.Td && cell.DataAtom != atom.Th) {
+						continue
+					}
+					cellType := "tableCell"
+					if cell.DataAtom == atom.Th {
+						cellType = "tableHeader"
+					}
+					row.Content = append(row.Content, &Node{Type: cellType, Content: nonEmptyBlocks(htmlBlocks(children(cell)))})
+				}
+				table.Content = append(table.Content, row)
+			}
+		}
+	}
+	rows(n)
+	return table
+}
+
+func htmlInlines(parent *html.Node, marks []Mark) []*Node {
+	var nodes []*Node
+	for _, child := range children(parent) {
+		nodes = appendInline(nodes, htmlInline(child, marks)...)
+	}
+	return nodes
+}
+
+func htmlInline(n *html.Node, marks []Mark) []*Node {
+	switch n.Type {
+	case html.TextNode:
+		return []*Node{textNode(htmlSpace.ReplaceAllString(n.Data, " "), marks)}
+	case html.ElementNode:
+	default:
+		return nil
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		return []*Node{{Type: "hardBreak"}}
+	case atom.Img:
+		src := htmlAttr(n, "src")
+		if src == "" {
+			return nil
+		}
+		image := &Node{Type: "image", Attrs: map[string]interface{}{"src": src, "alt": htmlAttr(n, "alt")}}
+		if title := htmlAttr(n, "title"); title != "" {
+			image.SetAttr("title", title)
+		}
+		return []*Node{image}
+	case atom.Script, atom.Style, atom.Input:
+		return nil
+	}
+
+	if mark, ok := htmlMark(n); ok {
+		marks = withMark(marks, mark)
+	}
+	return htmlInlines(n, marks)
+}
+
+// htmlMark returns the mark written by an inline element, as produced by
+// the StarterKit, Highlight, TextStyle and Color extensions.
+func htmlMark(n *html.Node) (Mark, bool) {
+	switch n.DataAtom {
+	case atom.Strong, atom.B:
+		return Mark{Type: "bold"}, true
+	case atom.Em, atom.I:
+		return Mark{Type: "italic"}, true
+	case atom.S, atom.Strike, atom.Del:
+		return Mark{Type: "strike"}, true
+	case atom.U:
+		return Mark{Type: "underline"}, true
+	case atom.Code:
+		return Mark{Type: "code"}, true
+	case atom.Mark:
+		highlight := Mark{Type: "highlight"}
+		if color := htmlAttr(n, "data-color"); color != "" {
+			highlight.Attrs = map[string]interface{}{"color": color}
+		}
+		return highlight, true
+	case atom.A:
+		href := htmlAttr(n, "href")
+		if href == "" {
+			return Mark{}, false
+		}
+		return Mark{Type: "link", Attrs: map[string]interface{}{"href": href}}, true
+	case atom.Span:
+		if color := htmlStyle(n)["color"]; color != "" {
+			return Mark{Type: "textStyle", Attrs: map[string]interface{}{"color": color}}, true
+		}
+	}
+	return Mark{}, false
+}
+
+// trimInline drops the whitespace at the edges of a block, which HTML does
+// not render either.
+func trimInline(nodes []*Node) []*Node {
+	for len(nodes) > 0 && nodes[0].Type == "text" {
+		nodes[0].Text = strings.TrimLeft(nodes[0].Text, " ")
+		if nodes[0].Text != "" {
+			break
+		}
+		nodes = nodes[1:]
+	}
+	for len(nodes) > 0 && nodes[len(nodes)-1].Type == "text" {
+		last := nodes[len(nodes)-1]
+		last.Text = strings.TrimRight(last.Text, " ")
+		if last.Text != "" {
+			break
+		}
+		nodes = nodes[:len(nodes)-1]
+	}
+	return nodes
+}
+
+// nonEmptyBlocks returns blocks, or a single empty paragraph when there are
+// none, since list items, quotes and cells need at least one block.
+func nonEmptyBlocks(blocks []*Node) []*Node {
+	if len(blocks) == 0 {
+		return []*Node{{Type: "paragraph"}}
+	}
+	return blocks
+}
+
+func withTextAlign(node *Node, n *html.Node) *Node {
+	if align := htmlStyle(n)["text-align"]; align != "" {
+		node.SetAttr("textAlign", align)
+	}
+	return node
+}
+
+func children(n *html.Node) []*html.Node {
+	var nodes []*html.Node
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		nodes = append(nodes, child)
+	}
+	return nodes
+}
+
+func htmlAttr(n *html.Node, key string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return ""
+}
+
+// htmlStyle parses the style attribute into lower case property names.
+func htmlStyle(n *html.Node) map[string]string {
+	style := map[string]string{}
+	for _, declaration := range strings.Split(htmlAttr(n, "style"), ";") {
+		name, value, ok := strings.Cut(declaration, ":")
+		if !ok {
+			continue
+		}
+		style[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+	}
+	return style
+}
+
+// htmlText returns the raw text below n, keeping whitespace as written.
+func htmlText(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.DataAtom == atom.Br {
+			sb.WriteString("\n")
+			continue
+		}
+		sb.WriteString(htmlText(child))
+	}
+	return sb.String()
+}
@@ -0,0 +1,301 @@
+package tiptap
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	extast "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var markdownParser = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// FromMarkdown parses CommonMark/GFM into a TipTap document.
+func FromMarkdown(source []byte) (*Node, error) {
+	root := markdownParser.Parser().Parse(text.NewReader(source))
+	doc, ok := root.(*ast.Document)
+	if !ok {
+		return nil, fmt.Errorf("failed to parse markdown")
+	}
+
+	c := converter{source: source}
+	result := NewDoc()
+	result.Content = c.blocks(doc)
+	return result, nil
+}
+
+type converter struct {
+	source []byte
+}
+
+func (c *converter) blocks(parent ast.Node) []*Node {
+	var nodes []*Node
+	for child := parent.FirstChild(); child != nil; child = child.NextSibling() {
+		nodes = append(nodes, c.block(child)...)
+	}
+	return nodes
+}
+
+func (c *converter) block(n ast.Node) []*Node {
+	switch n := n.(type) {
+	case *ast.Paragraph, *ast.TextBlock:
+		inline := c.inlines(n, nil)
+		// A paragraph holding a single image becomes a block image.
+		if len(inline) == 1 && inline[0].Type == "image" {
+			return inline
+		}
+		if len(inline) == 0 {
+			return nil
+		}
+		return []*Node{{Type: "paragraph", Content: inline}}
+	case *ast.Heading:
+		return []*Node{{
+			Type:    "heading",
+			Attrs:   map[string]interface{}{"level": n.Level},
+			Content: c.inlines(n, nil),
+		}}
+	case *ast.FencedCodeBlock:
+		node := &Node{Type: "codeBlock", Attrs: map[string]interface{}{"language": nil}}
+		if language := string(n.Language(c.source)); language != "" {
+			node.SetAttr("language", language)
+		}
+		if code := c.lines(n); code != "" {
+			node.Content = []*Node{{Type: "text", Text: code}}
+		}
+		return []*Node{node}
+	case *ast.CodeBlock:
+		node := &Node{Type: "codeBlock", Attrs: map[string]interface{}{"language": nil}}
+		if code := c.lines(n); code != "" {
+			node.Content = []*Node{{Type: "text", Text: code}}
+		}
+		return []*Node{node}
+	case *ast.Blockquote:
+		return []*Node{{Type: "blockquote", Content: c.blocks(n)}}
+	case *ast.ThematicBreak:
+		return []*Node{{Type: "horizontalRule"}}
+	case *ast.List:
+		return []*Node{c.list(n)}
+	case *ast.HTMLBlock:
+		raw := strings.TrimSpace(c.lines(n))
+		if raw == "" {
+			return nil
+		}
+		return []*Node{{Type: "paragraph", Content: []*Node{{Type: "text", Text: raw}}}}
+	case *extast.Table:
+		return []*Node{c.table(n)}
+	}
+	return c.blocks(n)
+}
+
+func (c *converter) list(list *ast.List) *Node {
+	task := isTaskList(list)
+	node := &Node{Type: "bulletList"}
+	switch {
+	case task:
+		node.Type = "taskList"
+	case list.IsOrdered():
+		node.Type = "orderedList"
+		node.Attrs = map[string]interface{}{"start": list.Start}
+	}
+
+	for item := list.FirstChild(); item != nil; item = item.NextSibling() {
+		child := &Node{Type: "listItem", Content: c.blocks(item)}
+		if task {
+			child.Type = "taskItem"
+			child.Attrs = map[string]interface{}{"checked": taskChecked(item)}
+		}
+		if len(child.Content) == 0 {
+			child.Content = []*Node{{Type: "paragraph"}}
+		}
+		node.Content = append(node.Content, child)
+	}
+	return node
+}
+
+func isTaskList(list *ast.List) bool {
+	if list.IsOrdered() || list.FirstChild() == nil {
+		return false
+	}
+	for item := list.FirstChild(); item != nil; item = item.NextSibling() {
+		if taskCheckBox(item) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+func taskCheckBox(item ast.Node) *extast.TaskCheckBox {
+	first := item.FirstChild()
+	if first == nil {
+		return nil
+	}
+	box, _ := first.FirstChild().(*extast.TaskCheckBox)
+	return box
+}
+
+func taskChecked(item ast.Node) bool {
+	box := taskCheckBox(item)
+	return box != nil && box.IsChecked
+}
+
+func (c *converter) table(table *extast.Table) *Node {
+	node := &Node{Type: "table"}
+	for row := table.FirstChild(); row != nil; row = row.NextSibling() {
+		cellType := "tableCell"
+		if _, ok := row.(*extast.TableHeader); ok {
+			cellType = "tableHeader"
+		}
+		rowNode := &Node{Type: "tableRow"}
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			paragraph := &Node{Type: "paragraph", Content: c.inlines(cell, nil)}
+			rowNode.Content = append(rowNode.Content, &Node{Type: cellType, Content: []*Node{paragraph}})
+		}
+		node.Content = append(node.Content, rowNode)
+	}
+	return node
+}
+
+func (c *converter) lines(n ast.Node) string {
+	var sb strings.Builder
+	lines := n.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		sb.Write(segment.Value(c.source))
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+func (c *converter) inlines(parent ast.Node, marks []Mark) []*Node {
+	var nodes []*Node
+	for child := parent.FirstChild(); child != nil; child = child.NextSibling() {
+		nodes = appendInline(nodes, c.inline(child, marks)...)
+	}
+	return nodes
+}
+
+func (c *converter) inline(n ast.Node, marks []Mark) []*Node {
+	switch n := n.(type) {
+	case *ast.Text:
+		value := n.Segment.Value(c.source)
+		if !n.IsRaw() {
+			value = unescape(value)
+		}
+		nodes := []*Node{textNode(string(value), marks)}
+		switch {
+		case n.HardLineBreak():
+			nodes = append(nodes, &Node{Type: "hardBreak"})
+		case n.SoftLineBreak():
+			nodes = append(nodes, textNode(" ", marks))
+		}
+		return nodes
+	case *ast.String:
+		return []*Node{textNode(string(n.Value), marks)}
+	case *ast.CodeSpan:
+		var sb strings.Builder
+		for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+			if t, ok := child.(*ast.Text); ok {
+				sb.Write(t.Segment.Value(c.source))
+			}
+		}
+		return []*Node{textNode(sb.String(), []Mark{{Type: "code"}})}
+	case *ast.Emphasis:
+		markType := "italic"
+		if n.Level >= 2 {
+			markType = "bold"
+		}
+		return c.inlines(n, withMark(marks, Mark{Type: markType}))
+	case *extast.Strikethrough:
+		return c.inlines(n, withMark(marks, Mark{Type: "strike"}))
+	case *ast.Link:
+		link := Mark{Type: "link", Attrs: map[string]interface{}{"href": string(unescape(n.Destination))}}
+		if len(n.Title) > 0 {
+			link.Attrs["title"] = string(unescape(n.Title))
+		}
+		return c.inlines(n, withMark(marks, link))
+	case *ast.AutoLink:
+		href := string(n.URL(c.source))
+		if n.AutoLinkType == ast.AutoLinkEmail && !strings.HasPrefix(href, "mailto:") {
+			href = "mailto:" + href
+		}
+		link := Mark{Type: "link", Attrs: map[string]interface{}{"href": href}}
+		return []*Node{textNode(string(n.Label(c.source)), withMark(marks, link))}
+	case *ast.Image:
+		image := &Node{Type: "image", Attrs: map[string]interface{}{
+			"src": string(unescape(n.Destination)),
+			"alt": plainInline(c.inlines(n, nil)),
+		}}
+		if len(n.Title) > 0 {
+			image.SetAttr("title", string(unescape(n.Title)))
+		}
+		return []*Node{image}
+	case *ast.RawHTML:
+		var sb strings.Builder
+		for i := 0; i < n.Segments.Len(); i++ {
+			segment := n.Segments.At(i)
+			sb.Write(segment.Value(c.source))
+		}
+		return []*Node{textNode(sb.String(), marks)}
+	case *extast.TaskCheckBox:
+		return nil
+	}
+	return c.inlines(n, marks)
+}
+
+// unescape resolves backslash escapes and entity references, which goldmark
+// leaves in place for its own renderer to handle.
+func unescape(value []byte) []byte {
+	return util.ResolveNumericReferences(util.ResolveEntityNames(util.UnescapePunctuations(value)))
+}
+
+func textNode(value string, marks []Mark) *Node {
+	return &Node{Type: "text", Text: value, Marks: marks}
+}
+
+func withMark(marks []Mark, mark Mark) []Mark {
+	result := make([]Mark, 0, len(marks)+1)
+	result = append(result, marks...)
+	return append(result, mark)
+}
+
+// appendInline merges adjacent text nodes that carry the same marks, since
+// goldmark splits text at escapes and line breaks.
+func appendInline(nodes []*Node, next ...*Node) []*Node {
+	for _, node := range next {
+		if node.Type == "text" && node.Text == "" {
+			continue
+		}
+		if node.Type == "text" && len(nodes) > 0 {
+			last := nodes[len(nodes)-1]
+			if last.Type == "text" && sameMarks(last.Marks, node.Marks) {
+				last.Text += node.Text
+				continue
+			}
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+func sameMarks(a, b []Mark) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !sameMark(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func plainInline(nodes []*Node) string {
+	var sb strings.Builder
+	for _, node := range nodes {
+		sb.WriteString(node.textContent())
+	}
+	return sb.String()
+}
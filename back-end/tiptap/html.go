@@ -0,0 +1,161 @@
+package tiptap
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+)
+
+// ToHTML renders a document as an HTML fragment. Every piece of text and
+// every attribute is escaped and only safe URL schemes are kept, so the output
+// can be served to browsers as is.
+func ToHTML(doc *Node) string {
+	var sb strings.Builder
+	for _, child := range doc.Content {
+		writeHTML(&sb, child)
+	}
+	return sb.String()
+}
+
+// ToHTMLDocument wraps ToHTML in a standalone page with the given title.
+func ToHTMLDocument(title string, doc *Node) string {
+	return "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>" +
+		html.EscapeString(title) + "</title>\n</head>\n<body>\n<h1>" +
+		html.EscapeString(title) + "</h1>\n" + ToHTML(doc) + "\n</body>\n</html>\n"
+}
+
+func writeHTML(sb *strings.Builder, node *Node) {
+	switch node.Type {
+	case "text":
+		writeHTMLText(sb, node)
+		return
+	case "hardBreak":
+		sb.WriteString("<br>")
+		return
+	case "horizontalRule":
+		sb.WriteString("<hr>")
+		return
+	case "image":
+		src := SafeURL(node.Attr("src"))
+		if src == "" {
+			return
+		}
+		sb.WriteString(`<img src="` + html.EscapeString(src) + `" alt="` + html.EscapeString(node.Attr("alt")) + `"`)
+		if title := node.Attr("title"); title != "" {
+			sb.WriteString(` title="` + html.EscapeString(title) + `"`)
+		}
+		sb.WriteString(">")
+		return
+	case "codeBlock":
+		sb.WriteString("<pre><code")
+		if language := node.Attr("language"); language != "" {
+			sb.WriteString(` class="language-` + html.EscapeString(language) + `"`)
+		}
+		sb.WriteString(">" + html.EscapeString(node.textContent()) + "</code></pre>")
+		return
+	}
+
+	open, close := htmlTags(node)
+	sb.WriteString(open)
+	for _, child := range node.Content {
+		writeHTML(sb, child)
+	}
+	sb.WriteString(close)
+}
+
+func htmlTags(node *Node) (string, string) {
+	switch node.Type {
+	case "paragraph":
+		return "<p>", "</p>"
+	case "heading":
+		level := node.AttrInt("level", 1)
+		if level < 1 || level > 6 {
+			level = 1
+		}
+		return fmt.Sprintf("<h%d>", level), fmt.Sprintf("</h%d>", level)
+	case "blockquote":
+		return "<blockquote>", "</blockquote>"
+	case "bulletList":
+		return "<ul>", "</ul>"
+	case "orderedList":
+		if start := node.AttrInt("start", 1); start != 1 {
+			return fmt.Sprintf(`<ol start="%d">`, start), "</ol>"
+		}
+		return "<ol>", "</ol>"
+	case "listItem":
+		return "<li>", "</li>"
+	case "taskList":
+		return `<ul data-type="taskList">`, "</ul>"
+	case "taskItem":
+		if node.AttrBool("checked") {
+			return `<li data-type="taskItem" data-checked="true"><input type="checkbox" checked disabled> `, "</li>"
+		}
+		return `<li data-type="taskItem" data-checked="false"><input type="checkbox" disabled> `, "</li>"
+	case "table":
+		return "<table>", "</table>"
+	case "tableRow":
+		return "<tr>", "</tr>"
+	case "tableHeader":
+		return "<th>", "</th>"
+	case "tableCell":
+		return "<td>", "</td>"
+	}
+	return "", ""
+}
+
+func writeHTMLText(sb *strings.Builder, node *Node) {
+	var closers []string
+	for _, mark := range node.Marks {
+		open, close := htmlMarkTags(mark)
+		if open == "" {
+			continue
+		}
+		sb.WriteString(open)
+		closers = append(closers, close)
+	}
+	sb.WriteString(html.EscapeString(node.Text))
+	for i := len(closers) - 1; i >= 0; i-- {
+		sb.WriteString(closers[i])
+	}
+}
+
+func htmlMarkTags(mark Mark) (string, string) {
+	switch mark.Type {
+	case "bold":
+		return "<strong>", "</strong>"
+	case "italic":
+		return "<em>", "</em>"
+	case "strike":
+		return "<s>", "</s>"
+	case "code":
+		return "<code>", "</code>"
+	case "highlight":
+		return "<mark>", "</mark>"
+	case "link":
+		href := SafeURL(mark.Attr("href"))
+		if href == "" {
+			return "", ""
+		}
+		return `<a href="` + html.EscapeString(href) + `" rel="noopener noreferrer nofollow">`, "</a>"
+	}
+	return "", ""
+}
+
+// SafeURL returns the URL unchanged when it is relative or uses the http,
+// https or mailto scheme, and an empty string otherwise.
+func SafeURL(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return ""
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return ""
+	}
+	switch strings.ToLower(parsed.Scheme) {
+	case "", "http", "https", "mailto":
+		return raw
+	}
+	return ""
+}
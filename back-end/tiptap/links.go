@@ -0,0 +1,82 @@
+package tiptap
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const maxLinkContext = 200
+
+// noteHrefPattern matches link targets that point at another note, either
+// as note://<id> or as a URL whose path ends in /notes/<id>.
+var noteHrefPattern = regexp.MustCompile(`^(?:note://|(?:[a-z]+://[^/]*)?(?:/[^?#]*)?/notes/)(\d+)(?:[/?#].*)?$`)
+
+type NoteLink struct {
+	TargetID int
+	Context  string // text of the block containing the link
+}
+
+// NoteLinks returns the notes referenced by link marks and mention nodes,
+// one entry per target in document order.
+func NoteLinks(doc *Node) []NoteLink {
+	var links []NoteLink
+	seen := map[int]bool{}
+	add := func(target int, path []int) {
+		if seen[target] {
+			return
+		}
+		seen[target] = true
+		links = append(links, NoteLink{TargetID: target, Context: blockContext(doc, path)})
+	}
+
+	doc.Walk(func(node *Node, path []int) bool {
+		switch node.Type {
+		case "mention":
+			if id, ok := parseNoteID(node.Attr("id")); ok {
+				add(id, path)
+			}
+		case "text":
+			for _, mark := range node.Marks {
+				if mark.Type != "link" {
+					continue
+				}
+				if m := noteHrefPattern.FindStringSubmatch(mark.Attr("href")); m != nil {
+					if id, err := strconv.Atoi(m[1]); err == nil {
+						add(id, path)
+					}
+				}
+			}
+		}
+		return true
+	})
+	return links
+}
+
+func parseNoteID(value string) (int, bool) {
+	id, err := strconv.Atoi(strings.TrimPrefix(value, "note:"))
+	return id, err == nil && id > 0
+}
+
+// blockContext returns the text of the closest ancestor block of the node at
+// path, shortened to maxLinkContext runes.
+func blockContext(doc *Node, path []int) string {
+	for depth := len(path) - 1; depth >= 0; depth-- {
+		parent := doc.At(path[:depth])
+		if parent == nil || parent.Type == "doc" {
+			continue
+		}
+		if isBlock(parent.Type) {
+			return truncate(strings.Join(strings.Fields(parent.PlainText()), " "), maxLinkContext)
+		}
+	}
+	return ""
+}
+
+func truncate(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max-1]) + "…"
+}
@@ -0,0 +1,399 @@
+package tiptap
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// markOrder is the nesting order used when writing marks, outermost first.
+// Marks missing from it have no Markdown equivalent and are dropped.
+var markOrder = map[string]int{
+	"link":   0,
+	"bold":   1,
+	"italic": 2,
+	"strike": 3,
+}
+
+// ToMarkdown renders a document as CommonMark with the GFM extensions for
+// tables, task lists and strikethrough.
+func ToMarkdown(doc *Node) string {
+	out := markdownBlocks(doc.Content, false)
+	if out == "" {
+		return ""
+	}
+	return out + "\n"
+}
+
+func markdownBlocks(nodes []*Node, inListItem bool) string {
+	var sb strings.Builder
+	for i, node := range nodes {
+		block := markdownBlock(node)
+		if block == "" {
+			continue
+		}
+		if sb.Len() > 0 {
+			// Keep nested lists tight under their item's paragraph.
+			if inListItem && isList(node.Type) && i > 0 {
+				sb.WriteString("\n")
+			} else {
+				sb.WriteString("\n\n")
+			}
+		}
+		sb.WriteString(block)
+	}
+	return sb.String()
+}
+
+func markdownBlock(node *Node) string {
+	switch node.Type {
+	case "paragraph":
+		return markdownInline(node.Content)
+	case "heading":
+		level := node.AttrInt("level", 1)
+		if level < 1 || level > 6 {
+			level = 1
+		}
+		return strings.Repeat("#", level) + " " + markdownInline(node.Content)
+	case "codeBlock":
+		return markdownCodeBlock(node)
+	case "blockquote":
+		return prefixLines(markdownBlocks(node.Content, false), "> ", ">")
+	case "bulletList":
+		return markdownList(node, func(int, *Node) string { return "- " })
+	case "orderedList":
+		start := node.AttrInt("start", 1)
+		return markdownList(node, func(i int, _ *Node) string { return fmt.Sprintf("%d. ", start+i) })
+	case "taskList":
+		return markdownList(node, func(_ int, item *Node) string {
+			if item.AttrBool("checked") {
+				return "- [x] "
+			}
+			return "- [ ] "
+		})
+	case "horizontalRule":
+		return "---"
+	case "image":
+		return markdownImage(node)
+	case "table":
+		return markdownTable(node)
+	case "text", "hardBreak":
+		return markdownInline([]*Node{node})
+	}
+	return markdownBlocks(node.Content, false)
+}
+
+func isList(nodeType string) bool {
+	return nodeType == "bulletList" || nodeType == "orderedList" || nodeType == "taskList"
+}
+
+func markdownList(list *Node, marker func(i int, item *Node) string) string {
+	items := make([]string, 0, len(list.Content))
+	for i, item := range list.Content {
+		prefix := marker(i, item)
+		body := markdownBlocks(item.Content, true)
+		if body == "" {
+			items = append(items, strings.TrimRight(prefix, " "))
+			continue
+		}
+		indent := strings.Repeat(" ", len(prefix))
+		items = append(items, prefix+indentLines(body, indent))
+	}
+	return strings.Join(items, "\n")
+}
+
+func markdownCodeBlock(node *Node) string {
+	code := strings.TrimSuffix(node.textContent(), "\n")
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + node.Attr("language") + "\n" + code + "\n" + fence
+}
+
+func markdownImage(node *Node) string {
+	return "![" + escapeMarkdown(node.Attr("alt"), false) + "](" + markdownDestination(node.Attr("src"), node.Attr("title")) + ")"
+}
+
+func markdownDestination(href, title string) string {
+	dest := href
+	if strings.ContainsAny(dest, " ()<>") {
+		dest = "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(dest) + ">"
+	}
+	if title != "" {
+		dest += " \"" + strings.ReplaceAll(title, "\"", "\\\"") + "\""
+	}
+	return dest
+}
+
+func markdownTable(table *Node) string {
+	var rows [][]string
+	columns := 0
+	for _, row := range table.Content {
+		var cells []string
+		for _, cell := range row.Content {
+			var parts []string
+			for _, block := range cell.Content {
+				if text := markdownInline(block.Content); text != "" {
+					parts = append(parts, strings.ReplaceAll(text, "\\\n", " "))
+				}
+			}
+			cells = append(cells, strings.Join(parts, " "))
+		}
+		if len(cells) > columns {
+			columns = len(cells)
+		}
+		rows = append(rows, cells)
+	}
+	if len(rows) == 0 || columns == 0 {
+		return ""
+	}
+
+	writeRow := func(sb *strings.Builder, cells []string) {
+		sb.WriteString("|")
+		for i := 0; i < columns; i++ {
+			cell := ""
+			if i < len(cells) {
+				cell = cells[i]
+			}
+			sb.WriteString(" " + cell + " |")
+		}
+	}
+
+	var sb strings.Builder
+	writeRow(&sb, rows[0])
+	sb.WriteString("\n|")
+	for i := 0; i < columns; i++ {
+		sb.WriteString(" --- |")
+	}
+	for _, row := range rows[1:] {
+		sb.WriteString("\n")
+		writeRow(&sb, row)
+	}
+	return sb.String()
+}
+
+// inlineWriter serialises text nodes, opening and closing mark delimiters
+// only where the set of active marks changes. Whitespace is kept outside of
+// delimiters because CommonMark does not allow emphasis to start or end on it.
+type inlineWriter struct {
+	sb        strings.Builder
+	open      []Mark
+	pending   string
+	lineStart bool
+}
+
+func markdownInline(nodes []*Node) string {
+	w := inlineWriter{lineStart: true}
+	for _, node := range nodes {
+		switch node.Type {
+		case "text":
+			w.text(node)
+		case "hardBreak":
+			w.flushPending()
+			w.sb.WriteString("\\\n")
+			w.lineStart = true
+		case "image":
+			w.setMarks(markdownMarks(node.Marks))
+			w.write(markdownImage(node))
+		default:
+			w.text(&Node{Type: "text", Text: node.PlainText(), Marks: node.Marks})
+		}
+	}
+	w.setMarks(nil)
+	w.flushPending()
+	return w.sb.String()
+}
+
+func (w *inlineWriter) text(node *Node) {
+	core := strings.TrimSpace(node.Text)
+	if core == "" {
+		w.pending += node.Text
+		return
+	}
+	leading := node.Text[:strings.Index(node.Text, core)]
+	trailing := node.Text[len(leading)+len(core):]
+
+	marks := markdownMarks(node.Marks)
+	keep := w.commonMarks(marks)
+	w.closeMarks(keep)
+	w.flushPending()
+	w.write(leading)
+	w.openMarks(marks[keep:])
+	if node.hasMark("code") {
+		w.write(codeSpan(core))
+	} else {
+		w.write(escapeMarkdown(core, w.lineStart))
+	}
+	w.pending = trailing
+}
+
+func (w *inlineWriter) setMarks(marks []Mark) {
+	keep := w.commonMarks(marks)
+	w.closeMarks(keep)
+	w.flushPending()
+	w.openMarks(marks[keep:])
+}
+
+func (w *inlineWriter) commonMarks(marks []Mark) int {
+	keep := 0
+	for keep < len(w.open) && keep < len(marks) && sameMark(w.open[keep], marks[keep]) {
+		keep++
+	}
+	return keep
+}
+
+func (w *inlineWriter) closeMarks(keep int) {
+	for i := len(w.open) - 1; i >= keep; i-- {
+		mark := w.open[i]
+		switch mark.Type {
+		case "link":
+			w.write("](" + markdownDestination(mark.Attr("href"), mark.Attr("title")) + ")")
+		default:
+			w.write(markDelimiter(mark.Type))
+		}
+	}
+	w.open = w.open[:keep]
+}
+
+func (w *inlineWriter) openMarks(marks []Mark) {
+	for _, mark := range marks {
+		switch mark.Type {
+		case "link":
+			w.write("[")
+		default:
+			w.write(markDelimiter(mark.Type))
+		}
+		w.open = append(w.open, mark)
+	}
+}
+
+func (w *inlineWriter) flushPending() {
+	w.write(w.pending)
+	w.pending = ""
+}
+
+func (w *inlineWriter) write(s string) {
+	if s == "" {
+		return
+	}
+	w.sb.WriteString(s)
+	w.lineStart = false
+}
+
+func markDelimiter(markType string) string {
+	switch markType {
+	case "bold":
+		return "**"
+	case "italic":
+		return "*"
+	case "strike":
+		return "~~"
+	}
+	return ""
+}
+
+func markdownMarks(marks []Mark) []Mark {
+	var result []Mark
+	for _, mark := range marks {
+		if _, ok := markOrder[mark.Type]; ok {
+			result = append(result, mark)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return markOrder[result[i].Type] < markOrder[result[j].Type]
+	})
+	return result
+}
+
+func sameMark(a, b Mark) bool {
+	return a.Type == b.Type && a.Attr("href") == b.Attr("href") && a.Attr("title") == b.Attr("title") && a.Attr("color") == b.Attr("color")
+}
+
+func codeSpan(code string) string {
+	longest, run := 0, 0
+	for _, r := range code {
+		if r == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", longest+1)
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+		return fence + " " + code + " " + fence
+	}
+	return fence + code + fence
+}
+
+// escapeMarkdown backslash-escapes characters that would otherwise be read
+// as Markdown syntax. Block markers only matter at the start of a line.
+func escapeMarkdown(text string, lineStart bool) string {
+	runes := []rune(text)
+	var sb strings.Builder
+	for i, r := range runes {
+		switch r {
+		case '\\', '`', '*', '[', ']', '<', '~', '|':
+			sb.WriteRune('\\')
+		case '_':
+			before := i > 0 && isWordRune(runes[i-1])
+			after := i+1 < len(runes) && isWordRune(runes[i+1])
+			if !before || !after {
+				sb.WriteRune('\\')
+			}
+		case '&':
+			if i+1 < len(runes) && (unicode.IsLetter(runes[i+1]) || runes[i+1] == '#') {
+				sb.WriteRune('\\')
+			}
+		case '#', '-', '+', '>', '=':
+			if i == 0 && lineStart {
+				sb.WriteRune('\\')
+			}
+		case '.', ')':
+			if lineStart && i > 0 && allDigits(runes[:i]) {
+				sb.WriteRune('\\')
+			}
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func allDigits(runes []rune) bool {
+	for _, r := range runes {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func indentLines(text, indent string) string {
+	lines := strings.Split(text, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = indent + lines[i]
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func prefixLines(text, prefix, emptyPrefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = emptyPrefix
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
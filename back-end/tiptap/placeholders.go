@@ -0,0 +1,31 @@
+package tiptap
+
+import (
+	"regexp"
+	"strings"
+)
+
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// ExpandPlaceholders replaces {{name}} placeholders with values[name] in the
+// given string. Unknown placeholders are left as they are.
+func ExpandPlaceholders(text string, values map[string]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(text, func(match string) string {
+		name := placeholderPattern.FindStringSubmatch(match)[1]
+		if value, ok := values[strings.ToLower(name)]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+// FillPlaceholders expands placeholders inside the document's text nodes. A
+// placeholder split across differently formatted text nodes is not matched.
+func FillPlaceholders(doc *Node, values map[string]string) {
+	doc.Walk(func(node *Node, _ []int) bool {
+		if node.Type == "text" {
+			node.Text = ExpandPlaceholders(node.Text, values)
+		}
+		return true
+	})
+}
@@ -0,0 +1,83 @@
+package tiptap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const maxTaskText = 500
+
+type TaskItem struct {
+	Path    string // child indexes from the document root, dot separated
+	Text    string // text of the item without its nested lists
+	Checked bool
+}
+
+// TaskItems returns the taskItem nodes of the document in document order,
+// including items nested inside other items.
+func TaskItems(doc *Node) []TaskItem {
+	var items []TaskItem
+	doc.Walk(func(node *Node, path []int) bool {
+		if node.Type == "taskItem" {
+			items = append(items, TaskItem{
+				Path:    FormatPath(path),
+				Text:    taskText(node),
+				Checked: node.AttrBool("checked"),
+			})
+		}
+		return true
+	})
+	return items
+}
+
+func taskText(item *Node) string {
+	var parts []string
+	for _, child := range item.Content {
+		if isList(child.Type) {
+			continue
+		}
+		parts = append(parts, child.PlainText())
+	}
+	return truncate(strings.Join(strings.Fields(strings.Join(parts, " ")), " "), maxTaskText)
+}
+
+// SetTaskChecked sets the checked attribute of the taskItem at path.
+func SetTaskChecked(doc *Node, path string, checked bool) error {
+	indexes, err := ParsePath(path)
+	if err != nil {
+		return err
+	}
+	node := doc.At(indexes)
+	if node == nil || node.Type != "taskItem" {
+		return fmt.Errorf("no task item at %s", path)
+	}
+	node.SetAttr("checked", checked)
+	return nil
+}
+
+// FormatPath writes child indexes as a dot separated string such as "0.2.1".
+func FormatPath(path []int) string {
+	parts := make([]string, len(path))
+	for i, index := range path {
+		parts[i] = strconv.Itoa(index)
+	}
+	return strings.Join(parts, ".")
+}
+
+// ParsePath reads a path written by FormatPath.
+func ParsePath(path string) ([]int, error) {
+	if path == "" {
+		return nil, nil
+	}
+	parts := strings.Split(path, ".")
+	indexes := make([]int, len(parts))
+	for i, part := range parts {
+		index, err := strconv.Atoi(part)
+		if err != nil || index < 0 {
+			return nil, fmt.Errorf("invalid node path %q", path)
+		}
+		indexes[i] = index
+	}
+	return indexes, nil
+}
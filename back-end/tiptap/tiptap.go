@@ -0,0 +1,202 @@
+// Package tiptap works with the TipTap (ProseMirror) JSON documents stored in
+// notes.content and converts them to and from Markdown and HTML.
+package tiptap
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+type Mark struct {
+	Type  string                 `json:"type"`
+	Attrs map[string]interface{} `json:"attrs,omitempty"`
+}
+
+type Node struct {
+	Type    string                 `json:"type"`
+	Attrs   map[string]interface{} `json:"attrs,omitempty"`
+	Content []*Node                `json:"content,omitempty"`
+	Text    string                 `json:"text,omitempty"`
+	Marks   []Mark                 `json:"marks,omitempty"`
+}
+
+// NewDoc returns an empty document node.
+func NewDoc() *Node {
+	return &Node{Type: "doc"}
+}
+
+// Parse decodes stored note content. Empty content is an empty document.
+func Parse(content string) (*Node, error) {
+	if strings.TrimSpace(content) == "" {
+		return NewDoc(), nil
+	}
+
+	var doc Node
+	if err := json.Unmarshal([]byte(content), &doc); err != nil {
+		return nil, fmt.Errorf("note content is not a TipTap document: %w", err)
+	}
+	if doc.Type != "doc" {
+		return nil, fmt.Errorf("note content is not a TipTap document")
+	}
+	return &doc, nil
+}
+
+// ParseStored decodes content read back from notes.content. Notes saved
+// before content was stored as TipTap JSON hold the editor's HTML, which is
+// converted with FromHTML.
+func ParseStored(content string) (*Node, error) {
+	if trimmed := strings.TrimSpace(content); trimmed != "" && !strings.HasPrefix(trimmed, "{") {
+		return FromHTML(content)
+	}
+	return Parse(content)
+}
+
+// String encodes the node back to the JSON stored in notes.content.
+func (n *Node) String() string {
+	data, err := json.Marshal(n)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func (n *Node) Attr(key string) string {
+	if n.Attrs == nil {
+		return ""
+	}
+	switch value := n.Attrs[key].(type) {
+	case string:
+		return value
+	case float64:
+		return fmt.Sprintf("%g", value)
+	case bool:
+		return fmt.Sprintf("%t", value)
+	}
+	return ""
+}
+
+func (n *Node) AttrInt(key string, fallback int) int {
+	if n.Attrs == nil {
+		return fallback
+	}
+	switch value := n.Attrs[key].(type) {
+	case float64:
+		return int(value)
+	case int:
+		return value
+	}
+	return fallback
+}
+
+func (n *Node) AttrBool(key string) bool {
+	if n.Attrs == nil {
+		return false
+	}
+	value, _ := n.Attrs[key].(bool)
+	return value
+}
+
+func (n *Node) SetAttr(key string, value interface{}) {
+	if n.Attrs == nil {
+		n.Attrs = map[string]interface{}{}
+	}
+	n.Attrs[key] = value
+}
+
+func (m Mark) Attr(key string) string {
+	if m.Attrs == nil {
+		return ""
+	}
+	value, _ := m.Attrs[key].(string)
+	return value
+}
+
+// Walk visits the node and its descendants depth first. The path holds the
+// child indexes leading from the root to the visited node. Returning false
+// from fn skips the node's children.
+func (n *Node) Walk(fn func(node *Node, path []int) bool) {
+	n.walk(nil, fn)
+}
+
+func (n *Node) walk(path []int, fn func(node *Node, path []int) bool) {
+	if !fn(n, path) {
+		return
+	}
+	for i, child := range n.Content {
+		childPath := make([]int, len(path)+1)
+		copy(childPath, path)
+		childPath[len(path)] = i
+		child.walk(childPath, fn)
+	}
+}
+
+// At returns the descendant found by following path, or nil.
+func (n *Node) At(path []int) *Node {
+	node := n
+	for _, i := range path {
+		if i < 0 || i >= len(node.Content) {
+			return nil
+		}
+		node = node.Content[i]
+	}
+	return node
+}
+
+// PlainText returns the text of the node with blocks separated by newlines.
+func (n *Node) PlainText() string {
+	var sb strings.Builder
+	n.writePlainText(&sb)
+	return strings.TrimSpace(sb.String())
+}
+
+func (n *Node) writePlainText(sb *strings.Builder) {
+	switch n.Type {
+	case "text":
+		sb.WriteString(n.Text)
+		return
+	case "hardBreak":
+		sb.WriteString("\n")
+		return
+	}
+	for _, child := range n.Content {
+		child.writePlainText(sb)
+	}
+	if isBlock(n.Type) {
+		sb.WriteString("\n")
+	}
+}
+
+// textContent concatenates the raw text of all descendant text nodes.
+func (n *Node) textContent() string {
+	if n.Type == "text" {
+		return n.Text
+	}
+	var sb strings.Builder
+	for _, child := range n.Content {
+		if child.Type == "hardBreak" {
+			sb.WriteString("\n")
+			continue
+		}
+		sb.WriteString(child.textContent())
+	}
+	return sb.String()
+}
+
+func isBlock(nodeType string) bool {
+	switch nodeType {
+	case "paragraph", "heading", "codeBlock", "blockquote", "listItem", "taskItem",
+		"tableRow", "horizontalRule":
+		return true
+	}
+	return false
+}
+
+func (n *Node) hasMark(markType string) bool {
+	for _, mark := range n.Marks {
+		if mark.Type == markType {
+			return true
+		}
+	}
+	return false
+}
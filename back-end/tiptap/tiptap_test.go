@@ -0,0 +1,137 @@
+package tiptap
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fixtures returns the files in testdata/dir matching pattern.
+func fixtures(t *testing.T, dir, pattern string) []string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join("testdata", dir, pattern))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatalf("no fixtures in testdata/%s", dir)
+	}
+	return files
+}
+
+func readFixture(t *testing.T, file string) string {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// assertSameJSON compares two JSON documents regardless of formatting and
+// key order.
+func assertSameJSON(t *testing.T, got, want string) {
+	t.Helper()
+	var gotValue, wantValue interface{}
+	if err := json.Unmarshal([]byte(got), &gotValue); err != nil {
+		t.Fatalf("invalid JSON %q: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("invalid JSON %q: %v", want, err)
+	}
+	if !reflect.DeepEqual(gotValue, wantValue) {
+		t.Errorf("JSON differs\ngot:  %s\nwant: %s", got, want)
+	}
+}
+
+// The Markdown fixtures are written the way ToMarkdown writes them, so
+// importing and exporting one gives back the same file, and the document
+// survives being stored and exported again.
+func TestMarkdownRoundTrip(t *testing.T) {
+	for _, file := range fixtures(t, "markdown", "*.md") {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			source := readFixture(t, file)
+
+			doc, err := FromMarkdown([]byte(source))
+			if err != nil {
+				t.Fatalf("FromMarkdown: %v", err)
+			}
+			if got := ToMarkdown(doc); got != source {
+				t.Errorf("ToMarkdown(FromMarkdown(source)) differs\ngot:\n%s\nwant:\n%s", got, source)
+			}
+
+			stored, err := Parse(doc.String())
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			assertSameJSON(t, stored.String(), doc.String())
+
+			again, err := FromMarkdown([]byte(ToMarkdown(stored)))
+			if err != nil {
+				t.Fatalf("FromMarkdown: %v", err)
+			}
+			assertSameJSON(t, again.String(), doc.String())
+		})
+	}
+}
+
+// Each HTML fixture is content saved by the editor before notes were stored
+// as TipTap JSON, next to the document it converts to.
+func TestFromHTML(t *testing.T) {
+	for _, file := range fixtures(t, "html", "*.html") {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			doc, err := FromHTML(readFixture(t, file))
+			if err != nil {
+				t.Fatalf("FromHTML: %v", err)
+			}
+			assertSameJSON(t, doc.String(), readFixture(t, strings.TrimSuffix(file, ".html")+".json"))
+		})
+	}
+}
+
+func TestParseStored(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"empty", "  ", `{"type":"doc"}`},
+		{"json", `{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"hi"}]}]}`,
+			`{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"hi"}]}]}`},
+		{"html", "<p>hi <strong>there</strong></p>",
+			`{"type":"doc","content":[{"type":"paragraph","content":[{"type":"text","text":"hi "},{"type":"text","text":"there","marks":[{"type":"bold"}]}]}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := ParseStored(tt.content)
+			if err != nil {
+				t.Fatalf("ParseStored: %v", err)
+			}
+			assertSameJSON(t, doc.String(), tt.want)
+		})
+	}
+
+	if _, err := ParseStored(`{"type":"paragraph"}`); err == nil {
+		t.Error("ParseStored accepted JSON that is not a document")
+	}
+}
+
+func TestToHTMLEscapes(t *testing.T) {
+	doc, err := Parse(`{"type":"doc","content":[{"type":"paragraph","content":[
+		{"type":"text","text":"<script>alert(1)</script>"},
+		{"type":"text","text":"bad","marks":[{"type":"link","attrs":{"href":"javascript:alert(1)"}}]},
+		{"type":"text","text":"good","marks":[{"type":"link","attrs":{"href":"https://example.com/?a=1&b=\"2\""}}]}
+	]}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `<p>&lt;script&gt;alert(1)&lt;/script&gt;bad` +
+		`<a href="https://example.com/?a=1&amp;b=&#34;2&#34;" rel="noopener noreferrer nofollow">good</a></p>`
+	if got := ToHTML(doc); got != want {
+		t.Errorf("ToHTML\ngot:  %s\nwant: %s", got, want)
+	}
+}
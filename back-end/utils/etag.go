@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// FormatETag renders a row version as a strong entity tag.
+func FormatETag(version int) string {
+	return fmt.Sprintf("\"%d\"", version)
+}
+
+// ParseIfMatch reads the If-Match header and returns the version the client
+// expects. A missing header or "*" yields nil, meaning no precondition.
+//
+// If-Match uses the strong comparison (RFC 7232 section 3.1), which a weak
+// W/"..." tag never passes. It yields version 0, which no row has, so the
+// guarded write fails and the caller answers 412 like any other mismatch.
+func ParseIfMatch(c *fiber.Ctx) (*int, error) {
+	header := strings.TrimSpace(c.Get(fiber.HeaderIfMatch))
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	weak := strings.HasPrefix(header, "W/")
+	tag := strings.Trim(strings.TrimPrefix(header, "W/"), "\"")
+	version, err := strconv.Atoi(tag)
+	if err != nil {
+		return nil, fmt.Errorf("invalid If-Match header")
+	}
+	if weak {
+		version = 0
+	}
+	return &version, nil
+}
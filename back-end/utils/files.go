@@ -0,0 +1,23 @@
+package utils
+
+import "strings"
+
+// SafeFileName strips characters that are not allowed in file names on
+// common platforms. It returns fallback when nothing usable is left.
+func SafeFileName(name string, fallback string) string {
+	safe := strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		if r < 32 {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(name))
+	safe = strings.Trim(safe, ". ")
+	if safe == "" {
+		return fallback
+	}
+	return safe
+}
@@ -25,6 +25,14 @@ interface NoteCardProps {
   onClick: (note: Note) => void;
 }
 
+interface TipTapNode {
+  text?: string;
+  content?: TipTapNode[];
+}
+
+const getNodeText = (node: TipTapNode): string =>
+  node.text ?? (node.content ?? []).map(getNodeText).join(' ');
+
 const NoteCard: FC<NoteCardProps> = ({
   note,
   onEdit,
@@ -55,11 +63,16 @@ const NoteCard: FC<NoteCardProps> = ({
     handleMenuClose();
   };
 
-  // Extract plain text from HTML content for preview
-  const getPlainTextPreview = (htmlContent: string, maxLength: number = 150) => {
-    const div = document.createElement('div');
-    div.innerHTML = htmlContent;
-    const text = div.textContent || div.innerText || '';
+  // Extract plain text from TipTap JSON (or legacy HTML) content for preview
+  const getPlainTextPreview = (content: string, maxLength: number = 150) => {
+    let text: string;
+    try {
+      text = getNodeText(JSON.parse(content)).trim();
+    } catch {
+      const div = document.createElement('div');
+      div.innerHTML = content;
+      text = div.textContent || div.innerText || '';
+    }
     return text.length > maxLength ? text.substring(0, maxLength) + '...' : text;
   };
 
@@ -49,6 +49,15 @@ interface TipTapEditorProps {
   readOnly?: boolean;
 }
 
+// Notes are stored as TipTap JSON; older notes may still hold HTML.
+const parseContent = (content: string) => {
+  try {
+    return JSON.parse(content);
+  } catch {
+    return content;
+  }
+};
+
 const TipTapEditor: FC<TipTapEditorProps> = ({
   content,
   onChange,
@@ -83,17 +92,17 @@ const TipTapEditor: FC<TipTapEditorProps> = ({
         types: ['heading', 'paragraph'],
       }),
     ],
-    content,
+    content: parseContent(content),
     editable: !readOnly,
     onUpdate: ({ editor }) => {
-      onChange(editor.getHTML());
+      onChange(JSON.stringify(editor.getJSON()));
     },
   });
 
   useEffect(() => {
-    if (editor && content !== editor.getHTML()) {
+    if (editor && content !== JSON.stringify(editor.getJSON())) {
       // Only update if content has actually changed to avoid cursor issues
-      editor.commands.setContent(content, false);
+      editor.commands.setContent(parseContent(content), false);
     }
   }, [content, editor]);
 